@@ -0,0 +1,147 @@
+package passport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// ErrAuditChainBroken indicates that an audit entry has been modified or
+// removed.
+var ErrAuditChainBroken = NewError("audit_chain_broken", "audit chain broken", CategoryInternal)
+
+// Security-relevant actions that are recorded in the audit log.
+const (
+	AuditLogin                = "login"
+	AuditRegister             = "register"
+	AuditChangePassword       = "change_password"
+	AuditChangeEmail          = "change_email"
+	AuditCancelEmailChange    = "cancel_email_change"
+	AuditRevertEmailChange    = "revert_email_change"
+	AuditConfirm              = "confirm"
+	AuditSendConfirmation     = "send_confirmation"
+	AuditRequestResetPassword = "request_reset_password"
+	AuditResetPassword        = "reset_password"
+	AuditDeleteAccount        = "delete_account"
+	AuditLockAccount          = "lock_account"
+)
+
+// Outcomes of the audited actions.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry represents a single record in the append-only audit log.
+type AuditEntry struct {
+	ID      string `json:"id,omitempty"`
+	Action  string `json:"action"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason,omitempty"`
+
+	// ActorID is the authenticated user that performed the action, and is
+	// empty for anonymous requests.
+	ActorID string `json:"actor_id,omitempty"`
+
+	// UserID is the user the action was performed on, when known.
+	// Identifier is the email, username or phone submitted for the user,
+	// which is the only target of a failed login or of a mail requested
+	// for an unknown user.
+	UserID     string `json:"user_id,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// PrevHash and Hash are only set when hash chaining is enabled.
+	// PersonalHash is the hash of the identifier, IP and user agent, which
+	// is chained in their place, so that they can be erased with the user
+	// without breaking the chain. ErasedAt is set when they are erased.
+	PrevHash     string    `json:"prev_hash,omitempty"`
+	Hash         string    `json:"hash,omitempty"`
+	PersonalHash string    `json:"personal_hash,omitempty"`
+	ErasedAt     time.Time `json:"erased_at,omitempty"`
+}
+
+// ComputeHash returns the hash of the entry, which includes the hash of the
+// previous entry. Modifying any entry will invalidate the hashes of all
+// subsequent entries. The personal data is hashed from the entry unless it
+// is erased, and the stored PersonalHash is used otherwise.
+func (a AuditEntry) ComputeHash() string {
+	personalHash := a.PersonalHash
+	if a.ErasedAt.IsZero() {
+		personalHash = a.computePersonalHash()
+	}
+	return hashFields(
+		a.PrevHash,
+		a.Action,
+		a.Outcome,
+		a.Reason,
+		a.ActorID,
+		a.UserID,
+		personalHash,
+		a.CreatedAt.UTC().Format(time.RFC3339Nano),
+	)
+}
+
+func (a AuditEntry) computePersonalHash() string {
+	return hashFields(a.Identifier, a.IP, a.UserAgent)
+}
+
+// Chain links the entry to the previous entry's hash.
+func (a AuditEntry) Chain(prevHash string) AuditEntry {
+	a.PrevHash = prevHash
+	a.PersonalHash = a.computePersonalHash()
+	a.Hash = a.ComputeHash()
+	return a
+}
+
+// Erase clears the personal data of the entry, which keeps the entry
+// verifiable when it is chained.
+func (a AuditEntry) Erase(now time.Time) AuditEntry {
+	a.Identifier = ""
+	a.IP = ""
+	a.UserAgent = ""
+	a.ErasedAt = now
+	return a
+}
+
+func hashFields(fields ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(h[:])
+}
+
+// VerifyAuditChain checks that the entries, ordered from the oldest, form an
+// unbroken hash chain.
+func VerifyAuditChain(entries []AuditEntry) error {
+	for i, entry := range entries {
+		if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			return ErrAuditChainBroken
+		}
+		if entry.Hash != entry.ComputeHash() {
+			return ErrAuditChainBroken
+		}
+	}
+	return nil
+}
+
+// NewAuditEntry returns a new AuditEntry for the given action, created at
+// the current time of the clock, which defaults to SystemClock when nil.
+func NewAuditEntry(clock Clock, action string, err error) AuditEntry {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	entry := AuditEntry{
+		Action:  action,
+		Outcome: AuditSuccess,
+		// Postgres only stores timestamps with microsecond precision.
+		CreatedAt: clock.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err != nil {
+		entry.Outcome = AuditFailure
+		entry.Reason = err.Error()
+	}
+	return entry
+}
@@ -0,0 +1,69 @@
+package passport_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditChain(t *testing.T) {
+	assert := assert.New(t)
+
+	var entries []passport.AuditEntry
+	var prevHash string
+	for _, action := range []string{passport.AuditRegister, passport.AuditConfirm, passport.AuditLogin} {
+		entry := passport.NewAuditEntry(nil, action, nil)
+		entry.Identifier = "john.doe@mail.com"
+		entry.IP = "127.0.0.1"
+		entry = entry.Chain(prevHash)
+		entries = append(entries, entry)
+		prevHash = entry.Hash
+	}
+	assert.Nil(passport.VerifyAuditChain(entries))
+
+	t.Run("when entry is modified", func(t *testing.T) {
+		tampered := append([]passport.AuditEntry(nil), entries...)
+		tampered[1].Outcome = passport.AuditFailure
+		assert.Equal(passport.ErrAuditChainBroken, passport.VerifyAuditChain(tampered))
+	})
+
+	t.Run("when identifier is modified", func(t *testing.T) {
+		tampered := append([]passport.AuditEntry(nil), entries...)
+		tampered[2].Identifier = "jane.doe@mail.com"
+		assert.Equal(passport.ErrAuditChainBroken, passport.VerifyAuditChain(tampered))
+	})
+
+	t.Run("when personal data is erased", func(t *testing.T) {
+		erased := append([]passport.AuditEntry(nil), entries...)
+		erased[1] = erased[1].Erase(time.Now())
+		assert.Equal("", erased[1].Identifier)
+		assert.Equal("", erased[1].IP)
+		assert.Nil(passport.VerifyAuditChain(erased))
+
+		// The personal data cannot be modified without erasing it.
+		erased[1].ErasedAt = time.Time{}
+		assert.Equal(passport.ErrAuditChainBroken, passport.VerifyAuditChain(erased))
+	})
+
+	t.Run("when entry is removed", func(t *testing.T) {
+		tampered := []passport.AuditEntry{entries[0], entries[2]}
+		assert.Equal(passport.ErrAuditChainBroken, passport.VerifyAuditChain(tampered))
+	})
+}
+
+func TestNewAuditEntryFailure(t *testing.T) {
+	assert := assert.New(t)
+	entry := passport.NewAuditEntry(nil, passport.AuditLogin, errors.New("bad password"))
+	assert.Equal(passport.AuditFailure, entry.Outcome)
+	assert.Equal("bad password", entry.Reason)
+}
+
+func TestNewAuditEntryClock(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 1500, time.UTC)
+	entry := passport.NewAuditEntry(passport.NewFakeClock(now), passport.AuditLogin, nil)
+	assert.Equal(now.Truncate(time.Microsecond), entry.CreatedAt)
+}
@@ -1,14 +1,13 @@
 package passport
 
 import (
-	"errors"
 	"time"
 )
 
 // ErrConfirmationRequired indicates that the email requires confirmation.
 var (
-	ErrConfirmationRequired = errors.New("confirmation required")
-	ErrConfirmed            = errors.New("already confirmed")
+	ErrConfirmationRequired = NewError("confirmation_required", "confirmation required", CategoryForbidden)
+	ErrConfirmed            = NewError("confirmed", "already confirmed", CategoryConflict)
 )
 
 // ConfirmationTokenValidity represents the duration the confirmation token is
@@ -25,16 +24,32 @@ type Confirmable struct {
 	// to verify the email before replacing the primary email to avoid
 	// users from "chopping" other user's email.
 	UnconfirmedEmail string `json:"unconfirmed_email,omitempty"`
+
+	// ConfirmationSentCount is the number of tokens sent on the day of
+	// ConfirmationSentAt, for throttling.
+	ConfirmationSentCount int `json:"confirmation_sent_count,omitempty"`
 }
 
 // Valid checks if the confirmation token is still within the validity period.
 func (c Confirmable) Valid(ttl time.Duration) bool {
-	return time.Since(c.ConfirmationSentAt) < ttl
+	return c.ValidAt(time.Now(), ttl)
+}
+
+// ValidAt is like Valid, except that the validity is checked against the
+// given time.
+func (c Confirmable) ValidAt(now time.Time, ttl time.Duration) bool {
+	return now.Sub(c.ConfirmationSentAt) < ttl
 }
 
 // ValidateExpiry returns an error indicating the token has expired.
 func (c Confirmable) ValidateExpiry(ttl time.Duration) error {
-	if valid := c.Valid(ttl); !valid {
+	return c.ValidateExpiryAt(time.Now(), ttl)
+}
+
+// ValidateExpiryAt is like ValidateExpiry, except that the expiry is checked
+// against the given time.
+func (c Confirmable) ValidateExpiryAt(now time.Time, ttl time.Duration) error {
+	if valid := c.ValidAt(now, ttl); !valid {
 		return ErrTokenExpired
 	}
 	return nil
@@ -63,10 +78,14 @@ func (c Confirmable) ValidateUnconfirmed() error {
 
 // NewConfirmable returns a new Confirmable.
 func NewConfirmable(token, email string) Confirmable {
+	return NewConfirmableAt(token, email, time.Now())
+}
+
+// NewConfirmableAt returns a new Confirmable sent at the given time.
+func NewConfirmableAt(token, email string, sentAt time.Time) Confirmable {
 	return Confirmable{
-		// TODO: Customize factory.
 		ConfirmationToken:  token,
-		ConfirmationSentAt: time.Now(),
+		ConfirmationSentAt: sentAt,
 		UnconfirmedEmail:   email,
 	}
 }
@@ -0,0 +1,202 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+var auditTable = "audit_log"
+
+// auditLockID is the advisory lock that serializes the appends when hash
+// chaining is enabled.
+const auditLockID = 7428390
+
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// AuditLog represents the append-only repository for the security audit
+// trail.
+type AuditLog struct {
+	tx        Tx
+	hashChain bool
+}
+
+// NewAuditLog returns a new pointer to AuditLog struct.
+func NewAuditLog(tx Tx) *AuditLog {
+	return &AuditLog{tx: tx}
+}
+
+func (a *AuditLog) WithTx(tx Tx) *AuditLog {
+	return &AuditLog{tx: tx, hashChain: a.hashChain}
+}
+
+// WithHashChain returns an AuditLog that links every new entry to the hash
+// of the previous entry, so that tampering can be detected with Verify.
+func (a *AuditLog) WithHashChain() *AuditLog {
+	return &AuditLog{tx: a.tx, hashChain: true}
+}
+
+// Append adds a new entry to the audit log.
+func (a *AuditLog) Append(ctx context.Context, entry passport.AuditEntry) (*passport.AuditEntry, error) {
+	tx := conn(ctx, a.tx)
+	if !a.hashChain {
+		return insertAuditEntry(tx, entry)
+	}
+
+	// The previous hash must be read and the entry written atomically,
+	// otherwise concurrent appends will fork the chain.
+	db, ok := tx.(beginner)
+	if !ok {
+		return appendChainedAuditEntry(tx, entry)
+	}
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := appendChainedAuditEntry(sqlTx, entry)
+	if err != nil {
+		sqlTx.Rollback()
+		return nil, err
+	}
+	return result, sqlTx.Commit()
+}
+
+// FindByUser returns the entries of the user within the given time range,
+// oldest first.
+func (a *AuditLog) FindByUser(ctx context.Context, userID string, from, to time.Time) ([]passport.AuditEntry, error) {
+	stmt := selectAuditStmt(auditTable, "user_id = $1 AND created_at >= $2 AND created_at < $3")
+	return getAuditEntries(conn(ctx, a.tx), stmt, userID, from, to)
+}
+
+// FindByIdentifier returns the entries recorded for the email, username or
+// phone within the given time range, oldest first. Unlike FindByUser, it
+// includes the failed attempts on an identifier.
+func (a *AuditLog) FindByIdentifier(ctx context.Context, identifier string, from, to time.Time) ([]passport.AuditEntry, error) {
+	stmt := selectAuditStmt(auditTable, "identifier = $1 AND created_at >= $2 AND created_at < $3")
+	return getAuditEntries(conn(ctx, a.tx), stmt, identifier, from, to)
+}
+
+// FindByTimeRange returns all entries within the given time range, oldest
+// first.
+func (a *AuditLog) FindByTimeRange(ctx context.Context, from, to time.Time) ([]passport.AuditEntry, error) {
+	stmt := selectAuditStmt(auditTable, "created_at >= $1 AND created_at < $2")
+	return getAuditEntries(conn(ctx, a.tx), stmt, from, to)
+}
+
+// Verify checks the hash chain of all the chained entries.
+func (a *AuditLog) Verify(ctx context.Context) error {
+	stmt := selectAuditStmt(auditTable, "hash <> ''")
+	entries, err := getAuditEntries(conn(ctx, a.tx), stmt)
+	if err != nil {
+		return err
+	}
+	return passport.VerifyAuditChain(entries)
+}
+
+func appendChainedAuditEntry(tx Tx, entry passport.AuditEntry) (*passport.AuditEntry, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditLockID); err != nil {
+		return nil, err
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT 	hash
+		FROM 	%s
+		WHERE 	hash <> ''
+		ORDER BY seq DESC
+		LIMIT 	1
+	`, auditTable)
+	var prevHash string
+	if err := tx.QueryRow(stmt).Scan(&prevHash); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return insertAuditEntry(tx, entry.Chain(prevHash))
+}
+
+func insertAuditEntry(tx Tx, entry passport.AuditEntry) (*passport.AuditEntry, error) {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s
+			(action, outcome, reason, actor_id, user_id, identifier, ip, user_agent, created_at, prev_hash, hash, personal_hash)
+		VALUES 	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`, auditTable)
+	if err := tx.QueryRow(stmt,
+		entry.Action,
+		entry.Outcome,
+		entry.Reason,
+		entry.ActorID,
+		entry.UserID,
+		entry.Identifier,
+		entry.IP,
+		entry.UserAgent,
+		entry.CreatedAt,
+		entry.PrevHash,
+		entry.Hash,
+		entry.PersonalHash,
+	).Scan(&entry.ID); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func selectAuditStmt(table, where string) string {
+	return fmt.Sprintf(`
+		SELECT 	id,
+			action,
+			outcome,
+			reason,
+			actor_id,
+			user_id,
+			identifier,
+			ip,
+			user_agent,
+			created_at,
+			prev_hash,
+			hash,
+			personal_hash,
+			erased_at
+		FROM 	%s
+		WHERE   %s
+		ORDER BY seq
+	`, table, where)
+}
+
+func getAuditEntries(tx Tx, stmt string, arguments ...interface{}) ([]passport.AuditEntry, error) {
+	rows, err := tx.Query(stmt, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []passport.AuditEntry
+	for rows.Next() {
+		var e passport.AuditEntry
+		var erasedAt sql.NullTime
+		if err := rows.Scan(
+			&e.ID,
+			&e.Action,
+			&e.Outcome,
+			&e.Reason,
+			&e.ActorID,
+			&e.UserID,
+			&e.Identifier,
+			&e.IP,
+			&e.UserAgent,
+			&e.CreatedAt,
+			&e.PrevHash,
+			&e.Hash,
+			&e.PersonalHash,
+			&erasedAt,
+		); err != nil {
+			return nil, err
+		}
+		e.ErasedAt = erasedAt.Time
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,118 @@
+package connector_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/connector"
+	"github.com/alextanhongpin/passport/examples/database"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestAuditLogSuite struct {
+	suite.Suite
+	db       *sql.DB
+	auditLog *connector.AuditLog
+}
+
+func (suite *TestAuditLogSuite) SetupSuite() {
+	suite.db = database.DB()
+	suite.auditLog = connector.NewAuditLog(suite.db).WithHashChain()
+}
+
+func (suite *TestAuditLogSuite) TearDownTest() {
+	_, err := suite.db.Exec("TRUNCATE TABLE audit_log")
+	suite.Nil(err)
+}
+
+func (suite *TestAuditLogSuite) TestAppendChained() {
+	var prevHash string
+	for _, action := range []string{passport.AuditRegister, passport.AuditLogin} {
+		entry := passport.NewAuditEntry(nil, action, nil)
+		entry.UserID = "user_1"
+
+		result, err := suite.auditLog.Append(context.TODO(), entry)
+		suite.Nil(err)
+		suite.True(result.ID != "")
+		suite.Equal(prevHash, result.PrevHash)
+		prevHash = result.Hash
+	}
+	suite.Nil(suite.auditLog.Verify(context.TODO()))
+}
+
+func (suite *TestAuditLogSuite) TestAppendOnly() {
+	_, err := suite.auditLog.Append(context.TODO(), passport.NewAuditEntry(nil, passport.AuditLogin, nil))
+	suite.Nil(err)
+
+	_, err = suite.db.Exec("UPDATE audit_log SET outcome = 'failure'")
+	suite.NotNil(err)
+	_, err = suite.db.Exec("DELETE FROM audit_log")
+	suite.NotNil(err)
+}
+
+func (suite *TestAuditLogSuite) TestErase() {
+	entry := passport.NewAuditEntry(nil, passport.AuditLogin, nil)
+	entry.Identifier = "john.doe@mail.com"
+	entry.IP = "127.0.0.1"
+	_, err := suite.auditLog.Append(context.TODO(), entry)
+	suite.Nil(err)
+
+	// Only the personal data can be erased.
+	_, err = suite.db.Exec("UPDATE audit_log SET identifier = '', ip = '', user_agent = '', erased_at = now(), outcome = 'failure'")
+	suite.NotNil(err)
+	_, err = suite.db.Exec("UPDATE audit_log SET identifier = '', ip = '', user_agent = '', erased_at = now()")
+	suite.Nil(err)
+
+	// The erased entries are still verified.
+	suite.Nil(suite.auditLog.Verify(context.TODO()))
+}
+
+func (suite *TestAuditLogSuite) TestFindByUser() {
+	entry := passport.NewAuditEntry(nil, passport.AuditLogin, nil)
+	entry.UserID = "user_1"
+	_, err := suite.auditLog.Append(context.TODO(), entry)
+	suite.Nil(err)
+
+	var (
+		from = time.Now().Add(-time.Hour)
+		to   = time.Now().Add(time.Hour)
+	)
+	entries, err := suite.auditLog.FindByUser(context.TODO(), "user_1", from, to)
+	suite.Nil(err)
+	suite.Equal(1, len(entries))
+	suite.Equal(passport.AuditLogin, entries[0].Action)
+
+	entries, err = suite.auditLog.FindByUser(context.TODO(), "user_2", from, to)
+	suite.Nil(err)
+	suite.Equal(0, len(entries))
+
+	entries, err = suite.auditLog.FindByTimeRange(context.TODO(), from, to)
+	suite.Nil(err)
+	suite.Equal(1, len(entries))
+}
+
+func (suite *TestAuditLogSuite) TestFindByIdentifier() {
+	entry := passport.NewAuditEntry(nil, passport.AuditLogin, passport.ErrPasswordInvalid)
+	entry.Identifier = "john.doe@mail.com"
+	_, err := suite.auditLog.Append(context.TODO(), entry)
+	suite.Nil(err)
+
+	var (
+		from = time.Now().Add(-time.Hour)
+		to   = time.Now().Add(time.Hour)
+	)
+	entries, err := suite.auditLog.FindByIdentifier(context.TODO(), "john.doe@mail.com", from, to)
+	suite.Nil(err)
+	suite.Equal(1, len(entries))
+	suite.Equal(passport.AuditFailure, entries[0].Outcome)
+	suite.Equal("", entries[0].UserID)
+	suite.Nil(suite.auditLog.Verify(context.TODO()))
+}
+
+func TestAuditLogTestSuite(t *testing.T) {
+	suite.Run(t, new(TestAuditLogSuite))
+}
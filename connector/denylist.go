@@ -0,0 +1,113 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var denylistTable = "denylist"
+
+// Denylist stores the ids of the revoked tokens and sessions until they
+// expire, so that the revocation is shared by all the instances.
+type Denylist struct {
+	tx Tx
+}
+
+// NewDenylist returns a new pointer to Denylist struct.
+func NewDenylist(tx Tx) *Denylist {
+	return &Denylist{tx}
+}
+
+func (d *Denylist) WithTx(tx Tx) *Denylist {
+	return &Denylist{tx}
+}
+
+// Deny denies the id until it expires. Denying an id again extends the
+// expiry.
+func (d *Denylist) Deny(ctx context.Context, id string, expiresAt time.Time) error {
+	if id == "" {
+		return nil
+	}
+	stmt := fmt.Sprintf(`
+		INSERT INTO %[1]s
+			(id, expires_at)
+		VALUES 	($1, $2)
+		ON CONFLICT (id) DO UPDATE
+		SET 	expires_at = GREATEST(%[1]s.expires_at, EXCLUDED.expires_at)
+	`, denylistTable)
+	_, err := conn(ctx, d.tx).Exec(stmt, id, expiresAt)
+	return err
+}
+
+// Denied checks if any of the ids is denied.
+func (d *Denylist) Denied(ctx context.Context, ids ...string) (bool, error) {
+	stmt := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 	1
+			FROM 	%s
+			WHERE 	id = ANY($1)
+			AND 	issued_before IS NULL
+			AND 	expires_at > now()
+		)
+	`, denylistTable)
+	var denied bool
+	err := conn(ctx, d.tx).QueryRow(stmt, pq.Array(ids)).Scan(&denied)
+	return denied, err
+}
+
+// DenySubject denies the tokens of the subject issued before issuedBefore.
+// Denying the subject again moves both times forward.
+func (d *Denylist) DenySubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	if subject == "" {
+		return nil
+	}
+	stmt := fmt.Sprintf(`
+		INSERT INTO %[1]s
+			(id, issued_before, expires_at)
+		VALUES 	($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE
+		SET 	issued_before = GREATEST(%[1]s.issued_before, EXCLUDED.issued_before),
+			expires_at = GREATEST(%[1]s.expires_at, EXCLUDED.expires_at)
+	`, denylistTable)
+	_, err := conn(ctx, d.tx).Exec(stmt, subjectID(subject), issuedBefore, expiresAt)
+	return err
+}
+
+// SubjectDenied checks if the token of the subject was issued before the
+// subject was denied.
+func (d *Denylist) SubjectDenied(ctx context.Context, subject string, issuedAt time.Time) (bool, error) {
+	stmt := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 	1
+			FROM 	%s
+			WHERE 	id = $1
+			AND 	issued_before > $2
+			AND 	expires_at > now()
+		)
+	`, denylistTable)
+	var denied bool
+	err := conn(ctx, d.tx).QueryRow(stmt, subjectID(subject), issuedAt).Scan(&denied)
+	return denied, err
+}
+
+// DeleteExpired removes the expired ids, and returns the number of ids
+// removed.
+func (d *Denylist) DeleteExpired(ctx context.Context) (int64, error) {
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE 	expires_at <= now()
+	`, denylistTable)
+	res, err := conn(ctx, d.tx).Exec(stmt)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// subjectID keeps the subjects apart from the token and session ids.
+func subjectID(subject string) string {
+	return "sub:" + subject
+}
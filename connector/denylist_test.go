@@ -0,0 +1,88 @@
+package connector_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport/connector"
+	"github.com/alextanhongpin/passport/examples/database"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestDenylistSuite struct {
+	suite.Suite
+	db       *sql.DB
+	denylist *connector.Denylist
+}
+
+func (suite *TestDenylistSuite) SetupSuite() {
+	suite.db = database.DB()
+	suite.denylist = connector.NewDenylist(suite.db)
+}
+
+func (suite *TestDenylistSuite) TearDownTest() {
+	_, err := suite.db.Exec("TRUNCATE TABLE denylist")
+	suite.Nil(err)
+}
+
+func (suite *TestDenylistSuite) TestDeny() {
+	ctx := context.TODO()
+	suite.Nil(suite.denylist.Deny(ctx, "jti_1", time.Now().Add(time.Hour)))
+
+	denied, err := suite.denylist.Denied(ctx, "sid_1", "jti_1")
+	suite.Nil(err)
+	suite.True(denied)
+
+	denied, err = suite.denylist.Denied(ctx, "jti_2")
+	suite.Nil(err)
+	suite.False(denied)
+}
+
+func (suite *TestDenylistSuite) TestDenyExpired() {
+	ctx := context.TODO()
+	suite.Nil(suite.denylist.Deny(ctx, "jti_1", time.Now().Add(-time.Second)))
+
+	denied, err := suite.denylist.Denied(ctx, "jti_1")
+	suite.Nil(err)
+	suite.False(denied)
+
+	// Denying again extends the expiry.
+	suite.Nil(suite.denylist.Deny(ctx, "jti_1", time.Now().Add(time.Hour)))
+	denied, err = suite.denylist.Denied(ctx, "jti_1")
+	suite.Nil(err)
+	suite.True(denied)
+
+	suite.Nil(suite.denylist.Deny(ctx, "jti_2", time.Now().Add(-time.Second)))
+	n, err := suite.denylist.DeleteExpired(ctx)
+	suite.Nil(err)
+	suite.Equal(int64(1), n)
+}
+
+func (suite *TestDenylistSuite) TestDenySubject() {
+	ctx := context.TODO()
+	now := time.Now().Truncate(time.Second)
+	suite.Nil(suite.denylist.DenySubject(ctx, "1", now, now.Add(time.Hour)))
+
+	denied, err := suite.denylist.SubjectDenied(ctx, "1", now.Add(-time.Second))
+	suite.Nil(err)
+	suite.True(denied, "issued before the revocation")
+
+	denied, err = suite.denylist.SubjectDenied(ctx, "1", now)
+	suite.Nil(err)
+	suite.False(denied, "issued after the revocation")
+
+	denied, err = suite.denylist.SubjectDenied(ctx, "2", now.Add(-time.Second))
+	suite.Nil(err)
+	suite.False(denied, "other subjects are not denied")
+
+	denied, err = suite.denylist.Denied(ctx, "1", "sub:1")
+	suite.Nil(err)
+	suite.False(denied, "the subject is not a token id")
+}
+
+func TestDenylistTestSuite(t *testing.T) {
+	suite.Run(t, new(TestDenylistSuite))
+}
@@ -0,0 +1,185 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alextanhongpin/passport/mailer"
+)
+
+var mailQueueTable = "mail_queue"
+
+// MailQueue stores the outgoing mails until they are sent by the
+// mailer.Worker. The mails that failed permanently are kept as dead
+// letters.
+type MailQueue struct {
+	tx Tx
+}
+
+// NewMailQueue returns a new pointer to MailQueue struct.
+func NewMailQueue(tx Tx) *MailQueue {
+	return &MailQueue{tx}
+}
+
+func (q *MailQueue) WithTx(tx Tx) *MailQueue {
+	return &MailQueue{tx}
+}
+
+// Enqueue adds the message to the queue, unless a message with the same
+// idempotency key was queued before. It returns true when the message is
+// added.
+func (q *MailQueue) Enqueue(ctx context.Context, key string, msg mailer.Message) (bool, error) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return false, err
+	}
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s
+			(idempotency_key, message)
+		VALUES 	($1, $2)
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`, mailQueueTable)
+	res, err := conn(ctx, q.tx).Exec(stmt, key, string(b))
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
+
+// Claim returns the pending mails that are due, oldest first, and delays
+// their next attempt by the lease. The mails claimed by the other workers
+// are skipped.
+func (q *MailQueue) Claim(ctx context.Context, limit int, lease time.Duration) ([]mailer.Job, error) {
+	stmt := fmt.Sprintf(`
+		WITH due AS (
+			SELECT 	id
+			FROM 	%[1]s
+			WHERE 	status = 'pending'
+			AND 	next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT 	$1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE 	%[1]s q
+		SET 	next_attempt_at = now() + $2 * interval '1 millisecond'
+		FROM 	due
+		WHERE 	q.id = due.id
+		RETURNING q.id,
+			q.idempotency_key,
+			q.message,
+			q.attempts,
+			q.created_at
+	`, mailQueueTable)
+	rows, err := conn(ctx, q.tx).Query(stmt, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// MarkSent marks the mail as sent.
+func (q *MailQueue) MarkSent(ctx context.Context, id string) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	status = 'sent',
+			sent_at = now(),
+			attempts = attempts + 1,
+			last_error = ''
+		WHERE 	id = $1
+	`, mailQueueTable)
+	return q.exec(ctx, stmt, id)
+}
+
+// MarkFailed records the failed attempt and schedules the next attempt.
+func (q *MailQueue) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	attempts = attempts + 1,
+			last_error = $1,
+			next_attempt_at = $2
+		WHERE 	id = $3
+	`, mailQueueTable)
+	return q.exec(ctx, stmt, reason, retryAt, id)
+}
+
+// MarkDead records the failed attempt and stops retrying the mail.
+func (q *MailQueue) MarkDead(ctx context.Context, id, reason string) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	status = 'dead',
+			attempts = attempts + 1,
+			last_error = $1
+		WHERE 	id = $2
+	`, mailQueueTable)
+	return q.exec(ctx, stmt, reason, id)
+}
+
+// Dead returns the dead letters, newest first.
+func (q *MailQueue) Dead(ctx context.Context, limit int) ([]mailer.Job, error) {
+	stmt := fmt.Sprintf(`
+		SELECT 	id,
+			idempotency_key,
+			message,
+			attempts,
+			created_at
+		FROM 	%s
+		WHERE 	status = 'dead'
+		ORDER BY updated_at DESC
+		LIMIT 	$1
+	`, mailQueueTable)
+	rows, err := conn(ctx, q.tx).Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// Requeue retries the dead letter, e.g. after the mail server is fixed.
+func (q *MailQueue) Requeue(ctx context.Context, id string) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	status = 'pending',
+			attempts = 0,
+			next_attempt_at = now()
+		WHERE 	id = $1
+		AND 	status = 'dead'
+	`, mailQueueTable)
+	return q.exec(ctx, stmt, id)
+}
+
+func scanJobs(rows *sql.Rows) ([]mailer.Job, error) {
+	defer rows.Close()
+
+	var jobs []mailer.Job
+	for rows.Next() {
+		var j mailer.Job
+		var msg []byte
+		if err := rows.Scan(
+			&j.ID,
+			&j.Key,
+			&msg,
+			&j.Attempts,
+			&j.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(msg, &j.Message); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+func (q *MailQueue) exec(ctx context.Context, stmt string, args ...interface{}) (bool, error) {
+	res, err := conn(ctx, q.tx).Exec(stmt, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
@@ -0,0 +1,108 @@
+package connector_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport/connector"
+	"github.com/alextanhongpin/passport/examples/database"
+	"github.com/alextanhongpin/passport/mailer"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestMailQueueSuite struct {
+	suite.Suite
+	db    *sql.DB
+	queue *connector.MailQueue
+}
+
+func (suite *TestMailQueueSuite) SetupSuite() {
+	suite.db = database.DB()
+	suite.queue = connector.NewMailQueue(suite.db)
+}
+
+func (suite *TestMailQueueSuite) TearDownTest() {
+	_, err := suite.db.Exec("TRUNCATE TABLE mail_queue")
+	suite.Nil(err)
+}
+
+func (suite *TestMailQueueSuite) TestEnqueueIdempotent() {
+	ctx := context.TODO()
+	msg := mailer.Message{To: []string{"john.doe@mail.com"}, Subject: "Confirm your Email", Text: "token"}
+
+	queued, err := suite.queue.Enqueue(ctx, "key_1", msg)
+	suite.Nil(err)
+	suite.True(queued)
+
+	queued, err = suite.queue.Enqueue(ctx, "key_1", msg)
+	suite.Nil(err)
+	suite.False(queued)
+
+	jobs, err := suite.queue.Claim(ctx, 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(1, len(jobs))
+	suite.Equal("key_1", jobs[0].Key)
+	suite.Equal(msg, jobs[0].Message)
+
+	// Claimed mails are hidden until the lease expires.
+	jobs, err = suite.queue.Claim(ctx, 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(0, len(jobs))
+}
+
+func (suite *TestMailQueueSuite) TestDelivery() {
+	ctx := context.TODO()
+	msg := mailer.Message{To: []string{"john.doe@mail.com"}, Subject: "Confirm your Email", Text: "token"}
+	_, err := suite.queue.Enqueue(ctx, "key_1", msg)
+	suite.Nil(err)
+
+	jobs, err := suite.queue.Claim(ctx, 10, 0)
+	suite.Nil(err)
+	suite.Equal(1, len(jobs))
+	id := jobs[0].ID
+
+	updated, err := suite.queue.MarkFailed(ctx, id, "connection reset", time.Now())
+	suite.Nil(err)
+	suite.True(updated)
+
+	jobs, err = suite.queue.Claim(ctx, 10, 0)
+	suite.Nil(err)
+	suite.Equal(1, len(jobs))
+	suite.Equal(1, jobs[0].Attempts)
+
+	updated, err = suite.queue.MarkDead(ctx, id, "mailbox unavailable")
+	suite.Nil(err)
+	suite.True(updated)
+
+	jobs, err = suite.queue.Claim(ctx, 10, 0)
+	suite.Nil(err)
+	suite.Equal(0, len(jobs))
+
+	dead, err := suite.queue.Dead(ctx, 10)
+	suite.Nil(err)
+	suite.Equal(1, len(dead))
+	suite.Equal(id, dead[0].ID)
+
+	updated, err = suite.queue.Requeue(ctx, id)
+	suite.Nil(err)
+	suite.True(updated)
+
+	jobs, err = suite.queue.Claim(ctx, 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(1, len(jobs))
+
+	updated, err = suite.queue.MarkSent(ctx, id)
+	suite.Nil(err)
+	suite.True(updated)
+
+	updated, err = suite.queue.Requeue(ctx, id)
+	suite.Nil(err)
+	suite.False(updated)
+}
+
+func TestMailQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(TestMailQueueSuite))
+}
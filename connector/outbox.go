@@ -0,0 +1,176 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+var outboxTable = "outbox"
+
+// Outbox stores events in the same transaction as the state change, so
+// that they can be relayed later with at-least-once semantics. The events
+// that failed for the max attempts are kept as dead letters.
+type Outbox struct {
+	tx Tx
+}
+
+// NewOutbox returns a new pointer to Outbox struct.
+func NewOutbox(tx Tx) *Outbox {
+	return &Outbox{tx}
+}
+
+func (o *Outbox) WithTx(tx Tx) *Outbox {
+	return &Outbox{tx}
+}
+
+// Append adds a new event to the outbox and returns the id of the event.
+func (o *Outbox) Append(ctx context.Context, event passport.Event) (string, error) {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s
+			(type, payload)
+		VALUES 	($1, $2)
+		RETURNING id
+	`, outboxTable)
+	var id string
+	if err := conn(ctx, o.tx).QueryRow(stmt, event.Type, string(event.Payload)).Scan(&id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Claim returns the pending events that are due, oldest first, and delays
+// their next attempt by the lease. The events claimed by the other relays
+// are skipped.
+func (o *Outbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]passport.Event, error) {
+	stmt := fmt.Sprintf(`
+		WITH due AS (
+			SELECT 	id
+			FROM 	%[1]s
+			WHERE 	status = 'pending'
+			AND 	next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT 	$1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE 	%[1]s o
+		SET 	next_attempt_at = now() + $2 * interval '1 millisecond'
+		FROM 	due
+		WHERE 	o.id = due.id
+		RETURNING o.id,
+			o.type,
+			o.payload,
+			o.attempts,
+			o.created_at
+	`, outboxTable)
+	rows, err := conn(ctx, o.tx).Query(stmt, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// MarkDelivered marks the event as delivered, so that it will not be
+// relayed again.
+func (o *Outbox) MarkDelivered(ctx context.Context, id string) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	status = 'delivered',
+			delivered_at = now(),
+			attempts = attempts + 1,
+			last_error = ''
+		WHERE 	id = $1
+	`, outboxTable)
+	return o.exec(ctx, stmt, id)
+}
+
+// MarkFailed records the failed delivery and schedules the next attempt.
+func (o *Outbox) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	attempts = attempts + 1,
+			last_error = $1,
+			next_attempt_at = $2
+		WHERE 	id = $3
+	`, outboxTable)
+	return o.exec(ctx, stmt, reason, retryAt, id)
+}
+
+// MarkDead records the failed delivery and stops retrying the event.
+func (o *Outbox) MarkDead(ctx context.Context, id, reason string) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	status = 'dead',
+			attempts = attempts + 1,
+			last_error = $1
+		WHERE 	id = $2
+	`, outboxTable)
+	return o.exec(ctx, stmt, reason, id)
+}
+
+// Dead returns the dead letters, newest first.
+func (o *Outbox) Dead(ctx context.Context, limit int) ([]passport.Event, error) {
+	stmt := fmt.Sprintf(`
+		SELECT 	id,
+			type,
+			payload,
+			attempts,
+			created_at
+		FROM 	%s
+		WHERE 	status = 'dead'
+		ORDER BY updated_at DESC
+		LIMIT 	$1
+	`, outboxTable)
+	rows, err := conn(ctx, o.tx).Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// Requeue retries the dead letter, e.g. after the sink is fixed.
+func (o *Outbox) Requeue(ctx context.Context, id string) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	status = 'pending',
+			attempts = 0,
+			next_attempt_at = now()
+		WHERE 	id = $1
+		AND 	status = 'dead'
+	`, outboxTable)
+	return o.exec(ctx, stmt, id)
+}
+
+func scanEvents(rows *sql.Rows) ([]passport.Event, error) {
+	defer rows.Close()
+
+	var events []passport.Event
+	for rows.Next() {
+		var e passport.Event
+		var payload []byte
+		if err := rows.Scan(
+			&e.ID,
+			&e.Type,
+			&payload,
+			&e.Attempts,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		e.Payload = payload
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (o *Outbox) exec(ctx context.Context, stmt string, args ...interface{}) (bool, error) {
+	res, err := conn(ctx, o.tx).Exec(stmt, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
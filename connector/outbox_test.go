@@ -0,0 +1,125 @@
+package connector_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/connector"
+	"github.com/alextanhongpin/passport/examples/database"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestOutboxSuite struct {
+	suite.Suite
+	db     *sql.DB
+	outbox *connector.Outbox
+}
+
+func (suite *TestOutboxSuite) SetupSuite() {
+	suite.db = database.DB()
+	suite.outbox = connector.NewOutbox(suite.db)
+}
+
+func (suite *TestOutboxSuite) TearDownTest() {
+	_, err := suite.db.Exec("TRUNCATE TABLE outbox")
+	suite.Nil(err)
+}
+
+func (suite *TestOutboxSuite) TestAppendRollback() {
+	event, err := passport.NewEvent(passport.EventUserRegistered, map[string]string{"email": "john.doe@mail.com"})
+	suite.Nil(err)
+
+	tx, err := suite.db.Begin()
+	suite.Nil(err)
+	_, err = suite.outbox.WithTx(tx).Append(context.TODO(), event)
+	suite.Nil(err)
+	suite.Nil(tx.Rollback())
+
+	events, err := suite.outbox.Claim(context.TODO(), 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(0, len(events))
+}
+
+func (suite *TestOutboxSuite) TestDelivery() {
+	event, err := passport.NewEvent(passport.EventUserRegistered, map[string]string{"email": "john.doe@mail.com"})
+	suite.Nil(err)
+	id, err := suite.outbox.Append(context.TODO(), event)
+	suite.Nil(err)
+
+	events, err := suite.outbox.Claim(context.TODO(), 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(1, len(events))
+	suite.Equal(id, events[0].ID)
+
+	var payload map[string]string
+	suite.Nil(events[0].Decode(&payload))
+	suite.Equal("john.doe@mail.com", payload["email"])
+
+	// Claimed events are hidden until the lease expires.
+	events, err = suite.outbox.Claim(context.TODO(), 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(0, len(events))
+
+	// Failed deliveries are not pending until the next attempt.
+	updated, err := suite.outbox.MarkFailed(context.TODO(), id, "bad sink", time.Now().Add(time.Hour))
+	suite.Nil(err)
+	suite.True(updated)
+
+	updated, err = suite.outbox.MarkDelivered(context.TODO(), id)
+	suite.Nil(err)
+	suite.True(updated)
+}
+
+func (suite *TestOutboxSuite) TestClaimSkipLocked() {
+	event, err := passport.NewEvent(passport.EventUserRegistered, map[string]string{"email": "john.doe@mail.com"})
+	suite.Nil(err)
+	_, err = suite.outbox.Append(context.TODO(), event)
+	suite.Nil(err)
+
+	// The event claimed by a relay that has not committed yet is
+	// skipped by the other relays.
+	tx, err := suite.db.Begin()
+	suite.Nil(err)
+	defer tx.Rollback()
+	events, err := suite.outbox.WithTx(tx).Claim(context.TODO(), 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(1, len(events))
+
+	events, err = suite.outbox.Claim(context.TODO(), 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(0, len(events))
+}
+
+func (suite *TestOutboxSuite) TestDeadLetter() {
+	event, err := passport.NewEvent(passport.EventUserRegistered, map[string]string{"email": "john.doe@mail.com"})
+	suite.Nil(err)
+	id, err := suite.outbox.Append(context.TODO(), event)
+	suite.Nil(err)
+
+	updated, err := suite.outbox.MarkDead(context.TODO(), id, "bad sink")
+	suite.Nil(err)
+	suite.True(updated)
+
+	// Dead letters are not retried until they are requeued.
+	dead, err := suite.outbox.Dead(context.TODO(), 10)
+	suite.Nil(err)
+	suite.Equal(1, len(dead))
+	suite.Equal(1, dead[0].Attempts)
+
+	updated, err = suite.outbox.Requeue(context.TODO(), id)
+	suite.Nil(err)
+	suite.True(updated)
+
+	events, err := suite.outbox.Claim(context.TODO(), 10, time.Minute)
+	suite.Nil(err)
+	suite.Equal(1, len(events))
+	suite.Equal(0, events[0].Attempts)
+}
+
+func TestOutboxTestSuite(t *testing.T) {
+	suite.Run(t, new(TestOutboxSuite))
+}
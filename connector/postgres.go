@@ -3,9 +3,13 @@ package connector
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/alextanhongpin/passport"
+
+	"github.com/lib/pq"
 )
 
 // Postgres represents an implementation of the repository for User.
@@ -24,101 +28,387 @@ func (p *Postgres) WithTx(tx Tx) *Postgres {
 
 func (p *Postgres) WithEmail(ctx context.Context, email string) (*passport.User, error) {
 	stmt := selectUserStmt(table, "email = $1")
-	return getUser(p.tx, stmt, email)
+	return getUser(conn(ctx, p.tx), stmt, email)
 }
 
-func (p *Postgres) Create(ctx context.Context, email, encryptedPassword string) (*passport.User, error) {
+// Create creates the user with the email, and the optional username and
+// phone in identifiers.
+func (p *Postgres) Create(ctx context.Context, email, encryptedPassword string, identifiers ...passport.Identifier) (*passport.User, error) {
+	var username, phone sql.NullString
+	for _, identifier := range identifiers {
+		switch identifier.Kind {
+		case passport.IdentifierUsername:
+			username = NewNullString(identifier.Value)
+		case passport.IdentifierPhone:
+			phone = NewNullString(identifier.Value)
+		default:
+			return nil, passport.ErrIdentifierUnsupported
+		}
+	}
 	stmt := fmt.Sprintf(`
 		INSERT INTO %s
-			(email, encrypted_password, unconfirmed_email)
-		VALUES 	($1, $2, $1)
+			(email, encrypted_password, unconfirmed_email, username, phone)
+		VALUES 	($1, $2, $1, $3, $4)
 		RETURNING id
 	`, table)
 	var u passport.User
-	if err := p.tx.QueryRow(stmt, email, encryptedPassword).Scan(&u.ID); err != nil {
-		return nil, err
+	if err := conn(ctx, p.tx).QueryRow(stmt, email, encryptedPassword, username, phone).Scan(&u.ID); err != nil {
+		return nil, duplicateError(err)
 	}
 	return &u, nil
 }
 
-func (p *Postgres) UpdateRecoverable(ctx context.Context, email string, recoverable passport.Recoverable) (bool, error) {
+// WithIdentifier finds the user by the email, username or phone.
+func (p *Postgres) WithIdentifier(ctx context.Context, identifier passport.Identifier) (*passport.User, error) {
+	column, err := identifierColumn(identifier.Kind)
+	if err != nil {
+		return nil, err
+	}
+	stmt := selectUserStmt(table, column+" = $1")
+	return getUser(conn(ctx, p.tx), stmt, identifier.Value)
+}
+
+// UpdateIdentifier sets the username or phone of the user, or clears it when
+// the value is empty. The email is changed with UpdateConfirmable instead,
+// so that it is confirmed.
+func (p *Postgres) UpdateIdentifier(ctx context.Context, userID string, version int, identifier passport.Identifier) (bool, error) {
+	if !identifier.In(passport.IdentifierUsername, passport.IdentifierPhone) {
+		return false, passport.ErrIdentifierUnsupported
+	}
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	%s = $1,
+			version = version + 1
+		WHERE 	id = $2
+		AND 	deleted_at IS NULL
+		AND 	version = $3
+	`, table, identifier.Kind)
+	res, err := conn(ctx, p.tx).Exec(stmt, NewNullString(identifier.Value), userID, version)
+	if err != nil {
+		return false, duplicateError(err)
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
+}
+
+func (p *Postgres) UpdateRecoverable(ctx context.Context, email string, version int, recoverable passport.Recoverable) (bool, error) {
 	stmt := fmt.Sprintf(`
 		UPDATE  %s
 		SET 	reset_password_token = $1,
 			reset_password_sent_at = $2,
-			allow_password_change = $3
-		WHERE 	email = $4
+			reset_password_sent_count = $3,
+			allow_password_change = $4,
+			version = version + 1
+		WHERE 	email = $5
+		AND 	deleted_at IS NULL
+		AND 	version = $6
 	`, table)
-	res, err := p.tx.Exec(stmt,
+	res, err := conn(ctx, p.tx).Exec(stmt,
 		NewNullString(recoverable.ResetPasswordToken),
 		NewNullTime(recoverable.ResetPasswordSentAt),
+		recoverable.ResetPasswordSentCount,
 		recoverable.AllowPasswordChange,
 		email,
+		version,
 	)
 	if err != nil {
 		return false, err
 	}
-	rows, err := res.RowsAffected()
-	return rows > 0, err
+	return checkUpdated(conn(ctx, p.tx), res, "email = $1", email)
+}
+
+// UpdateRecoverableSigned is like UpdateRecoverable, except that the reset
+// password token is not written, since the signed tokens are not stored.
+func (p *Postgres) UpdateRecoverableSigned(ctx context.Context, email string, version int, recoverable passport.Recoverable) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	reset_password_sent_at = $1,
+			reset_password_sent_count = $2,
+			allow_password_change = $3,
+			version = version + 1
+		WHERE 	email = $4
+		AND 	deleted_at IS NULL
+		AND 	version = $5
+	`, table)
+	res, err := conn(ctx, p.tx).Exec(stmt,
+		NewNullTime(recoverable.ResetPasswordSentAt),
+		recoverable.ResetPasswordSentCount,
+		recoverable.AllowPasswordChange,
+		email,
+		version,
+	)
+	if err != nil {
+		return false, err
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "email = $1", email)
 }
 
 func (p *Postgres) WithResetPasswordToken(ctx context.Context, token string) (*passport.User, error) {
 	stmt := selectUserStmt(table, "reset_password_token = $1")
-	return getUser(p.tx, stmt, token)
+	return getUser(conn(ctx, p.tx), stmt, token)
 }
 
-func (p *Postgres) UpdatePassword(ctx context.Context, userID string, encryptedPassword string) (bool, error) {
+func (p *Postgres) UpdatePassword(ctx context.Context, userID string, version int, encryptedPassword string) (bool, error) {
 	stmt := fmt.Sprintf(`
 		UPDATE  %s
-		SET 	encrypted_password = $1
+		SET 	encrypted_password = $1,
+			version = version + 1
 		WHERE 	id = $2
+		AND 	deleted_at IS NULL
+		AND 	version = $3
 	`, table)
-	res, err := p.tx.Exec(stmt, encryptedPassword, userID)
+	res, err := conn(ctx, p.tx).Exec(stmt, encryptedPassword, userID, version)
 	if err != nil {
 		return false, err
 	}
-	rows, err := res.RowsAffected()
-	return rows > 0, err
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
+}
+
+// Lock locks the account until the password is reset.
+func (p *Postgres) Lock(ctx context.Context, userID string, version int) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	locked_at = now(),
+			version = version + 1
+		WHERE 	id = $1
+		AND 	deleted_at IS NULL
+		AND 	version = $2
+	`, table)
+	res, err := conn(ctx, p.tx).Exec(stmt, userID, version)
+	if err != nil {
+		return false, err
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
 }
 
-func (p *Postgres) UpdateConfirmable(ctx context.Context, email string, confirmable passport.Confirmable) (bool, error) {
+func (p *Postgres) Unlock(ctx context.Context, userID string, version int) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	locked_at = NULL,
+			version = version + 1
+		WHERE 	id = $1
+		AND 	deleted_at IS NULL
+		AND 	version = $2
+	`, table)
+	res, err := conn(ctx, p.tx).Exec(stmt, userID, version)
+	if err != nil {
+		return false, err
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
+}
+
+func (p *Postgres) UpdateConfirmable(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error) {
 	stmt := fmt.Sprintf(`
 		UPDATE  %s
 		SET 	email = COALESCE(NULLIF($4, ''), email),
 			confirmation_token = $1,
 			confirmation_sent_at = $2,
 			confirmed_at = COALESCE($3, now()),
-			unconfirmed_email = $4
+			unconfirmed_email = $4,
+			confirmation_sent_count = $7,
+			version = version + 1
 		WHERE 	email = $5
+		AND 	deleted_at IS NULL
+		AND 	version = $6
 	`, table)
 
-	res, err := p.tx.Exec(stmt,
+	res, err := conn(ctx, p.tx).Exec(stmt,
 		NewNullString(confirmable.ConfirmationToken),
 		NewNullTime(confirmable.ConfirmationSentAt),
 		NewNullTime(confirmable.ConfirmedAt),
 		confirmable.UnconfirmedEmail,
 		email,
+		version,
+		confirmable.ConfirmationSentCount,
 	)
 	if err != nil {
-		return false, err
+		return false, duplicateError(err)
 	}
-	rows, err := res.RowsAffected()
-	return rows > 0, err
+	return checkUpdated(conn(ctx, p.tx), res, "email = $1", email)
+}
+
+// UpdateConfirmableSigned is like UpdateConfirmable, except that the
+// confirmation token is not written, since the signed tokens are not stored.
+func (p *Postgres) UpdateConfirmableSigned(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	email = COALESCE(NULLIF($3, ''), email),
+			confirmation_sent_at = $1,
+			confirmed_at = COALESCE($2, now()),
+			unconfirmed_email = $3,
+			confirmation_sent_count = $6,
+			version = version + 1
+		WHERE 	email = $4
+		AND 	deleted_at IS NULL
+		AND 	version = $5
+	`, table)
+
+	res, err := conn(ctx, p.tx).Exec(stmt,
+		NewNullTime(confirmable.ConfirmationSentAt),
+		NewNullTime(confirmable.ConfirmedAt),
+		confirmable.UnconfirmedEmail,
+		email,
+		version,
+		confirmable.ConfirmationSentCount,
+	)
+	if err != nil {
+		return false, duplicateError(err)
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "email = $1", email)
 }
 
 func (p *Postgres) WithConfirmationToken(ctx context.Context, token string) (*passport.User, error) {
 	stmt := selectUserStmt(table, "confirmation_token = $1")
-	return getUser(p.tx, stmt, token)
+	return getUser(conn(ctx, p.tx), stmt, token)
+}
+
+// ConsumeResetPasswordToken clears the reset password token and returns the
+// user in a single statement, so that each token can only be used once. Only
+// tokens sent after sentAfter can be consumed. Resetting the password also
+// unlocks the account, but only with a token sent after the account was
+// locked, e.g. not with a token requested by whoever changed the email
+// before the change was reverted.
+func (p *Postgres) ConsumeResetPasswordToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	reset_password_token = NULL,
+			reset_password_sent_at = NULL,
+			allow_password_change = false,
+			locked_at = NULL,
+			version = version + 1
+		WHERE 	reset_password_token = $1
+		AND 	deleted_at IS NULL
+		AND 	reset_password_sent_at > $2
+		AND 	allow_password_change
+		AND 	(locked_at IS NULL OR reset_password_sent_at > locked_at)
+		RETURNING %s
+	`, table, userColumns)
+	return getUser(conn(ctx, p.tx), stmt, token, sentAfter)
 }
 
+// ConsumeConfirmationToken confirms the unconfirmed email and clears the
+// confirmation token in a single statement, so that each token can only be
+// used once. Only tokens sent after sentAfter can be consumed.
+func (p *Postgres) ConsumeConfirmationToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	email = COALESCE(NULLIF(unconfirmed_email, ''), email),
+			confirmation_token = NULL,
+			confirmation_sent_at = NULL,
+			confirmed_at = now(),
+			unconfirmed_email = '',
+			version = version + 1
+		WHERE 	confirmation_token = $1
+		AND 	deleted_at IS NULL
+		AND 	confirmation_sent_at > $2
+		RETURNING %s
+	`, table, userColumns)
+	return getUser(conn(ctx, p.tx), stmt, token, sentAfter)
+}
+
+func (p *Postgres) UpdateRevertable(ctx context.Context, userID string, version int, revertable passport.Revertable) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	previous_email = $1,
+			revert_email_token = $2,
+			revert_email_sent_at = $3,
+			version = version + 1
+		WHERE 	id = $4
+		AND 	deleted_at IS NULL
+		AND 	version = $5
+	`, table)
+	res, err := conn(ctx, p.tx).Exec(stmt,
+		revertable.PreviousEmail,
+		NewNullString(revertable.RevertEmailToken),
+		NewNullTime(revertable.RevertEmailSentAt),
+		userID,
+		version,
+	)
+	if err != nil {
+		return false, err
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
+}
+
+func (p *Postgres) WithRevertEmailToken(ctx context.Context, token string) (*passport.User, error) {
+	stmt := selectUserStmt(table, "revert_email_token = $1")
+	return getUser(conn(ctx, p.tx), stmt, token)
+}
+
+// RestoreEmail cancels the pending email change by restoring the previous
+// email, and clears the confirmation and revert email tokens.
+func (p *Postgres) RestoreEmail(ctx context.Context, userID string, version int) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	email = previous_email,
+			confirmation_token = NULL,
+			confirmation_sent_at = NULL,
+			unconfirmed_email = '',
+			previous_email = '',
+			revert_email_token = NULL,
+			revert_email_sent_at = NULL,
+			version = version + 1
+		WHERE 	id = $1
+		AND 	deleted_at IS NULL
+		AND 	version = $2
+		AND 	previous_email <> ''
+	`, table)
+	res, err := conn(ctx, p.tx).Exec(stmt, userID, version)
+	if err != nil {
+		if PgDuplicateError(err) {
+			return false, passport.ErrEmailExists
+		}
+		return false, err
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
+}
+
+// ConsumeRevertEmailToken restores the previous email, clears the pending
+// email change and locks the account in a single statement, so that each
+// token can only be used once. Only tokens sent after sentAfter can be
+// consumed. The pending reset password token is cleared too, since it may
+// have been sent to the email that is reverted.
+func (p *Postgres) ConsumeRevertEmailToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	email = previous_email,
+			confirmation_token = NULL,
+			confirmation_sent_at = NULL,
+			unconfirmed_email = '',
+			previous_email = '',
+			revert_email_token = NULL,
+			revert_email_sent_at = NULL,
+			reset_password_token = NULL,
+			reset_password_sent_at = NULL,
+			allow_password_change = false,
+			locked_at = now(),
+			version = version + 1
+		WHERE 	revert_email_token = $1
+		AND 	deleted_at IS NULL
+		AND 	revert_email_sent_at > $2
+		AND 	previous_email <> ''
+		RETURNING %s
+	`, table, userColumns)
+	user, err := getUser(conn(ctx, p.tx), stmt, token, sentAfter)
+	if PgDuplicateError(err) {
+		return nil, passport.ErrEmailExists
+	}
+	return user, err
+}
+
+// HasEmail checks if the email is taken. The previous email of a pending
+// email change is reserved until the revert email token is consumed or
+// cleared, so that the change can still be reverted.
 func (p *Postgres) HasEmail(ctx context.Context, email string) (bool, error) {
 	stmt := fmt.Sprintf(`
 		SELECT EXISTS (
-			SELECT 1 FROM %s WHERE email = $1
+			SELECT 	1
+			FROM 	%s
+			WHERE 	(email = $1 OR (previous_email = $1 AND revert_email_token IS NOT NULL))
+			AND 	deleted_at IS NULL
 		)
 	`, table)
 	var exists bool
-	if err := p.tx.QueryRow(stmt, email).Scan(&exists); err != nil {
+	if err := conn(ctx, p.tx).QueryRow(stmt, email).Scan(&exists); err != nil {
 		return false, err
 	}
 	return exists, nil
@@ -126,30 +416,328 @@ func (p *Postgres) HasEmail(ctx context.Context, email string) (bool, error) {
 
 func (p *Postgres) Find(ctx context.Context, id string) (*passport.User, error) {
 	stmt := selectUserStmt(table, "id = $1")
-	return getUser(p.tx, stmt, id)
+	return getUser(conn(ctx, p.tx), stmt, id)
+}
+
+// FindIncludingDeleted is like Find, except that it also returns the users
+// that are deleted but not erased yet, e.g. to export their data within the
+// grace period.
+func (p *Postgres) FindIncludingDeleted(ctx context.Context, id string) (*passport.User, error) {
+	stmt := fmt.Sprintf(`
+		SELECT 	%s
+		FROM 	%s
+		WHERE 	id = $1
+		AND 	erased_at IS NULL
+	`, userColumns, table)
+	return getUser(conn(ctx, p.tx), stmt, id)
+}
+
+// Delete soft deletes the user. The deleted users are excluded from the
+// queries, and their email can be registered again.
+func (p *Postgres) Delete(ctx context.Context, userID string, version int) (bool, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	deleted_at = now(),
+			version = version + 1
+		WHERE 	id = $1
+		AND 	deleted_at IS NULL
+		AND 	version = $2
+	`, table)
+	res, err := conn(ctx, p.tx).Exec(stmt, userID, version)
+	if err != nil {
+		return false, err
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
+}
+
+// EraseDeleted anonymizes the users deleted before deletedBefore, and
+// returns the number of users erased. The rows are kept with their id, so
+// that the audit log can still refer to them. In the same statement, the
+// identifier, IP and user agent of their audit entries are erased, the
+// mails and events sent to them before the deletion are cleared and
+// dead-lettered when pending, and the rate limits of their identifiers are
+// removed, unless the identifiers are used by another user.
+func (p *Postgres) EraseDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	stmt := fmt.Sprintf(`
+		WITH erased AS (
+			SELECT 	id,
+				deleted_at,
+				ARRAY[email, unconfirmed_email, previous_email, username, phone] AS identifiers
+			FROM 	%[1]s
+			WHERE 	deleted_at < $1
+			AND 	erased_at IS NULL
+			FOR UPDATE
+		), identifiers AS (
+			SELECT 	e.deleted_at,
+				i AS identifier
+			FROM 	erased e, unnest(e.identifiers) i
+			WHERE 	i <> ''
+		), audit AS (
+			UPDATE 	%[2]s a
+			SET 	identifier = '',
+				ip = '',
+				user_agent = '',
+				erased_at = now()
+			WHERE 	a.erased_at IS NULL
+			AND 	(
+				a.user_id IN (SELECT id::TEXT FROM erased)
+				OR a.actor_id IN (SELECT id::TEXT FROM erased)
+				OR (a.user_id = '' AND EXISTS (
+					SELECT 	1
+					FROM 	identifiers i
+					WHERE 	i.identifier = a.identifier
+					AND 	a.created_at < i.deleted_at
+				))
+			)
+		), mails AS (
+			UPDATE 	%[3]s q
+			SET 	message = '{}',
+				status = CASE WHEN q.status = 'pending' THEN 'dead' ELSE q.status END,
+				last_error = CASE WHEN q.status = 'pending' THEN 'erased' ELSE q.last_error END
+			FROM 	identifiers i
+			WHERE 	q.message->'to' ? i.identifier
+			AND 	q.created_at < i.deleted_at
+		), events AS (
+			UPDATE 	%[4]s o
+			SET 	payload = '{}',
+				status = CASE WHEN o.status = 'pending' THEN 'dead' ELSE o.status END,
+				last_error = CASE WHEN o.status = 'pending' THEN 'erased' ELSE o.last_error END
+			FROM 	identifiers i
+			WHERE 	o.payload->>'email' = i.identifier
+			AND 	o.created_at < i.deleted_at
+		), limits AS (
+			DELETE FROM %[5]s r
+			USING 	identifiers i
+			WHERE 	right(r.key, length(i.identifier) + 1) = ':' || lower(i.identifier)
+			AND 	NOT EXISTS (
+				SELECT 	1
+				FROM 	%[1]s l
+				WHERE 	l.deleted_at IS NULL
+				AND 	lower(i.identifier) IN (lower(l.email), l.username, l.phone)
+			)
+		)
+		UPDATE  %[1]s l
+		SET 	email = '',
+			username = NULL,
+			phone = NULL,
+			encrypted_password = '',
+			confirmation_token = NULL,
+			confirmation_sent_at = NULL,
+			confirmed_at = NULL,
+			unconfirmed_email = '',
+			reset_password_token = NULL,
+			reset_password_sent_at = NULL,
+			allow_password_change = false,
+			previous_email = '',
+			revert_email_token = NULL,
+			revert_email_sent_at = NULL,
+			extra = '{}',
+			erased_at = now(),
+			version = version + 1
+		FROM 	erased e
+		WHERE 	l.id = e.id
+	`, table, auditTable, mailQueueTable, outboxTable, rateLimitTable)
+	res, err := conn(ctx, p.tx).Exec(stmt, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MergeExtra adds the keys of extra to the extra data of the user, replacing
+// the values of the existing keys, in a single statement.
+func (p *Postgres) MergeExtra(ctx context.Context, userID string, extra passport.Extra) (*passport.User, error) {
+	b, err := marshalExtra(extra)
+	if err != nil {
+		return nil, err
+	}
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	extra = extra || $1::JSONB,
+			version = version + 1
+		WHERE 	id = $2
+		AND 	deleted_at IS NULL
+		RETURNING %s
+	`, table, userColumns)
+	return getUser(conn(ctx, p.tx), stmt, b, userID)
+}
+
+// PatchExtra is like MergeExtra, except that the keys set to nil are
+// removed, like a JSON merge patch of the top-level keys.
+func (p *Postgres) PatchExtra(ctx context.Context, userID string, patch passport.Extra) (*passport.User, error) {
+	var (
+		set     = make(passport.Extra)
+		removed = []string{}
+	)
+	for key, value := range patch {
+		if value == nil {
+			removed = append(removed, key)
+			continue
+		}
+		set[key] = value
+	}
+	b, err := marshalExtra(set)
+	if err != nil {
+		return nil, err
+	}
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	extra = (extra || $1::JSONB) - $2::TEXT[],
+			version = version + 1
+		WHERE 	id = $3
+		AND 	deleted_at IS NULL
+		RETURNING %s
+	`, table, userColumns)
+	return getUser(conn(ctx, p.tx), stmt, b, pq.Array(removed), userID)
+}
+
+// ReplaceExtra replaces the extra data of the user. Unlike MergeExtra and
+// PatchExtra, the extra data is usually read first, so the update is
+// conditional on the version.
+func (p *Postgres) ReplaceExtra(ctx context.Context, userID string, version int, extra passport.Extra) (bool, error) {
+	b, err := marshalExtra(extra)
+	if err != nil {
+		return false, err
+	}
+	stmt := fmt.Sprintf(`
+		UPDATE  %s
+		SET 	extra = $1,
+			version = version + 1
+		WHERE 	id = $2
+		AND 	deleted_at IS NULL
+		AND 	version = $3
+	`, table)
+	res, err := conn(ctx, p.tx).Exec(stmt, b, userID, version)
+	if err != nil {
+		return false, err
+	}
+	return checkUpdated(conn(ctx, p.tx), res, "id = $1", userID)
+}
+
+// FindByExtra returns the users whose extra data contains all the keys and
+// values of match, oldest first, e.g. passport.Extra{"username": "john"}.
+func (p *Postgres) FindByExtra(ctx context.Context, match passport.Extra) ([]passport.User, error) {
+	b, err := marshalExtra(match)
+	if err != nil {
+		return nil, err
+	}
+	stmt := selectUserStmt(table, "extra @> $1::JSONB") + "ORDER BY created_at"
+	return getUsers(conn(ctx, p.tx), stmt, b)
+}
+
+// marshalExtra encodes the extra data as a JSON object.
+func marshalExtra(extra passport.Extra) (string, error) {
+	if extra == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(extra)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// checkUpdated distinguishes a missing user from a user that has been
+// updated by someone else when no rows are affected by a versioned update.
+func checkUpdated(tx Tx, res sql.Result, where string, arguments ...interface{}) (bool, error) {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows > 0 {
+		return true, nil
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM %s WHERE %s AND deleted_at IS NULL
+		)
+	`, table, where)
+	var exists bool
+	if err := tx.QueryRow(stmt, arguments...).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, passport.ErrConcurrentModification
+	}
+	return false, nil
+}
+
+// identifierColumn returns the column of the kind of identifier.
+func identifierColumn(kind string) (string, error) {
+	switch kind {
+	case passport.IdentifierEmail, passport.IdentifierUsername, passport.IdentifierPhone:
+		return kind, nil
+	default:
+		return "", passport.ErrIdentifierUnsupported
+	}
 }
 
 func getUser(tx Tx, stmt string, arguments ...interface{}) (*passport.User, error) {
+	return scanUser(tx.QueryRow(stmt, arguments...))
+}
+
+func getUsers(tx Tx, stmt string, arguments ...interface{}) ([]passport.User, error) {
+	rows, err := tx.Query(stmt, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []passport.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	return users, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (*passport.User, error) {
 	var u passport.User
-	var resetPasswordToken, confirmationToken sql.NullString
-	var resetPasswordSentAt, confirmationSentAt, confirmedAt sql.NullTime
+	var username, phone, resetPasswordToken, confirmationToken, revertEmailToken sql.NullString
+	var resetPasswordSentAt, confirmationSentAt, confirmedAt, revertEmailSentAt, lockedAt, deletedAt sql.NullTime
 	var encryptedPassword string
-	if err := tx.QueryRow(stmt, arguments...).Scan(
+	var extra []byte
+	if err := row.Scan(
 		&u.ID,
 		&u.CreatedAt,
 		&u.Email,
+		&username,
+		&phone,
 		&encryptedPassword,
 		&resetPasswordToken,
 		&resetPasswordSentAt,
+		&u.Recoverable.ResetPasswordSentCount,
 		&u.Recoverable.AllowPasswordChange,
 		&confirmationToken,
 		&confirmationSentAt,
+		&u.Confirmable.ConfirmationSentCount,
 		&confirmedAt,
 		&u.Confirmable.UnconfirmedEmail,
+		&u.Revertable.PreviousEmail,
+		&revertEmailToken,
+		&revertEmailSentAt,
+		&lockedAt,
+		&deletedAt,
+		&extra,
+		&u.Version,
 	); err != nil {
 		return nil, err
 	}
 
+	if username.Valid {
+		u.Username = username.String
+	}
+	if phone.Valid {
+		u.Phone = phone.String
+	}
 	if resetPasswordToken.Valid {
 		u.Recoverable.ResetPasswordToken = resetPasswordToken.String
 	}
@@ -165,6 +753,23 @@ func getUser(tx Tx, stmt string, arguments ...interface{}) (*passport.User, erro
 	if confirmedAt.Valid {
 		u.Confirmable.ConfirmedAt = confirmedAt.Time
 	}
+	if revertEmailToken.Valid {
+		u.Revertable.RevertEmailToken = revertEmailToken.String
+	}
+	if revertEmailSentAt.Valid {
+		u.Revertable.RevertEmailSentAt = revertEmailSentAt.Time
+	}
+	if lockedAt.Valid {
+		u.Lockable.LockedAt = lockedAt.Time
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = deletedAt.Time
+	}
+	if len(extra) > 0 {
+		if err := json.Unmarshal(extra, &u.Extra); err != nil {
+			return nil, err
+		}
+	}
 	u.EncryptedPassword = passport.NewPassword(encryptedPassword)
 	return &u, nil
 }
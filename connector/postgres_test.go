@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/alextanhongpin/passport"
 	"github.com/alextanhongpin/passport/connector"
 	"github.com/alextanhongpin/passport/examples/database"
+	"github.com/alextanhongpin/passport/mailer"
 	"github.com/alextanhongpin/passport/usecase"
 
 	"github.com/stretchr/testify/suite"
@@ -77,13 +80,13 @@ func (suite *TestPostgresSuite) TestWithEmailSuccess() {
 }
 
 func (suite *TestPostgresSuite) TestUpdateRecoverableNoRows() {
-	updated, err := suite.repository.UpdateRecoverable(context.TODO(), "jane@mail.com", passport.Recoverable{})
+	updated, err := suite.repository.UpdateRecoverable(context.TODO(), "jane@mail.com", 0, passport.Recoverable{})
 	suite.False(updated)
 	suite.Nil(err)
 }
 
 func (suite *TestPostgresSuite) TestUpdateRecoverableSuccess() {
-	updated, err := suite.repository.UpdateRecoverable(context.TODO(), suite.user.Email, passport.Recoverable{
+	updated, err := suite.repository.UpdateRecoverable(context.TODO(), suite.user.Email, 0, passport.Recoverable{
 		ResetPasswordToken:  "token_1",
 		AllowPasswordChange: true,
 	})
@@ -98,15 +101,32 @@ func (suite *TestPostgresSuite) TestWithResetPasswordTokenNoRows() {
 }
 
 func (suite *TestPostgresSuite) TestUpdatePasswordSuccess() {
-	updated, err := suite.repository.UpdatePassword(context.TODO(), suite.user.ID, "abc")
+	updated, err := suite.repository.UpdatePassword(context.TODO(), suite.user.ID, 0, "abc")
 	suite.Nil(err)
 	suite.True(updated)
 }
 
+func (suite *TestPostgresSuite) TestUpdatePasswordConcurrentModification() {
+	updated, err := suite.repository.UpdatePassword(context.TODO(), suite.user.ID, 0, "abc")
+	suite.Nil(err)
+	suite.True(updated)
+
+	// The version read is now stale.
+	updated, err = suite.repository.UpdatePassword(context.TODO(), suite.user.ID, 0, "def")
+	suite.False(updated)
+	suite.Equal(passport.ErrConcurrentModification, err)
+
+	user, err := suite.repository.Find(context.TODO(), suite.user.ID)
+	suite.Nil(err)
+	suite.Equal(1, user.Version)
+	suite.Equal("abc", user.EncryptedPassword.Value())
+}
+
 func (suite *TestPostgresSuite) TestUpdateConfirmableSuccess() {
 	updated, err := suite.repository.UpdateConfirmable(
 		context.TODO(),
 		suite.user.Email,
+		0,
 		passport.Confirmable{},
 	)
 	suite.Nil(err)
@@ -132,6 +152,424 @@ func (suite *TestPostgresSuite) TestFind() {
 	suite.Equal(suite.user.ID, user.ID)
 }
 
+func (suite *TestPostgresSuite) TestConsumeResetPasswordTokenOnce() {
+	updated, err := suite.repository.UpdateRecoverable(context.TODO(), suite.user.Email, 0, passport.NewRecoverable("token_1"))
+	suite.Nil(err)
+	suite.True(updated)
+
+	consumed := consumeConcurrently(10, func() error {
+		_, err := suite.repository.ConsumeResetPasswordToken(context.TODO(), "token_1", time.Now().Add(-time.Hour))
+		return err
+	})
+	suite.Equal(1, consumed)
+}
+
+func (suite *TestPostgresSuite) TestConsumeResetPasswordTokenExpired() {
+	updated, err := suite.repository.UpdateRecoverable(context.TODO(), suite.user.Email, 0, passport.NewRecoverable("token_1"))
+	suite.Nil(err)
+	suite.True(updated)
+
+	user, err := suite.repository.ConsumeResetPasswordToken(context.TODO(), "token_1", time.Now().Add(time.Minute))
+	suite.Nil(user)
+	suite.Equal(sql.ErrNoRows, err)
+}
+
+func (suite *TestPostgresSuite) TestConsumeConfirmationTokenOnce() {
+	confirmable := passport.NewConfirmable("token_1", suite.user.Email)
+	updated, err := suite.repository.UpdateConfirmable(context.TODO(), suite.user.Email, 0, confirmable)
+	suite.Nil(err)
+	suite.True(updated)
+
+	consumed := consumeConcurrently(10, func() error {
+		_, err := suite.repository.ConsumeConfirmationToken(context.TODO(), "token_1", time.Now().Add(-time.Hour))
+		return err
+	})
+	suite.Equal(1, consumed)
+
+	user, err := suite.repository.Find(context.TODO(), suite.user.ID)
+	suite.Nil(err)
+	suite.True(user.Verified())
+}
+
+func (suite *TestPostgresSuite) TestUpdateSigned() {
+	ctx := context.TODO()
+	updated, err := suite.repository.UpdateRecoverable(ctx, suite.user.Email, 0, passport.NewRecoverable("token_1"))
+	suite.Nil(err)
+	suite.True(updated)
+	updated, err = suite.repository.UpdateConfirmable(ctx, suite.user.Email, 1, passport.NewConfirmable("token_2", suite.user.Email))
+	suite.Nil(err)
+	suite.True(updated)
+
+	// The signed updates do not write the token columns.
+	recoverable := passport.NewRecoverable("")
+	recoverable.ResetPasswordSentCount = 2
+	updated, err = suite.repository.UpdateRecoverableSigned(ctx, suite.user.Email, 2, recoverable)
+	suite.Nil(err)
+	suite.True(updated)
+
+	confirmable := passport.NewConfirmable("", suite.user.Email)
+	confirmable.ConfirmationSentCount = 2
+	updated, err = suite.repository.UpdateConfirmableSigned(ctx, suite.user.Email, 3, confirmable)
+	suite.Nil(err)
+	suite.True(updated)
+
+	user, err := suite.repository.Find(ctx, suite.user.ID)
+	suite.Nil(err)
+	suite.Equal("token_1", user.ResetPasswordToken)
+	suite.Equal(2, user.ResetPasswordSentCount)
+	suite.Equal("token_2", user.ConfirmationToken)
+	suite.Equal(2, user.ConfirmationSentCount)
+	suite.Equal(4, user.Version)
+}
+
+func (suite *TestPostgresSuite) TestConsumeRevertEmailTokenOnce() {
+	revertable := passport.NewRevertableAt("token_1", suite.user.Email, time.Now())
+	updated, err := suite.repository.UpdateRevertable(context.TODO(), suite.user.ID, 0, revertable)
+	suite.Nil(err)
+	suite.True(updated)
+
+	confirmable := passport.NewConfirmable("token_2", "jane.doe@mail.com")
+	updated, err = suite.repository.UpdateConfirmable(context.TODO(), suite.user.Email, 1, confirmable)
+	suite.Nil(err)
+	suite.True(updated)
+
+	consumed := consumeConcurrently(10, func() error {
+		_, err := suite.repository.ConsumeRevertEmailToken(context.TODO(), "token_1", time.Now().Add(-time.Hour))
+		return err
+	})
+	suite.Equal(1, consumed)
+
+	user, err := suite.repository.Find(context.TODO(), suite.user.ID)
+	suite.Nil(err)
+	suite.Equal(suite.user.Email, user.Email)
+	suite.Equal("", user.UnconfirmedEmail)
+	suite.True(user.Locked())
+}
+
+func (suite *TestPostgresSuite) TestConsumeResetPasswordTokenLocked() {
+	ctx := context.TODO()
+	updated, err := suite.repository.UpdateRecoverable(ctx, suite.user.Email, 0, passport.NewRecoverableAt("token_1", time.Now().Add(-time.Minute)))
+	suite.Nil(err)
+	suite.True(updated)
+	locked, err := suite.repository.Lock(ctx, suite.user.ID, 1)
+	suite.Nil(err)
+	suite.True(locked)
+
+	// The token sent before the lock cannot unlock the account.
+	_, err = suite.repository.ConsumeResetPasswordToken(ctx, "token_1", time.Now().Add(-time.Hour))
+	suite.Equal(sql.ErrNoRows, err)
+
+	updated, err = suite.repository.UpdateRecoverable(ctx, suite.user.Email, 2, passport.NewRecoverableAt("token_2", time.Now().Add(time.Minute)))
+	suite.Nil(err)
+	suite.True(updated)
+	user, err := suite.repository.ConsumeResetPasswordToken(ctx, "token_2", time.Now().Add(-time.Hour))
+	suite.Nil(err)
+	suite.False(user.Locked())
+}
+
+func (suite *TestPostgresSuite) TestPreviousEmailReserved() {
+	ctx := context.TODO()
+	revertable := passport.NewRevertableAt("token_1", suite.user.Email, time.Now())
+	updated, err := suite.repository.UpdateRevertable(ctx, suite.user.ID, 0, revertable)
+	suite.Nil(err)
+	suite.True(updated)
+	updated, err = suite.repository.UpdateConfirmable(ctx, suite.user.Email, 1, passport.NewConfirmable("token_2", "jane.doe@mail.com"))
+	suite.Nil(err)
+	suite.True(updated)
+
+	// The previous email cannot be taken until the change is reverted.
+	exists, err := suite.repository.HasEmail(ctx, suite.user.Email)
+	suite.Nil(err)
+	suite.True(exists)
+	_, err = suite.repository.Create(ctx, suite.user.Email, "12345678")
+	suite.Equal(passport.ErrEmailExists, err)
+
+	other, err := suite.repository.Create(ctx, "jane@mail.com", "12345678")
+	suite.Nil(err)
+	_, err = suite.repository.UpdateConfirmable(ctx, "jane@mail.com", 0, passport.NewConfirmable("token_3", suite.user.Email))
+	suite.Equal(passport.ErrEmailExists, err)
+
+	user, err := suite.repository.ConsumeRevertEmailToken(ctx, "token_1", time.Now().Add(-time.Hour))
+	suite.Nil(err)
+	suite.Equal(suite.user.Email, user.Email)
+
+	found, err := suite.repository.Find(ctx, other.ID)
+	suite.Nil(err)
+	suite.Equal("jane@mail.com", found.Email)
+}
+
+func (suite *TestPostgresSuite) TestDelete() {
+	ctx := context.TODO()
+	deleted, err := suite.repository.Delete(ctx, suite.user.ID, 0)
+	suite.Nil(err)
+	suite.True(deleted)
+
+	// The deleted user is excluded from the queries.
+	_, err = suite.repository.Find(ctx, suite.user.ID)
+	suite.Equal(sql.ErrNoRows, err)
+	_, err = suite.repository.WithEmail(ctx, suite.user.Email)
+	suite.Equal(sql.ErrNoRows, err)
+	exists, err := suite.repository.HasEmail(ctx, suite.user.Email)
+	suite.Nil(err)
+	suite.False(exists)
+	updated, err := suite.repository.UpdatePassword(ctx, suite.user.ID, 1, "password")
+	suite.Nil(err)
+	suite.False(updated)
+
+	// The deleted user can still be found for the export.
+	user, err := suite.repository.FindIncludingDeleted(ctx, suite.user.ID)
+	suite.Nil(err)
+	suite.Equal(suite.user.Email, user.Email)
+	suite.False(user.DeletedAt.IsZero())
+
+	// The email can be registered again.
+	user, err = suite.repository.Create(ctx, suite.user.Email, "12345678")
+	suite.Nil(err)
+	suite.NotEqual(suite.user.ID, user.ID)
+}
+
+func (suite *TestPostgresSuite) TestEraseDeleted() {
+	ctx := context.TODO()
+	deleted, err := suite.repository.Delete(ctx, suite.user.ID, 0)
+	suite.Nil(err)
+	suite.True(deleted)
+
+	// Users within the grace period are kept.
+	n, err := suite.repository.EraseDeleted(ctx, time.Now().Add(-time.Hour))
+	suite.Nil(err)
+	suite.Equal(int64(0), n)
+
+	n, err = suite.repository.EraseDeleted(ctx, time.Now().Add(time.Hour))
+	suite.Nil(err)
+	suite.Equal(int64(1), n)
+
+	var email, encryptedPassword string
+	err = suite.db.QueryRow(`SELECT email, encrypted_password FROM login WHERE id = $1`, suite.user.ID).Scan(&email, &encryptedPassword)
+	suite.Nil(err)
+	suite.Equal("", email)
+	suite.Equal("", encryptedPassword)
+
+	// Erased users cannot be exported.
+	_, err = suite.repository.FindIncludingDeleted(ctx, suite.user.ID)
+	suite.Equal(sql.ErrNoRows, err)
+
+	// Erased users are not erased again.
+	n, err = suite.repository.EraseDeleted(ctx, time.Now().Add(time.Hour))
+	suite.Nil(err)
+	suite.Equal(int64(0), n)
+}
+
+func (suite *TestPostgresSuite) TestEraseDeletedPersonalData() {
+	ctx := context.TODO()
+	defer func() {
+		_, err := suite.db.Exec("TRUNCATE TABLE audit_log, mail_queue, outbox, rate_limit")
+		suite.Nil(err)
+	}()
+
+	entry := passport.NewAuditEntry(nil, passport.AuditLogin, nil)
+	entry.UserID = suite.user.ID
+	entry.IP = "127.0.0.1"
+	_, err := connector.NewAuditLog(suite.db).WithHashChain().Append(ctx, entry)
+	suite.Nil(err)
+
+	_, err = connector.NewMailQueue(suite.db).Enqueue(ctx, "key_1", mailer.Message{
+		To:      []string{suite.user.Email},
+		Subject: "Confirm your email",
+		Text:    "token",
+	})
+	suite.Nil(err)
+
+	event, err := passport.NewEvent(passport.EventUserRegistered, map[string]string{"email": suite.user.Email})
+	suite.Nil(err)
+	_, err = connector.NewOutbox(suite.db).Append(ctx, event)
+	suite.Nil(err)
+
+	_, err = suite.db.Exec(`INSERT INTO rate_limit (key) VALUES ($1), ($2)`, "login:email:"+suite.user.Email, "login:ip:127.0.0.1")
+	suite.Nil(err)
+
+	deleted, err := suite.repository.Delete(ctx, suite.user.ID, 0)
+	suite.Nil(err)
+	suite.True(deleted)
+	n, err := suite.repository.EraseDeleted(ctx, time.Now().Add(time.Hour))
+	suite.Nil(err)
+	suite.Equal(int64(1), n)
+
+	var ip string
+	err = suite.db.QueryRow(`SELECT ip FROM audit_log WHERE user_id = $1`, suite.user.ID).Scan(&ip)
+	suite.Nil(err)
+	suite.Equal("", ip)
+	suite.Nil(connector.NewAuditLog(suite.db).Verify(ctx))
+
+	// The pending mails and events are not sent.
+	var message, status string
+	err = suite.db.QueryRow(`SELECT message, status FROM mail_queue`).Scan(&message, &status)
+	suite.Nil(err)
+	suite.Equal("{}", message)
+	suite.Equal("dead", status)
+
+	var payload string
+	err = suite.db.QueryRow(`SELECT payload, status FROM outbox`).Scan(&payload, &status)
+	suite.Nil(err)
+	suite.Equal("{}", payload)
+	suite.Equal("dead", status)
+
+	var keys []string
+	rows, err := suite.db.Query(`SELECT key FROM rate_limit`)
+	suite.Nil(err)
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		suite.Nil(rows.Scan(&key))
+		keys = append(keys, key)
+	}
+	suite.Equal([]string{"login:ip:127.0.0.1"}, keys)
+}
+
+func (suite *TestPostgresSuite) TestExtra() {
+	ctx := context.TODO()
+	user, err := suite.repository.Find(ctx, suite.user.ID)
+	suite.Nil(err)
+	suite.Equal(passport.Extra{}, user.Extra)
+
+	user, err = suite.repository.MergeExtra(ctx, suite.user.ID, passport.Extra{"name": "John", "age": 30})
+	suite.Nil(err)
+	suite.Equal(passport.Extra{"name": "John", "age": float64(30)}, user.Extra)
+
+	// Keys set to nil are removed by the patch.
+	user, err = suite.repository.PatchExtra(ctx, suite.user.ID, passport.Extra{"age": nil, "city": "KL"})
+	suite.Nil(err)
+	suite.Equal(passport.Extra{"name": "John", "city": "KL"}, user.Extra)
+
+	users, err := suite.repository.FindByExtra(ctx, passport.Extra{"city": "KL"})
+	suite.Nil(err)
+	suite.Equal(1, len(users))
+	suite.Equal(suite.user.ID, users[0].ID)
+
+	users, err = suite.repository.FindByExtra(ctx, passport.Extra{"city": "SG"})
+	suite.Nil(err)
+	suite.Equal(0, len(users))
+
+	_, err = suite.repository.ReplaceExtra(ctx, suite.user.ID, user.Version-1, passport.Extra{})
+	suite.Equal(passport.ErrConcurrentModification, err)
+	updated, err := suite.repository.ReplaceExtra(ctx, suite.user.ID, user.Version, passport.Extra{"name": "Jane"})
+	suite.Nil(err)
+	suite.True(updated)
+
+	user, err = suite.repository.Find(ctx, suite.user.ID)
+	suite.Nil(err)
+	suite.Equal(passport.Extra{"name": "Jane"}, user.Extra)
+}
+
+func (suite *TestPostgresSuite) TestLock() {
+	ctx := context.TODO()
+	locked, err := suite.repository.Lock(ctx, suite.user.ID, 0)
+	suite.Nil(err)
+	suite.True(locked)
+
+	user, err := suite.repository.Find(ctx, suite.user.ID)
+	suite.Nil(err)
+	suite.True(user.Locked())
+
+	_, err = suite.repository.Lock(ctx, suite.user.ID, 0)
+	suite.Equal(passport.ErrConcurrentModification, err)
+
+	unlocked, err := suite.repository.Unlock(ctx, suite.user.ID, user.Version)
+	suite.Nil(err)
+	suite.True(unlocked)
+}
+
+func (suite *TestPostgresSuite) TestIdentifiers() {
+	ctx := context.TODO()
+	var (
+		username = passport.Identifier{Kind: passport.IdentifierUsername, Value: "jane"}
+		phone    = passport.Identifier{Kind: passport.IdentifierPhone, Value: "+60123456789"}
+	)
+	user, err := suite.repository.Create(ctx, "jane@mail.com", "12345678", username, phone)
+	suite.Nil(err)
+
+	for _, identifier := range []passport.Identifier{
+		{Kind: passport.IdentifierEmail, Value: "jane@mail.com"},
+		username,
+		phone,
+	} {
+		found, err := suite.repository.WithIdentifier(ctx, identifier)
+		suite.Nil(err)
+		suite.Equal(user.ID, found.ID)
+		suite.Equal("jane", found.Username)
+		suite.Equal("+60123456789", found.Phone)
+	}
+
+	_, err = suite.repository.WithIdentifier(ctx, passport.Identifier{Kind: "nickname", Value: "jane"})
+	suite.Equal(passport.ErrIdentifierUnsupported, err)
+
+	// The identifiers are unique per kind.
+	_, err = suite.repository.Create(ctx, "jane.doe@mail.com", "12345678", username)
+	suite.Equal(passport.ErrUsernameExists, err)
+	_, err = suite.repository.Create(ctx, "jane.doe@mail.com", "12345678", phone)
+	suite.Equal(passport.ErrPhoneExists, err)
+	_, err = suite.repository.Create(ctx, "jane@mail.com", "12345678")
+	suite.Equal(passport.ErrEmailExists, err)
+	_, err = suite.repository.UpdateIdentifier(ctx, suite.user.ID, 0, username)
+	suite.Equal(passport.ErrUsernameExists, err)
+
+	// The users without the identifiers do not conflict.
+	updated, err := suite.repository.UpdateIdentifier(ctx, suite.user.ID, 0, passport.Identifier{Kind: passport.IdentifierUsername, Value: "john"})
+	suite.Nil(err)
+	suite.True(updated)
+	found, err := suite.repository.WithIdentifier(ctx, passport.Identifier{Kind: passport.IdentifierUsername, Value: "john"})
+	suite.Nil(err)
+	suite.Equal(suite.user.ID, found.ID)
+	suite.Equal("", found.Phone)
+
+	_, err = suite.repository.UpdateIdentifier(ctx, suite.user.ID, 1, passport.Identifier{Kind: passport.IdentifierEmail, Value: "john@mail.com"})
+	suite.Equal(passport.ErrIdentifierUnsupported, err)
+
+	// The identifiers of the deleted users can be used again.
+	deleted, err := suite.repository.Delete(ctx, user.ID, 0)
+	suite.Nil(err)
+	suite.True(deleted)
+	_, err = suite.repository.Create(ctx, "jane.doe@mail.com", "12345678", username, phone)
+	suite.Nil(err)
+}
+
+// consumeConcurrently runs fn n times concurrently, and returns the number
+// of calls that succeeded.
+func consumeConcurrently(n int, fn func() error) int {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		consumed int
+	)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if err := fn(); err == nil {
+				mu.Lock()
+				consumed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return consumed
+}
+
+func (suite *TestPostgresSuite) TestTxRunnerRollback() {
+	runner := connector.NewTxRunner(suite.db)
+	err := runner.RunInTx(context.TODO(), func(ctx context.Context) error {
+		updated, err := suite.repository.UpdatePassword(ctx, suite.user.ID, 0, "abc")
+		suite.Nil(err)
+		suite.True(updated)
+		return sql.ErrTxDone
+	})
+	suite.Equal(sql.ErrTxDone, err)
+
+	user, err := suite.repository.Find(context.TODO(), suite.user.ID)
+	suite.Nil(err)
+	suite.Equal("12345678", user.EncryptedPassword.Value())
+}
+
 func TestPostgresTestSuite(t *testing.T) {
 	suite.Run(t, new(TestPostgresSuite))
 }
@@ -154,6 +592,8 @@ type TestAuthenticateSuite struct {
 	requestResetPassword *usecase.RequestResetPassword
 	resetPassword        *usecase.ResetPassword
 	changeEmail          *usecase.ChangeEmail
+	cancelEmailChange    *usecase.CancelEmailChange
+	revertEmailChange    *usecase.RevertEmailChange
 }
 
 func (suite *TestAuthenticateSuite) SetupSuite() {
@@ -194,19 +634,30 @@ func (suite *TestAuthenticateSuite) SetupSuite() {
 			TokenGenerator: tg,
 		},
 	)
-	suite.resetPassword = usecase.NewResetPassword(
+	var err error
+	suite.resetPassword, err = usecase.NewResetPassword(
 		usecase.ResetPasswordOptions{
 			Repository:               suite.repository,
 			EncoderComparer:          a2,
 			RecoverableTokenValidity: passport.RecoverableTokenValidity,
+			TxRunner:                 connector.NewTxRunner(suite.db),
 		},
 	)
-	suite.changeEmail = usecase.NewChangeEmail(
+	suite.Nil(err)
+	suite.changeEmail, err = usecase.NewChangeEmail(
 		usecase.ChangeEmailOptions{
 			Repository:     suite.repository,
 			TokenGenerator: tg,
+			TxRunner:       connector.NewTxRunner(suite.db),
 		},
 	)
+	suite.Nil(err)
+	suite.cancelEmailChange = usecase.NewCancelEmailChange(
+		usecase.CancelEmailChangeOptions{Repository: suite.repository},
+	)
+	suite.revertEmailChange = usecase.NewRevertEmailChange(
+		usecase.RevertEmailChangeOptions{Repository: suite.repository},
+	)
 }
 
 func (suite *TestAuthenticateSuite) SetupTest() {
@@ -317,27 +768,172 @@ func (suite *TestAuthenticateSuite) TestResetPassword() {
 	loginFn(suite, email, password)
 }
 
+func (suite *TestAuthenticateSuite) TestResetPasswordConcurrent() {
+	var (
+		email    = passport.NewEmail("john.doe@mail.com")
+		password = passport.NewPassword("87654321")
+	)
+	confirmFn(suite, email)
+	token, err := suite.requestResetPassword.Exec(
+		context.TODO(),
+		email,
+	)
+	suite.Nil(err)
+
+	consumed := consumeConcurrently(5, func() error {
+		_, err := suite.resetPassword.Exec(
+			context.TODO(),
+			passport.NewToken(token),
+			password,
+			password,
+		)
+		return err
+	})
+	suite.Equal(1, consumed)
+}
+
 func (suite *TestAuthenticateSuite) TestChangeEmail() {
 	var (
 		newEmail = passport.NewEmail("jane.doe@mail.com")
 		password = passport.NewPassword("12345678")
 	)
-	token, err := suite.changeEmail.Exec(
+	change, err := suite.changeEmail.Exec(
 		context.TODO(),
 		passport.NewUserID(suite.id),
 		newEmail,
 	)
 	suite.Nil(err)
-	suite.True(token != "")
+	suite.True(change.ConfirmationToken != "")
+	suite.True(change.RevertEmailToken != "")
+	suite.Equal(suite.cred.Email.Value(), change.PreviousEmail)
 
-	err = suite.confirm.Exec(
+	_, err = suite.confirm.Exec(
 		context.TODO(),
-		passport.NewToken(token),
+		passport.NewToken(change.ConfirmationToken),
 	)
 	suite.Nil(err)
 	loginFn(suite, newEmail, password)
 }
 
+func (suite *TestAuthenticateSuite) TestCancelEmailChange() {
+	password := passport.NewPassword("12345678")
+	confirmFn(suite, suite.cred.Email)
+
+	change, err := suite.changeEmail.Exec(
+		context.TODO(),
+		passport.NewUserID(suite.id),
+		passport.NewEmail("jane.doe@mail.com"),
+	)
+	suite.Nil(err)
+
+	err = suite.cancelEmailChange.Exec(context.TODO(), passport.NewUserID(suite.id))
+	suite.Nil(err)
+	loginFn(suite, suite.cred.Email, password)
+
+	// The confirmation token of the cancelled change can no longer be used.
+	_, err = suite.confirm.Exec(
+		context.TODO(),
+		passport.NewToken(change.ConfirmationToken),
+	)
+	suite.Equal(passport.ErrUserNotFound, err)
+
+	err = suite.cancelEmailChange.Exec(context.TODO(), passport.NewUserID(suite.id))
+	suite.Equal(passport.ErrEmailChangeNotFound, err)
+}
+
+func (suite *TestAuthenticateSuite) TestRevertEmailChange() {
+	var (
+		newEmail = passport.NewEmail("jane.doe@mail.com")
+		password = passport.NewPassword("12345678")
+	)
+	change, err := suite.changeEmail.Exec(
+		context.TODO(),
+		passport.NewUserID(suite.id),
+		newEmail,
+	)
+	suite.Nil(err)
+	_, err = suite.confirm.Exec(
+		context.TODO(),
+		passport.NewToken(change.ConfirmationToken),
+	)
+	suite.Nil(err)
+
+	user, err := suite.revertEmailChange.Exec(
+		context.TODO(),
+		passport.NewToken(change.RevertEmailToken),
+	)
+	suite.Nil(err)
+	suite.Equal(suite.cred.Email.Value(), user.Email)
+	suite.True(user.Locked())
+
+	// The account is locked until the password is reset.
+	_, err = suite.login.Exec(context.TODO(), suite.cred)
+	suite.Equal(passport.ErrAccountLocked, err)
+
+	token, err := suite.requestResetPassword.Exec(context.TODO(), suite.cred.Email)
+	suite.Nil(err)
+	_, err = suite.resetPassword.Exec(
+		context.TODO(),
+		passport.NewToken(token),
+		password,
+		password,
+	)
+	suite.Nil(err)
+	loginFn(suite, suite.cred.Email, password)
+
+	_, err = suite.revertEmailChange.Exec(
+		context.TODO(),
+		passport.NewToken(change.RevertEmailToken),
+	)
+	suite.Equal(passport.ErrUserNotFound, err)
+}
+
+func (suite *TestAuthenticateSuite) TestRevertEmailChangeResetRequested() {
+	var (
+		newEmail = passport.NewEmail("jane.doe@mail.com")
+		password = passport.NewPassword("87654321")
+	)
+	confirmFn(suite, suite.cred.Email)
+
+	token, err := suite.requestResetPassword.Exec(context.TODO(), suite.cred.Email)
+	suite.Nil(err)
+
+	change, err := suite.changeEmail.Exec(
+		context.TODO(),
+		passport.NewUserID(suite.id),
+		newEmail,
+	)
+	suite.Nil(err)
+	_, err = suite.revertEmailChange.Exec(
+		context.TODO(),
+		passport.NewToken(change.RevertEmailToken),
+	)
+	suite.Nil(err)
+
+	// The reset password token requested before the revert can no longer
+	// unlock the account.
+	_, err = suite.resetPassword.Exec(
+		context.TODO(),
+		passport.NewToken(token),
+		password,
+		password,
+	)
+	suite.Equal(passport.ErrUserNotFound, err)
+
+	_, err = suite.login.Exec(context.TODO(), suite.cred)
+	suite.Equal(passport.ErrAccountLocked, err)
+}
+
+func (suite *TestAuthenticateSuite) TestExportUser() {
+	export, err := usecase.NewExportUser(usecase.ExportUserOptions{
+		Repository: suite.repository,
+	}).Exec(context.TODO(), passport.NewUserID(suite.id))
+	suite.Nil(err)
+	suite.Equal(suite.id, export.User.ID)
+	suite.Equal(suite.cred.Email.Value(), export.User.Email)
+	suite.False(export.User.CreatedAt.IsZero())
+}
+
 func confirmFn(suite *TestAuthenticateSuite, email passport.Email) {
 	token, err := suite.sendConfirmation.Exec(
 		context.TODO(),
@@ -346,7 +942,7 @@ func confirmFn(suite *TestAuthenticateSuite, email passport.Email) {
 	suite.Nil(err)
 	suite.True(token != "")
 
-	err = suite.confirm.Exec(
+	_, err = suite.confirm.Exec(
 		context.TODO(),
 		passport.NewToken(token),
 	)
@@ -0,0 +1,97 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alextanhongpin/passport/ratelimit"
+)
+
+var rateLimitTable = "rate_limit"
+
+// RateLimitStore keeps the state of the rate limited keys, so that the
+// limits are shared by all the instances.
+type RateLimitStore struct {
+	db *sql.DB
+}
+
+// NewRateLimitStore returns a new pointer to RateLimitStore struct.
+func NewRateLimitStore(db *sql.DB) *RateLimitStore {
+	return &RateLimitStore{db}
+}
+
+// Update locks the row of the key while the state is updated. It runs in
+// its own transaction, so that the requests are counted even when the
+// transaction of the request is rolled back.
+func (r *RateLimitStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(ratelimit.State) ratelimit.State) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s
+			(key)
+		VALUES 	($1)
+		ON CONFLICT (key) DO NOTHING
+	`, rateLimitTable)
+	if _, err := tx.Exec(stmt, key); err != nil {
+		return err
+	}
+
+	stmt = fmt.Sprintf(`
+		SELECT 	count,
+			previous,
+			since,
+			expires_at <= now()
+		FROM 	%s
+		WHERE 	key = $1
+		FOR UPDATE
+	`, rateLimitTable)
+	var s ratelimit.State
+	var since sql.NullTime
+	var expired bool
+	if err := tx.QueryRow(stmt, key).Scan(&s.Count, &s.Previous, &since, &expired); err != nil {
+		return err
+	}
+	if expired {
+		s = ratelimit.State{}
+	} else if since.Valid {
+		s.Since = since.Time
+	}
+
+	s = fn(s)
+	stmt = fmt.Sprintf(`
+		UPDATE 	%s
+		SET 	count = $1,
+			previous = $2,
+			since = $3,
+			expires_at = now() + $4 * interval '1 millisecond'
+		WHERE 	key = $5
+	`, rateLimitTable)
+	_, err = tx.Exec(stmt, s.Count, s.Previous, NewNullTime(s.Since), ttl.Milliseconds(), key)
+	return err
+}
+
+// DeleteExpired removes the expired keys, and returns the number of keys
+// removed.
+func (r *RateLimitStore) DeleteExpired(ctx context.Context) (int64, error) {
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE 	expires_at <= now()
+	`, rateLimitTable)
+	res, err := r.db.ExecContext(ctx, stmt)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,56 @@
+package connector_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport/connector"
+	"github.com/alextanhongpin/passport/examples/database"
+	"github.com/alextanhongpin/passport/ratelimit"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestRateLimitSuite struct {
+	suite.Suite
+	db      *sql.DB
+	limiter *ratelimit.Limiter
+}
+
+func (suite *TestRateLimitSuite) SetupSuite() {
+	suite.db = database.DB()
+	suite.limiter = ratelimit.New(ratelimit.Options{
+		Store:     connector.NewRateLimitStore(suite.db),
+		Algorithm: ratelimit.TokenBucket{Burst: 2, Interval: time.Hour},
+	})
+}
+
+func (suite *TestRateLimitSuite) TearDownTest() {
+	_, err := suite.db.Exec("TRUNCATE TABLE rate_limit")
+	suite.Nil(err)
+}
+
+func (suite *TestRateLimitSuite) TestAllow() {
+	ctx := context.TODO()
+	for i := 1; i >= 0; i-- {
+		res, err := suite.limiter.Allow(ctx, "login:ip:1.2.3.4")
+		suite.Nil(err)
+		suite.True(res.Allowed)
+		suite.Equal(i, res.Remaining)
+	}
+
+	res, err := suite.limiter.Allow(ctx, "login:ip:1.2.3.4")
+	suite.Nil(err)
+	suite.False(res.Allowed)
+	suite.True(res.RetryAfter > 0)
+
+	res, err = suite.limiter.Allow(ctx, "login:ip:5.6.7.8")
+	suite.Nil(err)
+	suite.True(res.Allowed)
+}
+
+func TestRateLimitTestSuite(t *testing.T) {
+	suite.Run(t, new(TestRateLimitSuite))
+}
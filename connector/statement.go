@@ -2,20 +2,36 @@ package connector
 
 import "fmt"
 
-func selectUserStmt(table, where string) string {
-	return fmt.Sprintf(`
-		SELECT 	id,
+// userColumns are the columns scanned by getUser.
+const userColumns = `id,
 			created_at,
 			email,
+			username,
+			phone,
 			encrypted_password,
 			reset_password_token,
 			reset_password_sent_at,
+			reset_password_sent_count,
 			allow_password_change,
 			confirmation_token,
 			confirmation_sent_at,
+			confirmation_sent_count,
 			confirmed_at,
-			unconfirmed_email
+			unconfirmed_email,
+			previous_email,
+			revert_email_token,
+			revert_email_sent_at,
+			locked_at,
+			deleted_at,
+			extra,
+			version`
+
+// selectUserStmt selects the users that are not deleted.
+func selectUserStmt(table, where string) string {
+	return fmt.Sprintf(`
+		SELECT 	%s
 		FROM 	%s
-		WHERE   %s
-	`, table, where)
+		WHERE   deleted_at IS NULL
+		AND     %s
+	`, userColumns, table, where)
 }
@@ -15,3 +15,64 @@ type Tx interface {
 	QueryRow(query string, args ...interface{}) *sql.Row
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
+
+type txContextKey struct{}
+
+// ContextWithTx returns a context carrying the transaction. Repositories in
+// this package will use the transaction in the context over their own.
+func ContextWithTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by the context, if any.
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(Tx)
+	return tx, ok
+}
+
+// conn returns the transaction in the context, falling back to the given
+// one.
+func conn(ctx context.Context, tx Tx) Tx {
+	if ctxTx, ok := TxFromContext(ctx); ok {
+		return ctxTx
+	}
+	return tx
+}
+
+// TxRunner runs functions in a transaction, which is passed down through
+// the context.
+type TxRunner struct {
+	db *sql.DB
+}
+
+// NewTxRunner returns a new pointer to TxRunner struct.
+func NewTxRunner(db *sql.DB) *TxRunner {
+	return &TxRunner{db}
+}
+
+// RunInTx commits the transaction if fn succeeds, and rolls it back
+// otherwise. When the context already carries a transaction, fn joins it.
+func (t *TxRunner) RunInTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	err = fn(ContextWithTx(ctx, tx))
+	return err
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/alextanhongpin/passport"
+
 	"github.com/lib/pq"
 )
 
@@ -14,6 +16,23 @@ func PgDuplicateError(err error) bool {
 	return false
 }
 
+// duplicateError maps the unique violations of the identifiers to their
+// errors, by the name of the violated index.
+func duplicateError(err error) error {
+	pgerr, ok := err.(*pq.Error)
+	if !ok || pgerr.Code != "23505" {
+		return err
+	}
+	switch pgerr.Constraint {
+	case "login_username_key":
+		return passport.ErrUsernameExists
+	case "login_phone_key":
+		return passport.ErrPhoneExists
+	default:
+		return passport.ErrEmailExists
+	}
+}
+
 func NewNullString(str string) sql.NullString {
 	if str == "" {
 		return sql.NullString{}
@@ -1,27 +1,90 @@
 package passport
 
-import "errors"
-
 // ErrInvalidCredential indicates the credential is invalid.
-var ErrInvalidCredential = errors.New("credential is invalid")
+var ErrInvalidCredential = NewError("invalid_credential", "credential is invalid", CategoryValidation)
 
 // Credential is the email/password pair to authenticate users.
 type Credential struct {
 	Email    Email
 	Password Password
+
+	// Username and Phone are the alternate identifiers. They are optional
+	// on register, and either of them can be used instead of the email to
+	// log in.
+	Username Username
+	Phone    Phone
+}
+
+// Identifier returns the identifier to log in with, which is the username
+// or phone when set, and the email otherwise.
+func (c Credential) Identifier() Identifier {
+	switch {
+	case c.Username != "":
+		return Identifier{Kind: IdentifierUsername, Value: c.Username.Value()}
+	case c.Phone != "":
+		return Identifier{Kind: IdentifierPhone, Value: c.Phone.Value()}
+	default:
+		return Identifier{Kind: IdentifierEmail, Value: c.Email.Value()}
+	}
+}
+
+// AlternateIdentifiers returns the username and phone that are set.
+func (c Credential) AlternateIdentifiers() []Identifier {
+	var identifiers []Identifier
+	if c.Username != "" {
+		identifiers = append(identifiers, Identifier{Kind: IdentifierUsername, Value: c.Username.Value()})
+	}
+	if c.Phone != "" {
+		identifiers = append(identifiers, Identifier{Kind: IdentifierPhone, Value: c.Phone.Value()})
+	}
+	return identifiers
 }
 
 // Validate is like Valid, except that it returns error instead of boolean.
+// The email is always required, even when the user logs in with the
+// username or phone, since the confirmation and reset password tokens are
+// sent to it. The username and phone are only validated when set.
 func (c Credential) Validate() error {
 	if err := c.Email.Validate(); err != nil {
 		return err
 	}
+	if err := c.validateUsername(); err != nil {
+		return err
+	}
+	if err := c.validatePhone(); err != nil {
+		return err
+	}
 	if err := c.Password.Validate(); err != nil {
 		return err
 	}
 	return nil
 }
 
+// ValidateAll is like Validate, except that it returns the errors of all
+// the fields as ValidationErrors.
+func (c Credential) ValidateAll() error {
+	var errs ValidationErrors
+	errs = errs.Add("email", c.Email.Validate(), nil)
+	errs = errs.Add("username", c.validateUsername(), nil)
+	errs = errs.Add("phone", c.validatePhone(), nil)
+	errs = c.Password.ValidateField("password", errs)
+	return errs.Err()
+}
+
+func (c Credential) validateUsername() error {
+	if c.Username == "" {
+		return nil
+	}
+	return c.Username.Validate()
+}
+
+func (c Credential) validatePhone() error {
+	if c.Phone == "" {
+		return nil
+	}
+	return c.Phone.Validate()
+}
+
 // NewCredential returns the email/password pair.
 func NewCredential(email, password string) Credential {
 	return Credential{
@@ -29,3 +92,18 @@ func NewCredential(email, password string) Credential {
 		Password: NewPassword(password),
 	}
 }
+
+// NewIdentifierCredential returns the credential to log in with the
+// identifier instead of the email.
+func NewIdentifierCredential(identifier Identifier, password string) Credential {
+	cred := Credential{Password: NewPassword(password)}
+	switch identifier.Kind {
+	case IdentifierUsername:
+		cred.Username = NewUsername(identifier.Value)
+	case IdentifierPhone:
+		cred.Phone = NewPhone(identifier.Value)
+	default:
+		cred.Email = NewEmail(identifier.Value)
+	}
+	return cred
+}
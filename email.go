@@ -1,7 +1,6 @@
 package passport
 
 import (
-	"errors"
 	"regexp"
 	"strings"
 )
@@ -14,11 +13,11 @@ var emailRegex = regexp.MustCompile(emailRegexPattern)
 // models. Have a method Valid() that returns bool, and another method
 // Validate() that returns error.
 var (
-	ErrEmailExists            = errors.New("email exists")
-	ErrEmailInvalid           = errors.New("email invalid")
-	ErrEmailOrPasswordInvalid = errors.New("email or password is invalid")
-	ErrEmailRequired          = errors.New("email required")
-	ErrEmailVerified          = errors.New("email verified")
+	ErrEmailExists            = NewError("email_exists", "email exists", CategoryConflict)
+	ErrEmailInvalid           = NewError("email_invalid", "email invalid", CategoryValidation)
+	ErrEmailOrPasswordInvalid = NewError("email_or_password_invalid", "email or password is invalid", CategoryUnauthorized)
+	ErrEmailRequired          = NewError("email_required", "email required", CategoryValidation)
+	ErrEmailVerified          = NewError("email_verified", "email verified", CategoryConflict)
 )
 
 type Email string
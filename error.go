@@ -0,0 +1,101 @@
+package passport
+
+import (
+	"errors"
+	"sync"
+)
+
+// Category groups the errors that are handled the same way by the clients,
+// and determines the transport status of the error.
+type Category string
+
+const (
+	CategoryValidation      Category = "validation"
+	CategoryNotFound        Category = "not_found"
+	CategoryConflict        Category = "conflict"
+	CategoryAborted         Category = "aborted"
+	CategoryUnauthorized    Category = "unauthorized"
+	CategoryForbidden       Category = "forbidden"
+	CategoryLocked          Category = "locked"
+	CategoryInternal        Category = "internal"
+	CategoryTooManyRequests Category = "too_many_requests"
+	CategoryUnavailable     Category = "unavailable"
+)
+
+// Error is an error with a stable machine-readable code, and a message that
+// is safe to show to the users.
+type Error struct {
+	Code     string
+	Message  string
+	Category Category
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var registry struct {
+	sync.Mutex
+	errors []*Error
+}
+
+// NewError returns a new Error. The errors are compared by identity, so they
+// are usually declared once as package level variables. The errors are
+// registered, so that they can be listed with Errors.
+func NewError(code, message string, category Category) *Error {
+	e := &Error{
+		Code:     code,
+		Message:  message,
+		Category: category,
+	}
+	registry.Lock()
+	registry.errors = append(registry.errors, e)
+	registry.Unlock()
+	return e
+}
+
+// Errors returns all the errors created with NewError, e.g. to check that
+// every error code has a translation.
+func Errors() []*Error {
+	registry.Lock()
+	defer registry.Unlock()
+	return append([]*Error(nil), registry.errors...)
+}
+
+// ErrInternal is returned to the clients in place of the errors that are
+// not an Error, so that the internal details are not exposed.
+var ErrInternal = NewError("internal", "internal error", CategoryInternal)
+
+// AsError returns the Error in the chain of err, or ErrInternal when there
+// is none. It returns nil when err is nil.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return ErrInternal
+}
+
+// ParamsError is an Error with the params of its message, such as the min
+// length of the password. It unwraps to the Error, so it can still be
+// matched with errors.Is.
+type ParamsError struct {
+	Err    *Error
+	Params map[string]interface{}
+}
+
+func (p ParamsError) Error() string {
+	return p.Err.Message
+}
+
+func (p ParamsError) Unwrap() error {
+	return p.Err
+}
+
+// WithParams returns the error with the params of its message.
+func (e *Error) WithParams(params map[string]interface{}) ParamsError {
+	return ParamsError{Err: e, Params: params}
+}
@@ -0,0 +1,25 @@
+package passport_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsError(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(passport.AsError(nil))
+
+	wrapped := fmt.Errorf("login: %w", passport.ErrEmailRequired)
+	assert.True(errors.Is(wrapped, passport.ErrEmailRequired))
+	assert.Equal(passport.ErrEmailRequired, passport.AsError(wrapped))
+	assert.Equal("email_required", passport.AsError(wrapped).Code)
+
+	// The details of unknown errors are not exposed.
+	e := passport.AsError(errors.New("pq: connection refused"))
+	assert.Equal(passport.ErrInternal, e)
+	assert.Equal(passport.CategoryInternal, e.Category)
+}
@@ -0,0 +1,42 @@
+package passport
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types that are recorded together with the state change that caused
+// them, so that the side effects (e.g. sending mails) are never lost.
+const (
+	EventUserRegistered         = "user.registered"
+	EventConfirmationRequested  = "user.confirmation_requested"
+	EventEmailChangeRequested   = "user.email_change_requested"
+	EventEmailChanged           = "user.email_changed"
+	EventResetPasswordRequested = "user.reset_password_requested"
+)
+
+// Event represents a change that has to be delivered to an external party.
+type Event struct {
+	ID        string          `json:"id,omitempty"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Attempts  int             `json:"attempts,omitempty"`
+	CreatedAt time.Time       `json:"created_at,omitempty"`
+}
+
+// Decode unmarshals the payload of the event into v.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// NewEvent returns a new Event with the payload encoded as json.
+func NewEvent(eventType string, payload interface{}) (Event, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		Type:    eventType,
+		Payload: b,
+	}, nil
+}
@@ -1,5 +0,0 @@
-package api
-
-import "github.com/alextanhongpin/pkg/contextkey"
-
-var UserContext = contextkey.Key("user_id")
@@ -1,3 +0,0 @@
-package api
-
-type M map[string]interface{}
@@ -0,0 +1,36 @@
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS outbox (
+	id UUID DEFAULT uuid_generate_v1mc(),
+
+	type TEXT NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+
+	-- Delivery. The pending events are claimed by pushing the
+	-- next_attempt_at forward, and dead events are not retried.
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	delivered_at TIMESTAMP WITH TIME ZONE NULL,
+
+	-- Timestamp.
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx
+ON outbox (next_attempt_at)
+WHERE status = 'pending';
+
+CREATE TRIGGER update_outbox_timestamp BEFORE UPDATE
+ON outbox FOR EACH ROW EXECUTE PROCEDURE
+  update_timestamp();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS update_outbox_timestamp
+ON outbox;
+
+DROP TABLE outbox;
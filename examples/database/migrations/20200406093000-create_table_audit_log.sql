@@ -0,0 +1,57 @@
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS audit_log (
+	id UUID DEFAULT uuid_generate_v1mc(),
+	seq BIGSERIAL NOT NULL,
+
+	action TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+
+	-- Who did what to whom.
+	actor_id TEXT NOT NULL DEFAULT '',
+	user_id TEXT NOT NULL DEFAULT '',
+	identifier TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+
+	-- Hash chain.
+	prev_hash TEXT NOT NULL DEFAULT '',
+	hash TEXT NOT NULL DEFAULT '',
+
+	-- Timestamp.
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (id),
+	UNIQUE (seq)
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_created_at_idx
+ON audit_log (user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS audit_log_identifier_created_at_idx
+ON audit_log (identifier, created_at);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx
+ON audit_log (created_at);
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION prevent_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+   RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ language 'plpgsql';
+-- +migrate StatementEnd
+
+CREATE TRIGGER prevent_audit_log_change BEFORE UPDATE OR DELETE
+ON audit_log FOR EACH ROW EXECUTE PROCEDURE
+  prevent_audit_log_change();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS prevent_audit_log_change
+ON audit_log;
+
+DROP FUNCTION IF EXISTS prevent_audit_log_change;
+
+DROP TABLE audit_log;
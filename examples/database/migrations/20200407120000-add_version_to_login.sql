@@ -0,0 +1,8 @@
+
+-- +migrate Up
+ALTER TABLE login
+ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE login
+DROP COLUMN IF EXISTS version;
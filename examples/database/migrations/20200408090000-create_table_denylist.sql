@@ -0,0 +1,18 @@
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS denylist (
+	-- The jti of a revoked token, or the sid of a revoked session.
+	id TEXT NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+	-- Timestamp.
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS denylist_expires_at_idx
+ON denylist (expires_at);
+
+-- +migrate Down
+DROP TABLE denylist;
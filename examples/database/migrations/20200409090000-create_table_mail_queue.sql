@@ -0,0 +1,37 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS mail_queue (
+	id UUID DEFAULT uuid_generate_v1mc(),
+
+	-- Prevents the same mail from being queued twice.
+	idempotency_key TEXT NOT NULL,
+	message JSONB NOT NULL,
+
+	-- Delivery. The pending mails are claimed by pushing the
+	-- next_attempt_at forward, and dead mails are not retried.
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	sent_at TIMESTAMP WITH TIME ZONE NULL,
+
+	-- Timestamp.
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (id),
+	UNIQUE (idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS mail_queue_pending_idx
+ON mail_queue (next_attempt_at)
+WHERE status = 'pending';
+
+CREATE TRIGGER update_mail_queue_timestamp BEFORE UPDATE
+ON mail_queue FOR EACH ROW EXECUTE PROCEDURE
+  update_timestamp();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS update_mail_queue_timestamp
+ON mail_queue;
+
+DROP TABLE mail_queue;
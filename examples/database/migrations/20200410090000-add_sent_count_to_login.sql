@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE login
+ADD COLUMN IF NOT EXISTS confirmation_sent_count INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS reset_password_sent_count INT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE login
+DROP COLUMN IF EXISTS confirmation_sent_count,
+DROP COLUMN IF EXISTS reset_password_sent_count;
@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS rate_limit (
+	key TEXT NOT NULL,
+
+	-- The state of the sliding window or the token bucket.
+	count DOUBLE PRECISION NOT NULL DEFAULT 0,
+	previous DOUBLE PRECISION NOT NULL DEFAULT 0,
+	since TIMESTAMP WITH TIME ZONE NULL,
+
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (key)
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_expires_at_idx
+ON rate_limit (expires_at);
+
+-- +migrate Down
+DROP TABLE rate_limit;
@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE login
+ADD COLUMN IF NOT EXISTS previous_email TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS revert_email_token TEXT UNIQUE NULL,
+ADD COLUMN IF NOT EXISTS revert_email_sent_at TIMESTAMP WITH TIME ZONE NULL,
+ADD COLUMN IF NOT EXISTS locked_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- +migrate Down
+ALTER TABLE login
+DROP COLUMN IF EXISTS previous_email,
+DROP COLUMN IF EXISTS revert_email_token,
+DROP COLUMN IF EXISTS revert_email_sent_at,
+DROP COLUMN IF EXISTS locked_at;
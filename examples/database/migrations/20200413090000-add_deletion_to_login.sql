@@ -0,0 +1,125 @@
+-- +migrate Up
+ALTER TABLE login
+ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- The email of the deleted users can be registered again.
+ALTER TABLE login
+DROP CONSTRAINT IF EXISTS login_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS login_email_key
+ON login (email)
+WHERE deleted_at IS NULL;
+
+-- The previous email of a pending email change is reserved until the revert
+-- email token is consumed or cleared, so that the change can be reverted.
+CREATE UNIQUE INDEX IF NOT EXISTS login_previous_email_key
+ON login (previous_email)
+WHERE revert_email_token IS NOT NULL AND deleted_at IS NULL;
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION check_login_email_reserved()
+RETURNS TRIGGER AS $$
+BEGIN
+	IF NEW.deleted_at IS NOT NULL OR NEW.email = '' THEN
+		RETURN NEW;
+	END IF;
+
+	-- Serializes the checks of the same email, since the reservation is
+	-- made by another row.
+	PERFORM pg_advisory_xact_lock(hashtext(NEW.email));
+
+	IF EXISTS (
+		SELECT 1 FROM login
+		WHERE id <> NEW.id
+		AND previous_email = NEW.email
+		AND revert_email_token IS NOT NULL
+		AND deleted_at IS NULL
+	) THEN
+		RAISE EXCEPTION 'email is reserved by a pending email change'
+		USING ERRCODE = 'unique_violation', CONSTRAINT = 'login_email_key';
+	END IF;
+
+	RETURN NEW;
+END;
+$$ language 'plpgsql';
+-- +migrate StatementEnd
+
+CREATE TRIGGER check_login_email_reserved BEFORE INSERT OR UPDATE OF email, deleted_at
+ON login FOR EACH ROW EXECUTE PROCEDURE
+  check_login_email_reserved();
+
+CREATE INDEX IF NOT EXISTS login_deleted_at_idx
+ON login (deleted_at)
+WHERE deleted_at IS NOT NULL AND erased_at IS NULL;
+
+-- The identifier, IP and user agent of the audit entries are erased with the
+-- user. The entries are chained with the hash of them instead, so that the
+-- erased entries can still be verified.
+ALTER TABLE audit_log
+ADD COLUMN IF NOT EXISTS personal_hash TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION prevent_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+	-- Only the erasure of the personal data is allowed.
+	IF TG_OP = 'UPDATE'
+	AND OLD.erased_at IS NULL
+	AND NEW.erased_at IS NOT NULL
+	AND NEW.identifier = ''
+	AND NEW.ip = ''
+	AND NEW.user_agent = ''
+	AND (NEW.id, NEW.seq, NEW.action, NEW.outcome, NEW.reason, NEW.actor_id, NEW.user_id, NEW.created_at, NEW.prev_hash, NEW.hash, NEW.personal_hash)
+	IS NOT DISTINCT FROM (OLD.id, OLD.seq, OLD.action, OLD.outcome, OLD.reason, OLD.actor_id, OLD.user_id, OLD.created_at, OLD.prev_hash, OLD.hash, OLD.personal_hash) THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ language 'plpgsql';
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- The emails of the deleted users are no longer unique, since they can be
+-- registered again and are cleared when erased, so the migration can only be
+-- reverted before any user is deleted.
+-- +migrate StatementBegin
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM login WHERE deleted_at IS NOT NULL) THEN
+		RAISE EXCEPTION 'cannot revert the deletion of the users once a user is deleted';
+	END IF;
+END;
+$$;
+-- +migrate StatementEnd
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION prevent_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+   RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ language 'plpgsql';
+-- +migrate StatementEnd
+
+ALTER TABLE audit_log
+DROP COLUMN IF EXISTS erased_at,
+DROP COLUMN IF EXISTS personal_hash;
+
+DROP INDEX IF EXISTS login_deleted_at_idx;
+
+DROP TRIGGER IF EXISTS check_login_email_reserved
+ON login;
+
+DROP FUNCTION IF EXISTS check_login_email_reserved;
+
+DROP INDEX IF EXISTS login_previous_email_key;
+
+DROP INDEX IF EXISTS login_email_key;
+
+ALTER TABLE login
+ADD CONSTRAINT login_email_key UNIQUE (email);
+
+ALTER TABLE login
+DROP COLUMN IF EXISTS erased_at;
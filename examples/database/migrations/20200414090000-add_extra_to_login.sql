@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE login
+ADD COLUMN IF NOT EXISTS extra JSONB NOT NULL DEFAULT '{}';
+
+-- Speeds up the lookups by the extra keys and values.
+CREATE INDEX IF NOT EXISTS login_extra_idx
+ON login USING GIN (extra jsonb_path_ops);
+
+-- +migrate Down
+DROP INDEX IF EXISTS login_extra_idx;
+
+ALTER TABLE login
+DROP COLUMN IF EXISTS extra;
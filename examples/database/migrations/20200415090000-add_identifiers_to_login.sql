@@ -0,0 +1,23 @@
+-- +migrate Up
+ALTER TABLE login
+ADD COLUMN IF NOT EXISTS username TEXT NULL,
+ADD COLUMN IF NOT EXISTS phone TEXT NULL;
+
+-- The identifiers are optional, and unique among the users that are not
+-- deleted.
+CREATE UNIQUE INDEX IF NOT EXISTS login_username_key
+ON login (username)
+WHERE deleted_at IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS login_phone_key
+ON login (phone)
+WHERE deleted_at IS NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS login_phone_key;
+
+DROP INDEX IF EXISTS login_username_key;
+
+ALTER TABLE login
+DROP COLUMN IF EXISTS phone,
+DROP COLUMN IF EXISTS username;
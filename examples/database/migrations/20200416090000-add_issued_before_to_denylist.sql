@@ -0,0 +1,11 @@
+-- +migrate Up
+-- A revoked subject denies the tokens of the user issued before
+-- issued_before. The id of the subject entries is prefixed with "sub:".
+ALTER TABLE denylist
+ADD COLUMN IF NOT EXISTS issued_before TIMESTAMP WITH TIME ZONE NULL;
+
+-- +migrate Down
+DELETE FROM denylist WHERE issued_before IS NOT NULL;
+
+ALTER TABLE denylist
+DROP COLUMN IF EXISTS issued_before;
@@ -1,69 +1,62 @@
 package mailer
 
 import (
+	"context"
+	"errors"
 	"fmt"
-)
 
-const (
-	from = "company@mail.com"
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/httpapi"
+	"github.com/alextanhongpin/passport/mailer"
 )
 
-type Mail struct {
-	From    string
-	To      string
-	Subject string
-	Body    string
-}
-
-type sender interface {
-	Send(Mail) error
+type queue interface {
+	Enqueue(ctx context.Context, key string, msg mailer.Message) (bool, error)
 }
 
-type NoopMailer struct {
-}
-
-func NewNoopMailer() *NoopMailer {
-	return &NoopMailer{}
-}
+// NoopSender prints the messages instead of sending them.
+type NoopSender struct{}
 
-func (n *NoopMailer) Send(mail Mail) error {
+func (NoopSender) Send(ctx context.Context, msg mailer.Message) error {
 	fmt.Printf(`From: %s
 To: %s
 Subject: %s
 Body:
 
 %s`,
-		mail.From,
-		mail.To,
-		mail.Subject,
-		mail.Body,
+		msg.From,
+		msg.To,
+		msg.Subject,
+		msg.Text,
 	)
 	return nil
 }
 
-func NewChangeEmail(to, token string) Mail {
-	return Mail{
-		From:    from,
-		To:      to,
-		Subject: "Change your Email",
-		Body:    fmt.Sprintf(`Your confirm email token: %s`, token),
-	}
+type Mailer struct {
+	templates *mailer.Templates
+	queue     queue
 }
 
-func NewSendConfirmation(to, token string) Mail {
-	return Mail{
-		From:    from,
-		To:      to,
-		Subject: "Confirm your Email",
-		Body:    fmt.Sprintf(`Your confirm email token: %s`, token),
-	}
+func NewMailer(templates *mailer.Templates, queue queue) *Mailer {
+	return &Mailer{templates, queue}
 }
 
-func NewResetPassword(to, token string) Mail {
-	return Mail{
-		From:    from,
-		To:      to,
-		Subject: "Reset your Password",
-		Body:    fmt.Sprintf(`Your reset password token: %s`, token),
+// Deliver queues the mail for the events relayed from the outbox. The mail
+// is only queued once for each token, even when the event is relayed again.
+func (m *Mailer) Deliver(ctx context.Context, event passport.Event) error {
+	var payload httpapi.MailPayload
+	if err := event.Decode(&payload); err != nil {
+		return err
+	}
+
+	msg, err := m.templates.Render(payload.Locale, event.Type, payload.Email, payload.Token)
+	if errors.Is(err, mailer.ErrTemplateNotFound) {
+		// Not a mail event.
+		return nil
+	}
+	if err != nil {
+		return err
 	}
+	_, err = m.queue.Enqueue(ctx, mailer.IdempotencyKey(event.Type, payload.Token), msg)
+	return err
 }
@@ -1,51 +1,317 @@
 package main
 
 import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
-	"github.com/alextanhongpin/passport/examples/api"
-	"github.com/alextanhongpin/passport/examples/controller"
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/connector"
 	"github.com/alextanhongpin/passport/examples/database"
-	"github.com/alextanhongpin/passport/examples/service"
-
-	"github.com/alextanhongpin/pkg/gojwt"
-
-	"github.com/julienschmidt/httprouter"
+	examplemailer "github.com/alextanhongpin/passport/examples/mailer"
+	"github.com/alextanhongpin/passport/httpapi"
+	"github.com/alextanhongpin/passport/i18n"
+	"github.com/alextanhongpin/passport/issuer"
+	"github.com/alextanhongpin/passport/mailer"
+	"github.com/alextanhongpin/passport/outbox"
+	"github.com/alextanhongpin/passport/ratelimit"
+	"github.com/alextanhongpin/passport/usecase"
 )
 
 //go:generate packr2
 func main() {
+	preview := flag.String("preview", "", "write the rendered mails to the directory and exit")
+	export := flag.String("export", "", "write the personal data of the user id as JSON and exit")
+	exportFile := flag.String("export-file", "export.json", "the file the personal data is written to")
+	flag.Parse()
+
+	catalog := i18n.Default()
+	templates, err := mailer.NewTemplates(mailer.TemplateOptions{
+		Catalog: catalog,
+		BaseURL: os.Getenv("APP_URL"),
+		Brand:   mailer.Brand{Name: "Passport"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	if *preview != "" {
+		if err := templates.Preview(*preview); err != nil {
+			panic(err)
+		}
+		log.Printf("Wrote the mail previews to %s", *preview)
+		return
+	}
+
 	db, err := database.Setup()
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 
-	signer := gojwt.New(gojwt.Option{
-		Secret:       []byte("secret"),
-		ExpiresAfter: 1 * time.Hour,
-	})
-	svc := service.New(db, signer)
-	ctl := controller.New(svc)
-
-	router := httprouter.New()
-	router.GET("/", indexHandler)
-	router.GET("/private", api.Protect(signer, indexHandler))
-	router.POST("/login", ctl.PostLogin)
-	router.POST("/register", ctl.PostRegister)
-	router.POST("/user/emails", api.Protect(signer, ctl.PostChangeEmail))
-	router.PUT("/user/passwords", api.Protect(signer, ctl.PutChangePassword))
-	router.PUT("/confirmations", ctl.PutConfirm)
-	router.POST("/confirmations", ctl.PostSendConfirmation)
-	router.PUT("/passwords", ctl.PutResetPassword)
-	router.POST("/passwords", ctl.PostRequestResetPassword)
+	if *export != "" {
+		if err := exportUser(db, *export, *exportFile); err != nil {
+			panic(err)
+		}
+		log.Printf("Wrote the personal data of %s to %s", *export, *exportFile)
+		return
+	}
+
+	key, err := loadKey(os.Getenv("JWT_KEY_ID"), os.Getenv("JWT_PRIVATE_KEY_FILE"))
+	if err != nil {
+		panic(err)
+	}
+	keyring := passport.NewKeyring(nil, key)
+	denylist := connector.NewDenylist(db)
+	iss := issuer.New(issuer.Options{
+		Keyring:  keyring,
+		Denylist: denylist,
+		Issuer:   "passport",
+		Audience: []string{"passport"},
+		ValidateSubject: usecase.NewCheckUser(usecase.CheckUserOptions{
+			Repository: connector.NewPostgres(db),
+		}).ValidateSubject,
+	})
+	var (
+		r              = connector.NewPostgres(db)
+		ec             = passport.NewArgon2Password()
+		tokenGenerator = passport.NewTokenGenerator()
+		txRunner       = connector.NewTxRunner(db)
+	)
+	mailQueue := connector.NewMailQueue(db)
+	m := examplemailer.NewMailer(templates, mailQueue)
+	// Limit the requests by IP and email, shared by all the instances.
+	rateLimitStore := connector.NewRateLimitStore(db)
+	perIP := ratelimit.New(ratelimit.Options{
+		Store:     rateLimitStore,
+		Algorithm: ratelimit.SlidingWindow{Limit: 30, Window: time.Minute},
+	})
+	perEmail := ratelimit.New(ratelimit.Options{
+		Store:     rateLimitStore,
+		Algorithm: ratelimit.TokenBucket{Burst: 5, Interval: 3 * time.Minute},
+	})
+	changeEmail, err := usecase.NewChangeEmail(usecase.ChangeEmailOptions{
+		Repository:     r,
+		TokenGenerator: tokenGenerator,
+		TxRunner:       txRunner,
+	})
+	if err != nil {
+		panic(err)
+	}
+	resetPassword, err := usecase.NewResetPassword(usecase.ResetPasswordOptions{
+		Repository:               r,
+		EncoderComparer:          ec,
+		RecoverableTokenValidity: passport.RecoverableTokenValidity,
+		TxRunner:                 txRunner,
+		ValidateAll:              true,
+	})
+	if err != nil {
+		panic(err)
+	}
+	handler := httpapi.New(httpapi.Options{
+		Login: usecase.NewLogin(usecase.LoginOptions{
+			Repository: r,
+			Comparer:   ec,
+			Identifiers: []string{
+				passport.IdentifierEmail,
+				passport.IdentifierUsername,
+				passport.IdentifierPhone,
+			},
+		}),
+		Register: usecase.NewRegister(usecase.RegisterOptions{
+			Repository:  r,
+			Encoder:     ec,
+			ValidateAll: true,
+		}),
+		ChangeEmail: changeEmail,
+		CancelEmailChange: usecase.NewCancelEmailChange(usecase.CancelEmailChangeOptions{
+			Repository: r,
+			TxRunner:   txRunner,
+		}),
+		RevertEmailChange: usecase.NewRevertEmailChange(usecase.RevertEmailChangeOptions{
+			Repository: r,
+			TxRunner:   txRunner,
+		}),
+		ChangePassword: usecase.NewChangePassword(usecase.ChangePasswordOptions{
+			Repository:      r,
+			EncoderComparer: ec,
+			TxRunner:        txRunner,
+			ValidateAll:     true,
+		}),
+		DeleteAccount: usecase.NewDeleteAccount(usecase.DeleteAccountOptions{
+			Repository: r,
+			Comparer:   ec,
+			TxRunner:   txRunner,
+		}),
+		Confirm: usecase.NewConfirm(usecase.ConfirmOptions{
+			Repository:                r,
+			ConfirmationTokenValidity: passport.ConfirmationTokenValidity,
+			TxRunner:                  txRunner,
+		}),
+		ResetPassword: resetPassword,
+		SendConfirmation: usecase.NewSendConfirmation(usecase.SendConfirmationOptions{
+			Repository:     r,
+			TokenGenerator: tokenGenerator,
+			TxRunner:       txRunner,
+		}),
+		RequestResetPassword: usecase.NewRequestResetPassword(usecase.RequestResetPasswordOptions{
+			Repository:     r,
+			TokenGenerator: tokenGenerator,
+			TxRunner:       txRunner,
+		}),
+		LockAccount: usecase.NewLockAccount(usecase.LockAccountOptions{
+			Repository: r,
+			TxRunner:   txRunner,
+		}),
+		Issuer: iss,
+		// The admins have {"roles": ["admin"]} in their extra data.
+		Claims:   httpapi.ExtraRoles("roles"),
+		Mailer:   httpapi.OutboxMailer(connector.NewOutbox(db)),
+		TxRunner: txRunner,
+		AuditLog: connector.NewAuditLog(db).WithHashChain(),
+		Catalog:  catalog,
+		RateLimits: httpapi.RateLimits{
+			Login:                httpapi.RateLimit{IP: perIP, IPEmail: perEmail},
+			Register:             httpapi.RateLimit{IP: perIP},
+			Confirm:              httpapi.RateLimit{IP: perIP},
+			SendConfirmation:     httpapi.RateLimit{IP: perIP, Email: perEmail},
+			RequestResetPassword: httpapi.RateLimit{IP: perIP, Email: perEmail},
+			RevertEmailChange:    httpapi.RateLimit{IP: perIP},
+		},
+	})
+
+	// Relay the mails recorded in the outbox.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	relay := outbox.NewRelay(outbox.RelayOptions{
+		Store: connector.NewOutbox(db),
+		Sink:  m,
+	})
+	go relay.Run(ctx)
+
+	// Send the mails in the queue.
+	worker := mailer.NewWorker(mailer.WorkerOptions{
+		Queue:  mailQueue,
+		Sender: newSender(),
+	})
+	go worker.Run(ctx)
+
+	// Erase the accounts deleted before the grace period.
+	erase := usecase.NewEraseDeletedAccounts(usecase.EraseDeletedAccountsOptions{
+		Repository: r,
+	})
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if n, err := erase.Exec(ctx); err != nil {
+				log.Printf("erase deleted accounts failed: %v\n", err)
+			} else if n > 0 {
+				log.Printf("erased %d deleted accounts\n", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(issuer.JWKSPath, issuer.JWKSHandler(keyring, 5*time.Minute))
+	mux.Handle("/private", httpapi.Protect(iss, http.HandlerFunc(privateHandler)))
+	mux.Handle("/", handler)
 
 	log.Println("Listening to port *:8080. Press ctrl + c to cancel.")
-	http.ListenAndServe(":8080", router)
+	http.ListenAndServe(":8080", mux)
+}
+
+// exportUser writes the personal data stored about the user, for subject
+// access requests.
+func exportUser(db *sql.DB, userID, path string) error {
+	export, err := usecase.NewExportUser(usecase.ExportUserOptions{
+		Repository: connector.NewPostgres(db),
+		AuditLog:   connector.NewAuditLog(db),
+	}).Exec(context.Background(), passport.NewUserID(userID))
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// newSender returns the SMTP mailer when SMTP_HOST is set, and prints the
+// mails otherwise.
+func newSender() interface {
+	Send(context.Context, mailer.Message) error
+} {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return examplemailer.NoopSender{}
+	}
+	port, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	return mailer.NewSMTPMailer(mailer.SMTPOptions{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASS"),
+		From:     os.Getenv("MAIL_FROM"),
+	})
+}
+
+// loadKey reads the PKCS #8 PEM encoded private key used to sign the access
+// tokens. Other services verify the tokens with the public key published at
+// the JWKS endpoint. A key is generated when none is configured, which
+// invalidates the issued tokens on restart.
+func loadKey(id, path string) (passport.Key, error) {
+	if id == "" {
+		id = "key_1"
+	}
+	if path == "" {
+		log.Println("JWT_PRIVATE_KEY_FILE is not set, generating an ephemeral Ed25519 key")
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return passport.Key{}, err
+		}
+		return issuer.NewKey(id, priv)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return passport.Key{}, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return passport.Key{}, errors.New("invalid private key pem")
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return passport.Key{}, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return passport.Key{}, issuer.ErrKeyUnsupported
+	}
+	return issuer.NewKey(id, signer)
 }
 
-func indexHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	api.JSON(w, api.M{"ok": true}, http.StatusOK)
+func privateHandler(w http.ResponseWriter, r *http.Request) {
+	claims, _ := httpapi.ClaimsFromContext(r.Context())
+	httpapi.JSON(w, claims, http.StatusOK)
 }
@@ -0,0 +1,107 @@
+package passport
+
+import (
+	"time"
+)
+
+// RedactedToken replaces the pending tokens in the export, so that the
+// export cannot be used to take over the account.
+const RedactedToken = "[redacted]"
+
+type (
+	// UserExport is the personal data stored about a user, for subject
+	// access requests. The sign in tracking (the Trackable of devise) and
+	// the sessions are not part of it, since they are not stored: the sign
+	// ins are only recorded in the audit log, and the access and refresh
+	// tokens are stateless.
+	UserExport struct {
+		ExportedAt time.Time       `json:"exported_at"`
+		User       ExportedUser    `json:"user"`
+		Tokens     []ExportedToken `json:"tokens"`
+		AuditLog   []AuditEntry    `json:"audit_log"`
+	}
+
+	// ExportedUser holds the fields of the User, without the password. The
+	// optional times are nil when not set.
+	ExportedUser struct {
+		ID               string     `json:"id"`
+		Email            string     `json:"email"`
+		Username         string     `json:"username,omitempty"`
+		Phone            string     `json:"phone,omitempty"`
+		CreatedAt        time.Time  `json:"created_at"`
+		ConfirmedAt      *time.Time `json:"confirmed_at,omitempty"`
+		UnconfirmedEmail string     `json:"unconfirmed_email,omitempty"`
+		PreviousEmail    string     `json:"previous_email,omitempty"`
+		Locked           bool       `json:"locked"`
+		LockedAt         *time.Time `json:"locked_at,omitempty"`
+		DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+		Extra            Extra      `json:"extra,omitempty"`
+	}
+
+	// ExportedToken is a pending token that was sent to the email. Signed
+	// tokens are not stored, and are exported without the token.
+	ExportedToken struct {
+		Purpose string    `json:"purpose"`
+		Token   string    `json:"token,omitempty"`
+		Email   string    `json:"email"`
+		SentAt  time.Time `json:"sent_at"`
+	}
+)
+
+// NewUserExport returns the export of the user and the audit entries, with
+// the tokens redacted.
+func NewUserExport(user User, entries []AuditEntry, exportedAt time.Time) UserExport {
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+	return UserExport{
+		ExportedAt: exportedAt,
+		User: ExportedUser{
+			ID:               user.ID,
+			Email:            user.Email,
+			Username:         user.Username,
+			Phone:            user.Phone,
+			CreatedAt:        user.CreatedAt,
+			ConfirmedAt:      optionalTime(user.ConfirmedAt),
+			UnconfirmedEmail: user.UnconfirmedEmail,
+			PreviousEmail:    user.PreviousEmail,
+			Locked:           user.Locked(),
+			LockedAt:         optionalTime(user.LockedAt),
+			DeletedAt:        optionalTime(user.DeletedAt),
+			Extra:            user.Extra,
+		},
+		Tokens:   exportTokens(user),
+		AuditLog: entries,
+	}
+}
+
+func exportTokens(user User) []ExportedToken {
+	tokens := []ExportedToken{}
+	add := func(purpose, token, email string, sentAt time.Time) {
+		if sentAt.IsZero() {
+			return
+		}
+		if token != "" {
+			token = RedactedToken
+		}
+		tokens = append(tokens, ExportedToken{
+			Purpose: purpose,
+			Token:   token,
+			Email:   email,
+			SentAt:  sentAt,
+		})
+	}
+	add(TokenPurposeConfirmation, user.ConfirmationToken, user.UnconfirmedEmail, user.ConfirmationSentAt)
+	add(TokenPurposeResetPassword, user.ResetPasswordToken, user.Email, user.ResetPasswordSentAt)
+	add(TokenPurposeRevertEmail, user.RevertEmailToken, user.PreviousEmail, user.RevertEmailSentAt)
+	return tokens
+}
+
+// optionalTime returns nil for the zero time, which is otherwise encoded
+// even with omitempty.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
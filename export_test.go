@@ -0,0 +1,60 @@
+package passport_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserExport(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := passport.User{
+		ID:                "1",
+		Email:             "jane.doe@mail.com",
+		EncryptedPassword: passport.NewPassword("password_hash"),
+		Recoverable:       passport.NewRecoverableAt("reset_token", now),
+		Confirmable:       passport.NewConfirmableAt("", "jane.doe@mail.com", now),
+		Revertable:        passport.NewRevertableAt("revert_token", "john.doe@mail.com", now),
+		Extra:             passport.Extra{"name": "Jane"},
+	}
+	export := passport.NewUserExport(user, nil, now)
+
+	assert.Equal("jane.doe@mail.com", export.User.Email)
+	assert.Equal("john.doe@mail.com", export.User.PreviousEmail)
+	assert.Equal(passport.Extra{"name": "Jane"}, export.User.Extra)
+	assert.False(export.User.Locked)
+	assert.Nil(export.User.ConfirmedAt)
+	assert.Nil(export.User.LockedAt)
+	assert.Nil(export.User.DeletedAt)
+	assert.Equal([]passport.ExportedToken{
+		{Purpose: passport.TokenPurposeConfirmation, Email: "jane.doe@mail.com", SentAt: now},
+		{Purpose: passport.TokenPurposeResetPassword, Token: passport.RedactedToken, Email: "jane.doe@mail.com", SentAt: now},
+		{Purpose: passport.TokenPurposeRevertEmail, Token: passport.RedactedToken, Email: "john.doe@mail.com", SentAt: now},
+	}, export.Tokens)
+	assert.Equal([]passport.AuditEntry{}, export.AuditLog)
+
+	b, err := json.Marshal(export)
+	assert.Nil(err)
+	for _, secret := range []string{"password_hash", "reset_token", "revert_token"} {
+		assert.False(strings.Contains(string(b), secret), secret)
+	}
+	for _, unset := range []string{"confirmed_at", "locked_at", "deleted_at"} {
+		assert.False(strings.Contains(string(b), unset), unset)
+	}
+
+	t.Run("when the user is locked and deleted", func(t *testing.T) {
+		user := user
+		user.LockedAt = now
+		user.DeletedAt = now
+		export := passport.NewUserExport(user, nil, now)
+		assert.True(export.User.Locked)
+		assert.Equal(&now, export.User.LockedAt)
+		assert.Equal(&now, export.User.DeletedAt)
+	})
+}
@@ -1,4 +1,30 @@
 package passport
 
+import "encoding/json"
+
 // Extra represents additional data that can be set for the user.
 type Extra map[string]interface{}
+
+// NewExtra returns the Extra of v, which is encoded with the json tags of
+// its fields, e.g. an app-defined profile struct.
+func NewExtra(v interface{}) (Extra, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var e Extra
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Decode unmarshals the extra data into v, e.g. an app-defined profile
+// struct. The keys that are not fields of v are ignored.
+func (e Extra) Decode(v interface{}) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,44 @@
+package passport_test
+
+import (
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type profile struct {
+	Name     string   `json:"name"`
+	Age      int      `json:"age,omitempty"`
+	Hobbies  []string `json:"hobbies,omitempty"`
+	Verified bool     `json:"verified"`
+}
+
+func TestExtraDecode(t *testing.T) {
+	assert := assert.New(t)
+	extra := passport.Extra{
+		"name":    "John",
+		"age":     float64(30),
+		"hobbies": []interface{}{"chess"},
+		"unknown": "ignored",
+	}
+	var p profile
+	assert.Nil(extra.Decode(&p))
+	assert.Equal(profile{Name: "John", Age: 30, Hobbies: []string{"chess"}}, p)
+
+	var invalid struct {
+		Name int `json:"name"`
+	}
+	assert.NotNil(extra.Decode(&invalid))
+}
+
+func TestNewExtra(t *testing.T) {
+	assert := assert.New(t)
+	extra, err := passport.NewExtra(profile{Name: "John", Verified: true})
+	assert.Nil(err)
+	assert.Equal(passport.Extra{"name": "John", "verified": true}, extra)
+
+	_, err = passport.NewExtra("not an object")
+	assert.NotNil(err)
+}
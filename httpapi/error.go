@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/i18n"
+	"github.com/alextanhongpin/passport/status"
+)
+
+var ErrBadRequest = passport.NewError("bad_request", "bad request", passport.CategoryValidation)
+
+var defaultCatalog = i18n.Default()
+
+type (
+	// Error is the JSON body of the failed requests. The validation
+	// errors of the request payload are keyed by the field name.
+	Error struct {
+		Code     string                 `json:"code"`
+		Message  string                 `json:"message"`
+		Category passport.Category      `json:"category"`
+		Params   map[string]interface{} `json:"params,omitempty"`
+		Fields   map[string]FieldError  `json:"fields,omitempty"`
+	}
+
+	FieldError struct {
+		Code    string                 `json:"code"`
+		Message string                 `json:"message"`
+		Params  map[string]interface{} `json:"params,omitempty"`
+	}
+)
+
+// StatusCode returns the http status code of the error. Unknown errors are
+// internal server errors.
+func StatusCode(err error) int {
+	return status.HTTP(err)
+}
+
+// WriteError writes the error with its status code, in the language of the
+// request. Only the code and the message of a passport.Error are exposed.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, defaultCatalog, err, StatusCode(err))
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, catalog *i18n.Catalog, err error, status int) {
+	if retryAfter, ok := passport.RetryAfter(err); ok {
+		// Rounded up, so that the retry is not too early.
+		seconds := (retryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+
+	locale := catalog.Negotiate(r.Header.Get("Accept-Language"))
+	e := passport.AsError(err)
+	body := Error{
+		Code:     e.Code,
+		Message:  catalog.Error(locale, err),
+		Category: e.Category,
+	}
+
+	var p passport.ParamsError
+	if errors.As(err, &p) {
+		body.Params = p.Params
+	}
+
+	var errs passport.ValidationErrors
+	if errors.As(err, &errs) {
+		body.Fields = make(map[string]FieldError)
+		for _, f := range errs {
+			body.Fields[f.Field] = FieldError{
+				Code:    f.Err.Code,
+				Message: catalog.Error(locale, f),
+				Params:  f.Params,
+			}
+		}
+	}
+	JSON(w, body, status)
+}
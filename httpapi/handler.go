@@ -0,0 +1,721 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/i18n"
+	"github.com/alextanhongpin/passport/issuer"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type (
+	loginUsecase interface {
+		Exec(ctx context.Context, cred passport.Credential) (*passport.User, error)
+	}
+
+	registerUsecase interface {
+		Exec(ctx context.Context, cred passport.Credential) (*passport.User, error)
+	}
+
+	changeEmailUsecase interface {
+		Exec(ctx context.Context, currentUserID passport.UserID, email passport.Email) (*passport.EmailChange, error)
+	}
+
+	cancelEmailChangeUsecase interface {
+		Exec(ctx context.Context, currentUserID passport.UserID) error
+	}
+
+	revertEmailChangeUsecase interface {
+		Exec(ctx context.Context, token passport.Token) (*passport.User, error)
+	}
+
+	changePasswordUsecase interface {
+		Exec(ctx context.Context, currentUserID passport.UserID, password, confirmPassword passport.Password) error
+	}
+
+	deleteAccountUsecase interface {
+		Exec(ctx context.Context, currentUserID passport.UserID, password passport.Password) error
+	}
+
+	confirmUsecase interface {
+		Exec(ctx context.Context, token passport.Token) (*passport.User, error)
+	}
+
+	resetPasswordUsecase interface {
+		Exec(ctx context.Context, token passport.Token, password, confirmPassword passport.Password) (*passport.User, error)
+	}
+
+	sendConfirmationUsecase interface {
+		Exec(ctx context.Context, email passport.Email) (string, error)
+	}
+
+	requestResetPasswordUsecase interface {
+		Exec(ctx context.Context, email passport.Email) (string, error)
+	}
+
+	lockAccountUsecase interface {
+		Exec(ctx context.Context, userID passport.UserID) error
+	}
+
+	tokenIssuer interface {
+		verifier
+		IssuePair(claims issuer.Claims) (*issuer.TokenPair, error)
+		Refresh(ctx context.Context, refreshToken string) (*issuer.TokenPair, error)
+		RevokeSession(ctx context.Context, claims *issuer.Claims) error
+		RevokeSubject(ctx context.Context, subject string) error
+	}
+
+	txRunner interface {
+		RunInTx(ctx context.Context, fn func(ctx context.Context) error) error
+	}
+
+	auditLog interface {
+		Append(ctx context.Context, entry passport.AuditEntry) (*passport.AuditEntry, error)
+	}
+
+	// Routes are the paths the flows are mounted on.
+	Routes struct {
+		Login                string
+		Register             string
+		Refresh              string
+		Logout               string
+		ChangeEmail          string
+		CancelEmailChange    string
+		RevertEmailChange    string
+		ChangePassword       string
+		DeleteAccount        string
+		Confirm              string
+		SendConfirmation     string
+		ResetPassword        string
+		RequestResetPassword string
+		LockAccount          string
+	}
+
+	// Options configures the flows of the Handler. A flow is only mounted
+	// when its usecase is set.
+	Options struct {
+		Login                loginUsecase
+		Register             registerUsecase
+		ChangeEmail          changeEmailUsecase
+		CancelEmailChange    cancelEmailChangeUsecase
+		RevertEmailChange    revertEmailChangeUsecase
+		ChangePassword       changePasswordUsecase
+		DeleteAccount        deleteAccountUsecase
+		Confirm              confirmUsecase
+		ResetPassword        resetPasswordUsecase
+		SendConfirmation     sendConfirmationUsecase
+		RequestResetPassword requestResetPasswordUsecase
+
+		// LockAccount is only allowed for the access tokens with the
+		// AdminRole, which defaults to DefaultAdminRole.
+		LockAccount lockAccountUsecase
+		AdminRole   string
+
+		// Issuer issues the tokens after login and register, and
+		// verifies the tokens of the protected routes.
+		Issuer tokenIssuer
+		Mailer Mailer
+
+		// Claims returns the claims of the tokens issued to the user
+		// after login and register, such as the roles. The subject is
+		// always the id of the user. Defaults to the subject only.
+		Claims func(ctx context.Context, user *passport.User) (issuer.Claims, error)
+
+		// TxRunner runs the flows that send mails in a transaction.
+		// AuditLog records the outcome of the flows. Both are optional.
+		TxRunner txRunner
+		AuditLog auditLog
+
+		// Catalog translates the error messages and is negotiated from
+		// the Accept-Language header. Defaults to i18n.Default().
+		Catalog *i18n.Catalog
+
+		// RateLimits are checked before the flows. ClientIP returns the
+		// IP that is rate limited and audited, and defaults to RemoteIP.
+		RateLimits RateLimits
+		ClientIP   func(r *http.Request) string
+
+		// Clock sets the time of the audit entries, and defaults to
+		// passport.SystemClock.
+		Clock passport.Clock
+
+		Routes Routes
+	}
+
+	// Handler serves the passport flows as a JSON API.
+	Handler struct {
+		options Options
+		router  *httprouter.Router
+	}
+)
+
+// DefaultAdminRole is the role required for the admin routes.
+const DefaultAdminRole = "admin"
+
+// DefaultRoutes are used for the routes that are not configured.
+var DefaultRoutes = Routes{
+	Login:                "/login",
+	Register:             "/register",
+	Refresh:              "/refresh",
+	Logout:               "/logout",
+	ChangeEmail:          "/user/emails",
+	CancelEmailChange:    "/user/emails",
+	RevertEmailChange:    "/user/emails/revert",
+	ChangePassword:       "/user/passwords",
+	DeleteAccount:        "/user",
+	Confirm:              "/confirmations",
+	SendConfirmation:     "/confirmations",
+	ResetPassword:        "/passwords",
+	RequestResetPassword: "/passwords",
+	LockAccount:          "/users/:id/lock",
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.router.ServeHTTP(w, r)
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	var req CredentialRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	cred := credential(req)
+	identifier := cred.Identifier()
+	if err := h.limit(r, "login", h.options.RateLimits.Login, identifier.Value); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user, err := h.options.Login.Exec(ctx, cred)
+	h.audit(r, passport.AuditLogin, userID(user), identifier.Value, err)
+	if errors.Is(err, passport.ErrUserNotFound) {
+		// Do not reveal if the identifier is registered.
+		err = passport.ErrEmailOrPasswordInvalid
+	}
+	// The confirmation can only be sent when logging in with the email,
+	// since the email is not revealed to the other identifiers.
+	if errors.Is(err, passport.ErrConfirmationRequired) && h.options.SendConfirmation != nil && identifier.Kind == passport.IdentifierEmail {
+		if err := h.runInTx(ctx, func(ctx context.Context) error {
+			return h.sendMail(ctx, r, passport.EventConfirmationRequested, req.Email, h.options.SendConfirmation.Exec)
+		}); err != nil && !errors.Is(err, passport.ErrTooManyRequests) {
+			// When throttled, the confirmation mail has been sent
+			// recently, so only the confirmation is required.
+			h.writeError(w, r, err)
+			return
+		}
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.issue(w, r, user, http.StatusOK)
+}
+
+func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
+	var req CredentialRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if err := h.limit(r, "register", h.options.RateLimits.Register, req.Email); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	cred := credential(req)
+
+	// The confirmation mail is sent in the same transaction as the new
+	// user, so that it is not lost when the process crashes.
+	var user *passport.User
+	err := h.runInTx(r.Context(), func(ctx context.Context) error {
+		var err error
+		user, err = h.options.Register.Exec(ctx, cred)
+		if err != nil {
+			return err
+		}
+		if h.options.SendConfirmation == nil {
+			return nil
+		}
+		return h.sendMail(ctx, r, passport.EventUserRegistered, req.Email, h.options.SendConfirmation.Exec)
+	})
+	h.audit(r, passport.AuditRegister, userID(user), cred.Identifier().Value, err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.issue(w, r, user, http.StatusCreated)
+}
+
+func (h *Handler) refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	pair, err := h.options.Issuer.Refresh(r.Context(), req.RefreshToken)
+	if errors.Is(err, passport.ErrUserNotFound) {
+		// The user has been deleted since the token was issued.
+		err = passport.ErrTokenInvalid
+	}
+	if err != nil {
+		if errors.Is(err, passport.ErrTokenInvalid) || errors.Is(err, passport.ErrTokenExpired) {
+			writeError(w, r, h.options.Catalog, err, http.StatusUnauthorized)
+			return
+		}
+		h.writeError(w, r, err)
+		return
+	}
+
+	JSON(w, pair, http.StatusOK)
+}
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	if err := h.options.Issuer.RevokeSession(r.Context(), claims); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) changeEmail(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	claims, _ := ClaimsFromContext(r.Context())
+	err := h.runInTx(r.Context(), func(ctx context.Context) error {
+		change, err := h.options.ChangeEmail.Exec(ctx, passport.NewUserID(claims.Subject), passport.NewEmail(req.Email))
+		if err != nil {
+			return err
+		}
+		if err := h.options.Mailer.SendMail(ctx, Mail{
+			Type:   passport.EventEmailChangeRequested,
+			Email:  req.Email,
+			Token:  change.ConfirmationToken,
+			Locale: h.locale(r),
+		}); err != nil {
+			return err
+		}
+
+		// Let the previous email revert the change, in case it was not
+		// made by the owner.
+		return h.options.Mailer.SendMail(ctx, Mail{
+			Type:   passport.EventEmailChanged,
+			Email:  change.PreviousEmail,
+			Token:  change.RevertEmailToken,
+			Locale: h.locale(r),
+		})
+	})
+	h.audit(r, passport.AuditChangeEmail, claims.Subject, "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) cancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	err := h.options.CancelEmailChange.Exec(r.Context(), passport.NewUserID(claims.Subject))
+	h.audit(r, passport.AuditCancelEmailChange, claims.Subject, "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) revertEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req TokenRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if err := h.limit(r, "revert_email_change", h.options.RateLimits.RevertEmailChange, ""); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user, err := h.options.RevertEmailChange.Exec(ctx, passport.NewToken(req.Token))
+	h.audit(r, passport.AuditRevertEmailChange, userID(user), "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Whoever changed the email may still hold the tokens of the user.
+	if err := h.options.Issuer.RevokeSubject(ctx, user.ID); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	var req ChangePasswordRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	claims, _ := ClaimsFromContext(ctx)
+	err := h.options.ChangePassword.Exec(ctx,
+		passport.NewUserID(claims.Subject),
+		passport.NewPassword(req.Password),
+		passport.NewPassword(req.ConfirmPassword),
+	)
+	h.audit(r, passport.AuditChangePassword, claims.Subject, "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// The user has to login again with the new password, and so do the
+	// other sessions, which may belong to whoever knew the old password.
+	if err := h.options.Issuer.RevokeSubject(ctx, claims.Subject); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req DeleteAccountRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	claims, _ := ClaimsFromContext(ctx)
+	err := h.options.DeleteAccount.Exec(ctx,
+		passport.NewUserID(claims.Subject),
+		passport.NewPassword(req.Password),
+	)
+	h.audit(r, passport.AuditDeleteAccount, claims.Subject, "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Revokes every session of the user, not only the current one.
+	if err := h.options.Issuer.RevokeSubject(ctx, claims.Subject); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) confirm(w http.ResponseWriter, r *http.Request) {
+	var req TokenRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if err := h.limit(r, "confirm", h.options.RateLimits.Confirm, ""); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	user, err := h.options.Confirm.Exec(r.Context(), passport.NewToken(req.Token))
+	h.audit(r, passport.AuditConfirm, userID(user), "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) sendConfirmation(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if err := h.limit(r, "send_confirmation", h.options.RateLimits.SendConfirmation, req.Email); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	err := h.runInTx(r.Context(), func(ctx context.Context) error {
+		return h.sendMail(ctx, r, passport.EventConfirmationRequested, req.Email, h.options.SendConfirmation.Exec)
+	})
+	h.audit(r, passport.AuditSendConfirmation, "", req.Email, err)
+	if hidesEmail(err) || errors.Is(err, passport.ErrEmailVerified) {
+		// Do not reveal if the email is registered.
+		err = nil
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user, err := h.options.ResetPassword.Exec(ctx,
+		passport.NewToken(req.Token),
+		passport.NewPassword(req.Password),
+		passport.NewPassword(req.ConfirmPassword),
+	)
+	h.audit(r, passport.AuditResetPassword, userID(user), "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// The sessions issued with the old password are ended.
+	if err := h.options.Issuer.RevokeSubject(ctx, user.ID); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) requestResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := decode(r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if err := h.limit(r, "request_reset_password", h.options.RateLimits.RequestResetPassword, req.Email); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	err := h.runInTx(r.Context(), func(ctx context.Context) error {
+		return h.sendMail(ctx, r, passport.EventResetPasswordRequested, req.Email, h.options.RequestResetPassword.Exec)
+	})
+	h.audit(r, passport.AuditRequestResetPassword, "", req.Email, err)
+	if hidesEmail(err) {
+		// Do not reveal if the email is registered.
+		err = nil
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hidesEmail checks if the error of a mail request would reveal that the
+// email is registered. The mails are only throttled for the registered
+// emails, so a throttled request is answered like an unknown email.
+func hidesEmail(err error) bool {
+	return errors.Is(err, passport.ErrUserNotFound) || errors.Is(err, passport.ErrTooManyRequests)
+}
+
+func (h *Handler) lockAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := httprouter.ParamsFromContext(ctx).ByName("id")
+	err := h.options.LockAccount.Exec(ctx, passport.NewUserID(id))
+	h.audit(r, passport.AuditLockAccount, id, "", err)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Locking only prevents new logins, the issued tokens are revoked.
+	if err := h.options.Issuer.RevokeSubject(ctx, id); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) issue(w http.ResponseWriter, r *http.Request, user *passport.User, status int) {
+	claims := issuer.Claims{Subject: user.ID}
+	if h.options.Claims != nil {
+		var err error
+		claims, err = h.options.Claims(r.Context(), user)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		claims.Subject = user.ID
+	}
+
+	pair, err := h.options.Issuer.IssuePair(claims)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	JSON(w, pair, status)
+}
+
+// sendMail generates the token for the email and mails it in the language
+// of the request.
+func (h *Handler) sendMail(ctx context.Context, r *http.Request, mailType, email string, generate func(context.Context, passport.Email) (string, error)) error {
+	token, err := generate(ctx, passport.NewEmail(email))
+	if err != nil {
+		return err
+	}
+	return h.options.Mailer.SendMail(ctx, Mail{
+		Type:   mailType,
+		Email:  email,
+		Token:  token,
+		Locale: h.locale(r),
+	})
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, h.options.Catalog, err, StatusCode(err))
+}
+
+func (h *Handler) locale(r *http.Request) string {
+	return h.options.Catalog.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+func (h *Handler) runInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if h.options.TxRunner == nil {
+		return fn(ctx)
+	}
+	return h.options.TxRunner.RunInTx(ctx, fn)
+}
+
+// audit records the outcome of the flow on the target user, who is known by
+// the id, or only by the submitted identifier when the flow failed before
+// the user was found. The actor is the user of the access token, and is
+// empty for the public routes. The request is not failed when the audit log
+// is unavailable.
+func (h *Handler) audit(r *http.Request, action, userID, identifier string, err error) {
+	if h.options.AuditLog == nil {
+		return
+	}
+
+	entry := passport.NewAuditEntry(h.options.Clock, action, err)
+	if claims, ok := ClaimsFromContext(r.Context()); ok {
+		entry.ActorID = claims.Subject
+	}
+	entry.UserID = userID
+	entry.Identifier = identifier
+	entry.IP = h.options.ClientIP(r)
+	entry.UserAgent = r.UserAgent()
+	if _, err := h.options.AuditLog.Append(r.Context(), entry); err != nil {
+		log.Printf("audit %s failed: %v\n", action, err)
+	}
+}
+
+func credential(req CredentialRequest) passport.Credential {
+	cred := passport.NewCredential(req.Email, req.Password)
+	cred.Username = passport.NewUsername(req.Username)
+	cred.Phone = passport.NewPhone(req.Phone)
+	return cred
+}
+
+func userID(user *passport.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.ID
+}
+
+func (h *Handler) mount() {
+	var (
+		routes  = h.options.Routes
+		o       = h.options
+		public  = func(fn http.HandlerFunc) http.Handler { return fn }
+		private = func(fn http.HandlerFunc) http.Handler { return protect(o.Issuer, o.Catalog, fn) }
+		admin   = func(fn http.HandlerFunc) http.Handler { return private(requireRole(o.AdminRole, o.Catalog, fn)) }
+	)
+	mount := func(enabled bool, method, path string, handler http.Handler) {
+		if enabled {
+			h.router.Handler(method, path, handler)
+		}
+	}
+	mount(o.Login != nil, "POST", routes.Login, public(h.login))
+	mount(o.Register != nil, "POST", routes.Register, public(h.register))
+	mount(true, "POST", routes.Refresh, public(h.refresh))
+	mount(true, "POST", routes.Logout, private(h.logout))
+	mount(o.ChangeEmail != nil, "POST", routes.ChangeEmail, private(h.changeEmail))
+	mount(o.CancelEmailChange != nil, "DELETE", routes.CancelEmailChange, private(h.cancelEmailChange))
+	mount(o.RevertEmailChange != nil, "PUT", routes.RevertEmailChange, public(h.revertEmailChange))
+	mount(o.ChangePassword != nil, "PUT", routes.ChangePassword, private(h.changePassword))
+	mount(o.DeleteAccount != nil, "DELETE", routes.DeleteAccount, private(h.deleteAccount))
+	mount(o.Confirm != nil, "PUT", routes.Confirm, public(h.confirm))
+	mount(o.SendConfirmation != nil, "POST", routes.SendConfirmation, public(h.sendConfirmation))
+	mount(o.ResetPassword != nil, "PUT", routes.ResetPassword, public(h.resetPassword))
+	mount(o.RequestResetPassword != nil, "POST", routes.RequestResetPassword, public(h.requestResetPassword))
+	mount(o.LockAccount != nil, "PUT", routes.LockAccount, admin(h.lockAccount))
+}
+
+// New returns a new Handler. The Issuer and Mailer are required.
+func New(options Options) *Handler {
+	routes := &options.Routes
+	for _, r := range []struct {
+		path     *string
+		fallback string
+	}{
+		{&routes.Login, DefaultRoutes.Login},
+		{&routes.Register, DefaultRoutes.Register},
+		{&routes.Refresh, DefaultRoutes.Refresh},
+		{&routes.Logout, DefaultRoutes.Logout},
+		{&routes.ChangeEmail, DefaultRoutes.ChangeEmail},
+		{&routes.CancelEmailChange, DefaultRoutes.CancelEmailChange},
+		{&routes.RevertEmailChange, DefaultRoutes.RevertEmailChange},
+		{&routes.ChangePassword, DefaultRoutes.ChangePassword},
+		{&routes.DeleteAccount, DefaultRoutes.DeleteAccount},
+		{&routes.Confirm, DefaultRoutes.Confirm},
+		{&routes.SendConfirmation, DefaultRoutes.SendConfirmation},
+		{&routes.ResetPassword, DefaultRoutes.ResetPassword},
+		{&routes.RequestResetPassword, DefaultRoutes.RequestResetPassword},
+		{&routes.LockAccount, DefaultRoutes.LockAccount},
+	} {
+		if *r.path == "" {
+			*r.path = r.fallback
+		}
+	}
+
+	if options.Catalog == nil {
+		options.Catalog = defaultCatalog
+	}
+	if options.ClientIP == nil {
+		options.ClientIP = RemoteIP
+	}
+	if options.AdminRole == "" {
+		options.AdminRole = DefaultAdminRole
+	}
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+
+	h := &Handler{
+		options: options,
+		router:  httprouter.New(),
+	}
+	h.mount()
+	return h
+}
@@ -0,0 +1,754 @@
+package httpapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/httpapi"
+	"github.com/alextanhongpin/passport/i18n"
+	"github.com/alextanhongpin/passport/issuer"
+	"github.com/alextanhongpin/passport/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockUserUsecase struct {
+	user *passport.User
+	err  error
+	cred passport.Credential
+}
+
+func (m *mockUserUsecase) Exec(ctx context.Context, cred passport.Credential) (*passport.User, error) {
+	m.cred = cred
+	return m.user, m.err
+}
+
+type mockTokenUsecase struct {
+	token string
+	err   error
+}
+
+func (m *mockTokenUsecase) Exec(ctx context.Context, email passport.Email) (string, error) {
+	return m.token, m.err
+}
+
+type mockChangePassword struct {
+	userID passport.UserID
+	err    error
+}
+
+func (m *mockChangePassword) Exec(ctx context.Context, currentUserID passport.UserID, password, confirmPassword passport.Password) error {
+	m.userID = currentUserID
+	return m.err
+}
+
+type mockChangeEmail struct {
+	change *passport.EmailChange
+	err    error
+}
+
+func (m *mockChangeEmail) Exec(ctx context.Context, currentUserID passport.UserID, email passport.Email) (*passport.EmailChange, error) {
+	return m.change, m.err
+}
+
+type mockCancelEmailChange struct {
+	userID passport.UserID
+	err    error
+}
+
+func (m *mockCancelEmailChange) Exec(ctx context.Context, currentUserID passport.UserID) error {
+	m.userID = currentUserID
+	return m.err
+}
+
+type mockRevertEmailChange struct {
+	token passport.Token
+	user  *passport.User
+	err   error
+}
+
+func (m *mockRevertEmailChange) Exec(ctx context.Context, token passport.Token) (*passport.User, error) {
+	m.token = token
+	return m.user, m.err
+}
+
+type mockDeleteAccount struct {
+	userID   passport.UserID
+	password passport.Password
+}
+
+func (m *mockDeleteAccount) Exec(ctx context.Context, currentUserID passport.UserID, password passport.Password) error {
+	m.userID = currentUserID
+	m.password = password
+	return nil
+}
+
+type mockResetPassword struct {
+	user *passport.User
+	err  error
+}
+
+func (m *mockResetPassword) Exec(ctx context.Context, token passport.Token, password, confirmPassword passport.Password) (*passport.User, error) {
+	return m.user, m.err
+}
+
+type mockLockAccount struct {
+	userID passport.UserID
+}
+
+func (m *mockLockAccount) Exec(ctx context.Context, userID passport.UserID) error {
+	m.userID = userID
+	return nil
+}
+
+type mockMailer struct {
+	mails []httpapi.Mail
+}
+
+func (m *mockMailer) SendMail(ctx context.Context, mail httpapi.Mail) error {
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+type mockAuditLog struct {
+	entries []passport.AuditEntry
+}
+
+func (m *mockAuditLog) Append(ctx context.Context, entry passport.AuditEntry) (*passport.AuditEntry, error) {
+	m.entries = append(m.entries, entry)
+	return &entry, nil
+}
+
+func newIssuer() *issuer.Issuer {
+	return issuer.New(issuer.Options{
+		Keyring: passport.NewKeyring(nil, passport.Key{ID: "key_1", Secret: []byte("secret")}),
+	})
+}
+
+func do(h http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestLogin(t *testing.T) {
+	iss := newIssuer()
+
+	t.Run("when login succeeds", func(t *testing.T) {
+		assert := assert.New(t)
+		h := httpapi.New(httpapi.Options{
+			Login:  &mockUserUsecase{user: &passport.User{ID: "1"}},
+			Issuer: iss,
+		})
+		w := do(h, "POST", "/login", `{"email": "john.doe@mail.com", "password": "12345678"}`, "")
+		assert.Equal(http.StatusOK, w.Code)
+
+		var pair issuer.TokenPair
+		assert.Nil(json.NewDecoder(w.Body).Decode(&pair))
+		claims, err := iss.Verify(pair.AccessToken)
+		assert.Nil(err)
+		assert.Equal("1", claims.Subject)
+	})
+
+	t.Run("when claims are set", func(t *testing.T) {
+		assert := assert.New(t)
+		h := httpapi.New(httpapi.Options{
+			Login:  &mockUserUsecase{user: &passport.User{ID: "1", Extra: passport.Extra{"plan": "pro"}}},
+			Issuer: iss,
+			Claims: func(ctx context.Context, user *passport.User) (issuer.Claims, error) {
+				return issuer.Claims{
+					Subject:  "2",
+					Roles:    []string{"admin"},
+					MFALevel: 1,
+					Extra:    map[string]interface{}{"plan": user.Extra["plan"]},
+				}, nil
+			},
+		})
+		w := do(h, "POST", "/login", `{"email": "john.doe@mail.com", "password": "12345678"}`, "")
+		assert.Equal(http.StatusOK, w.Code)
+
+		var pair issuer.TokenPair
+		assert.Nil(json.NewDecoder(w.Body).Decode(&pair))
+		claims, err := iss.Verify(pair.AccessToken)
+		assert.Nil(err)
+		assert.Equal("1", claims.Subject, "the subject is always the user")
+		assert.Equal([]string{"admin"}, claims.Roles)
+		assert.Equal(1, claims.MFALevel)
+		assert.Equal(map[string]interface{}{"plan": "pro"}, claims.Extra)
+	})
+
+	t.Run("when claims fail", func(t *testing.T) {
+		assert := assert.New(t)
+		h := httpapi.New(httpapi.Options{
+			Login:  &mockUserUsecase{user: &passport.User{ID: "1"}},
+			Issuer: iss,
+			Claims: func(ctx context.Context, user *passport.User) (issuer.Claims, error) {
+				return issuer.Claims{}, errors.New("roles unavailable")
+			},
+		})
+		w := do(h, "POST", "/login", `{"email": "john.doe@mail.com", "password": "12345678"}`, "")
+		assert.Equal(http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("when user is not found", func(t *testing.T) {
+		assert := assert.New(t)
+		h := httpapi.New(httpapi.Options{
+			Login:  &mockUserUsecase{err: passport.ErrUserNotFound},
+			Issuer: iss,
+		})
+		w := do(h, "POST", "/login", `{}`, "")
+		assert.Equal(http.StatusUnauthorized, w.Code)
+		assert.JSONEq(`{
+			"code": "email_or_password_invalid",
+			"message": "email or password is invalid",
+			"category": "unauthorized"
+		}`, w.Body.String())
+	})
+
+	t.Run("when confirmation is required", func(t *testing.T) {
+		assert := assert.New(t)
+		mailer := &mockMailer{}
+		h := httpapi.New(httpapi.Options{
+			Login:            &mockUserUsecase{err: passport.ErrConfirmationRequired},
+			SendConfirmation: &mockTokenUsecase{token: "token"},
+			Issuer:           iss,
+			Mailer:           mailer,
+		})
+		w := do(h, "POST", "/login", `{"email": "john.doe@mail.com"}`, "")
+		assert.Equal(http.StatusForbidden, w.Code)
+		assert.Equal([]httpapi.Mail{{
+			Type:   passport.EventConfirmationRequested,
+			Email:  "john.doe@mail.com",
+			Token:  "token",
+			Locale: "en",
+		}}, mailer.mails)
+	})
+
+	t.Run("when confirmation is required and throttled", func(t *testing.T) {
+		assert := assert.New(t)
+		mailer := &mockMailer{}
+		h := httpapi.New(httpapi.Options{
+			Login: &mockUserUsecase{err: passport.ErrConfirmationRequired},
+			SendConfirmation: &mockTokenUsecase{err: passport.RetryAfterError{
+				Err:        passport.ErrTooManyRequests,
+				RetryAfter: time.Minute,
+			}},
+			Issuer: iss,
+			Mailer: mailer,
+		})
+		w := do(h, "POST", "/login", `{"email": "john.doe@mail.com"}`, "")
+		assert.Equal(http.StatusForbidden, w.Code)
+		assert.Nil(mailer.mails)
+	})
+
+	t.Run("when logging in with the username", func(t *testing.T) {
+		assert := assert.New(t)
+		login := &mockUserUsecase{user: &passport.User{ID: "1"}}
+		h := httpapi.New(httpapi.Options{
+			Login:  login,
+			Issuer: iss,
+		})
+		w := do(h, "POST", "/login", `{"username": " John ", "password": "12345678"}`, "")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(passport.Identifier{Kind: passport.IdentifierUsername, Value: "john"}, login.cred.Identifier())
+	})
+
+	t.Run("when confirmation is required for the phone", func(t *testing.T) {
+		assert := assert.New(t)
+		mailer := &mockMailer{}
+		h := httpapi.New(httpapi.Options{
+			Login:            &mockUserUsecase{err: passport.ErrConfirmationRequired},
+			SendConfirmation: &mockTokenUsecase{token: "token"},
+			Issuer:           iss,
+			Mailer:           mailer,
+		})
+		w := do(h, "POST", "/login", `{"phone": "+60123456789"}`, "")
+		assert.Equal(http.StatusForbidden, w.Code)
+		assert.Nil(mailer.mails, "the email is unknown")
+	})
+
+	t.Run("when body is malformed", func(t *testing.T) {
+		h := httpapi.New(httpapi.Options{
+			Login:  &mockUserUsecase{},
+			Issuer: iss,
+		})
+		w := do(h, "POST", "/login", `{`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	assert := assert.New(t)
+	mailer := &mockMailer{}
+	h := httpapi.New(httpapi.Options{
+		Register:         &mockUserUsecase{user: &passport.User{ID: "1"}},
+		SendConfirmation: &mockTokenUsecase{token: "token"},
+		Issuer:           newIssuer(),
+		Mailer:           mailer,
+		Routes:           httpapi.Routes{Register: "/signup"},
+	})
+	w := do(h, "POST", "/signup", `{"email": "john.doe@mail.com", "password": "12345678"}`, "")
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Equal(1, len(mailer.mails))
+	assert.Equal(passport.EventUserRegistered, mailer.mails[0].Type)
+
+	w = do(h, "POST", "/register", `{}`, "")
+	assert.Equal(http.StatusNotFound, w.Code, "the route is configured")
+	w = do(h, "POST", "/login", `{}`, "")
+	assert.Equal(http.StatusNotFound, w.Code, "the flow is not mounted")
+}
+
+func TestRegisterIdentifiers(t *testing.T) {
+	assert := assert.New(t)
+	register := &mockUserUsecase{err: passport.ErrUsernameExists}
+	h := httpapi.New(httpapi.Options{
+		Register: register,
+		Issuer:   newIssuer(),
+	})
+	w := do(h, "POST", "/register", `{"email": "john.doe@mail.com", "username": "John", "phone": "+60 12-345 6789", "password": "12345678"}`, "")
+	assert.Equal(http.StatusConflict, w.Code)
+	assert.Equal([]passport.Identifier{
+		{Kind: passport.IdentifierUsername, Value: "john"},
+		{Kind: passport.IdentifierPhone, Value: "+60123456789"},
+	}, register.cred.AlternateIdentifiers())
+}
+
+func TestRegisterValidation(t *testing.T) {
+	assert := assert.New(t)
+	errs := passport.ValidationErrors{}.
+		Add("email", passport.ErrEmailInvalid, nil).
+		Add("password", passport.ErrPasswordTooShort, map[string]interface{}{"min": 8})
+	h := httpapi.New(httpapi.Options{
+		Register: &mockUserUsecase{err: errs},
+		Issuer:   newIssuer(),
+	})
+	w := do(h, "POST", "/register", `{"email": "john.doe", "password": "12345"}`, "")
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.JSONEq(`{
+		"code": "validation_failed",
+		"message": "validation failed",
+		"category": "validation",
+		"fields": {
+			"email": {"code": "email_invalid", "message": "email invalid"},
+			"password": {"code": "password_too_short", "message": "password must be at least 8 characters", "params": {"min": 8}}
+		}
+	}`, w.Body.String())
+}
+
+func TestErrorParams(t *testing.T) {
+	assert := assert.New(t)
+	h := httpapi.New(httpapi.Options{
+		Login:  &mockUserUsecase{err: passport.ErrPasswordTooShort.WithParams(map[string]interface{}{"min": 8})},
+		Issuer: newIssuer(),
+	})
+	w := do(h, "POST", "/login", `{"email": "john.doe@mail.com", "password": "12345"}`, "")
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.JSONEq(`{
+		"code": "password_too_short",
+		"message": "password must be at least 8 characters",
+		"category": "validation",
+		"params": {"min": 8}
+	}`, w.Body.String())
+}
+
+func TestLocalizedError(t *testing.T) {
+	assert := assert.New(t)
+	catalog := i18n.Default()
+	catalog.Add(i18n.Malay, map[string]string{"email_exists": "emel sudah didaftarkan"})
+	h := httpapi.New(httpapi.Options{
+		Register: &mockUserUsecase{err: passport.ErrEmailExists},
+		Issuer:   newIssuer(),
+		Catalog:  catalog,
+	})
+
+	r := httptest.NewRequest("POST", "/register", strings.NewReader(`{}`))
+	r.Header.Set("Accept-Language", "ms-MY,en;q=0.5")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(http.StatusConflict, w.Code)
+	assert.JSONEq(`{
+		"code": "email_exists",
+		"message": "emel sudah didaftarkan",
+		"category": "conflict"
+	}`, w.Body.String())
+}
+
+func TestChangePassword(t *testing.T) {
+	assert := assert.New(t)
+	iss := newIssuer()
+	changePassword := &mockChangePassword{}
+	h := httpapi.New(httpapi.Options{
+		ChangePassword: changePassword,
+		Issuer:         iss,
+	})
+
+	w := do(h, "PUT", "/user/passwords", `{}`, "")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	other, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	w = do(h, "PUT", "/user/passwords", `{"password": "12345678", "confirm_password": "12345678"}`, pair.AccessToken)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(passport.NewUserID("1"), changePassword.userID)
+
+	// Every session of the user is revoked after the password change.
+	for _, pair := range []*issuer.TokenPair{pair, other} {
+		w = do(h, "PUT", "/user/passwords", `{}`, pair.AccessToken)
+		assert.Equal(http.StatusUnauthorized, w.Code)
+		w = do(h, "POST", "/refresh", `{"refresh_token": "`+pair.RefreshToken+`"}`, "")
+		assert.Equal(http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	assert := assert.New(t)
+	iss := newIssuer()
+	h := httpapi.New(httpapi.Options{
+		ResetPassword: &mockResetPassword{user: &passport.User{ID: "1"}},
+		Issuer:        iss,
+	})
+
+	pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	w := do(h, "PUT", "/passwords", `{"token": "token", "password": "12345678", "confirm_password": "12345678"}`, "")
+	assert.Equal(http.StatusNoContent, w.Code)
+
+	// The sessions issued before the reset are revoked.
+	w = do(h, "POST", "/refresh", `{"refresh_token": "`+pair.RefreshToken+`"}`, "")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func TestLockAccount(t *testing.T) {
+	assert := assert.New(t)
+	iss := newIssuer()
+	lock := &mockLockAccount{}
+	h := httpapi.New(httpapi.Options{
+		LockAccount: lock,
+		Issuer:      iss,
+	})
+
+	user, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	w := do(h, "PUT", "/users/1/lock", ``, user.AccessToken)
+	assert.Equal(http.StatusForbidden, w.Code, "admin role is required")
+	assert.Equal(passport.UserID(""), lock.userID)
+
+	admin, err := iss.IssuePair(issuer.Claims{Subject: "2", Roles: []string{httpapi.DefaultAdminRole}})
+	assert.Nil(err)
+	w = do(h, "PUT", "/users/1/lock", ``, admin.AccessToken)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(passport.NewUserID("1"), lock.userID)
+
+	// The sessions of the locked user are revoked.
+	w = do(h, "POST", "/refresh", `{"refresh_token": "`+user.RefreshToken+`"}`, "")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	w = do(h, "POST", "/refresh", `{"refresh_token": "`+admin.RefreshToken+`"}`, "")
+	assert.Equal(http.StatusOK, w.Code)
+}
+
+func TestLockAccountIssuedRole(t *testing.T) {
+	iss := newIssuer()
+	login := func(t *testing.T, h http.Handler) string {
+		w := do(h, "POST", "/login", `{"email": "john.doe@mail.com", "password": "12345678"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var pair issuer.TokenPair
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&pair))
+		return pair.AccessToken
+	}
+
+	t.Run("when the user is an admin", func(t *testing.T) {
+		assert := assert.New(t)
+		lock := &mockLockAccount{}
+		h := httpapi.New(httpapi.Options{
+			Login:       &mockUserUsecase{user: &passport.User{ID: "2", Extra: passport.Extra{"roles": []interface{}{"admin"}}}},
+			LockAccount: lock,
+			Issuer:      iss,
+			Claims:      httpapi.ExtraRoles("roles"),
+		})
+		w := do(h, "PUT", "/users/1/lock", ``, login(t, h))
+		assert.Equal(http.StatusNoContent, w.Code)
+		assert.Equal(passport.NewUserID("1"), lock.userID)
+	})
+
+	t.Run("when the user has no roles", func(t *testing.T) {
+		assert := assert.New(t)
+		lock := &mockLockAccount{}
+		h := httpapi.New(httpapi.Options{
+			Login:       &mockUserUsecase{user: &passport.User{ID: "2"}},
+			LockAccount: lock,
+			Issuer:      iss,
+			Claims:      httpapi.ExtraRoles("roles"),
+		})
+		w := do(h, "PUT", "/users/1/lock", ``, login(t, h))
+		assert.Equal(http.StatusForbidden, w.Code)
+		assert.Equal(passport.UserID(""), lock.userID)
+	})
+}
+
+func TestAudit(t *testing.T) {
+	iss := newIssuer()
+
+	t.Run("when login fails", func(t *testing.T) {
+		assert := assert.New(t)
+		auditLog := &mockAuditLog{}
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		h := httpapi.New(httpapi.Options{
+			Login:    &mockUserUsecase{err: passport.ErrUserNotFound},
+			Issuer:   iss,
+			AuditLog: auditLog,
+			Clock:    passport.NewFakeClock(now),
+		})
+		do(h, "POST", "/login", `{"email": "john.doe@mail.com", "password": "12345678"}`, "")
+		assert.Equal(1, len(auditLog.entries))
+
+		entry := auditLog.entries[0]
+		assert.Equal(now, entry.CreatedAt)
+		assert.Equal(passport.AuditFailure, entry.Outcome)
+		assert.Equal("", entry.ActorID)
+		assert.Equal("", entry.UserID)
+		assert.Equal("john.doe@mail.com", entry.Identifier)
+	})
+
+	t.Run("when admin locks the user", func(t *testing.T) {
+		assert := assert.New(t)
+		auditLog := &mockAuditLog{}
+		h := httpapi.New(httpapi.Options{
+			LockAccount: &mockLockAccount{},
+			Issuer:      iss,
+			AuditLog:    auditLog,
+		})
+		admin, err := iss.IssuePair(issuer.Claims{Subject: "2", Roles: []string{httpapi.DefaultAdminRole}})
+		assert.Nil(err)
+		do(h, "PUT", "/users/1/lock", ``, admin.AccessToken)
+		assert.Equal(1, len(auditLog.entries))
+
+		entry := auditLog.entries[0]
+		assert.Equal(passport.AuditSuccess, entry.Outcome)
+		assert.Equal("2", entry.ActorID)
+		assert.Equal("1", entry.UserID)
+	})
+}
+
+func TestChangeEmail(t *testing.T) {
+	assert := assert.New(t)
+	iss := newIssuer()
+	mailer := &mockMailer{}
+	var (
+		cancel = &mockCancelEmailChange{}
+		revert = &mockRevertEmailChange{user: &passport.User{ID: "1"}}
+	)
+	h := httpapi.New(httpapi.Options{
+		ChangeEmail: &mockChangeEmail{change: &passport.EmailChange{
+			ConfirmationToken: "abc",
+			PreviousEmail:     "john.doe@mail.com",
+			RevertEmailToken:  "xyz",
+		}},
+		CancelEmailChange: cancel,
+		RevertEmailChange: revert,
+		Issuer:            iss,
+		Mailer:            mailer,
+	})
+
+	pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	w := do(h, "POST", "/user/emails", `{"email": "jane.doe@mail.com"}`, pair.AccessToken)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal([]httpapi.Mail{
+		{Type: passport.EventEmailChangeRequested, Email: "jane.doe@mail.com", Token: "abc", Locale: "en"},
+		{Type: passport.EventEmailChanged, Email: "john.doe@mail.com", Token: "xyz", Locale: "en"},
+	}, mailer.mails)
+
+	w = do(h, "DELETE", "/user/emails", ``, "")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	w = do(h, "DELETE", "/user/emails", ``, pair.AccessToken)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(passport.NewUserID("1"), cancel.userID)
+
+	// The previous email reverts the change without an access token.
+	w = do(h, "PUT", "/user/emails/revert", `{"token": "xyz"}`, "")
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(passport.NewToken("xyz"), revert.token)
+
+	// The sessions of whoever changed the email are revoked.
+	w = do(h, "DELETE", "/user/emails", ``, pair.AccessToken)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	w = do(h, "POST", "/refresh", `{"refresh_token": "`+pair.RefreshToken+`"}`, "")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func TestRefreshDeletedUser(t *testing.T) {
+	assert := assert.New(t)
+	iss := issuer.New(issuer.Options{
+		Keyring: passport.NewKeyring(nil, passport.Key{ID: "key_1", Secret: []byte("secret")}),
+		ValidateSubject: func(ctx context.Context, subject string) error {
+			return passport.ErrUserNotFound
+		},
+	})
+	h := httpapi.New(httpapi.Options{Issuer: iss})
+
+	pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	w := do(h, "POST", "/refresh", `{"refresh_token": "`+pair.RefreshToken+`"}`, "")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	assert := assert.New(t)
+	iss := newIssuer()
+	deleteAccount := &mockDeleteAccount{}
+	h := httpapi.New(httpapi.Options{
+		DeleteAccount: deleteAccount,
+		Issuer:        iss,
+	})
+
+	w := do(h, "DELETE", "/user", `{"password": "12345678"}`, "")
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	other, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+	w = do(h, "DELETE", "/user", `{"password": "12345678"}`, pair.AccessToken)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(passport.NewUserID("1"), deleteAccount.userID)
+	assert.Equal("12345678", deleteAccount.password.Value())
+
+	// Every session of the user is revoked after the account is deleted.
+	for _, pair := range []*issuer.TokenPair{pair, other} {
+		w = do(h, "DELETE", "/user", `{"password": "12345678"}`, pair.AccessToken)
+		assert.Equal(http.StatusUnauthorized, w.Code)
+		w = do(h, "POST", "/refresh", `{"refresh_token": "`+pair.RefreshToken+`"}`, "")
+		assert.Equal(http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestSendConfirmationUnknownEmail(t *testing.T) {
+	assert := assert.New(t)
+	for _, err := range []error{passport.ErrUserNotFound, passport.ErrEmailVerified} {
+		mailer := &mockMailer{}
+		h := httpapi.New(httpapi.Options{
+			SendConfirmation: &mockTokenUsecase{err: err},
+			Issuer:           newIssuer(),
+			Mailer:           mailer,
+		})
+		w := do(h, "POST", "/confirmations", `{"email": "john.doe@mail.com"}`, "")
+		assert.Equal(http.StatusNoContent, w.Code, "does not reveal if the email is registered")
+		assert.Equal(0, len(mailer.mails))
+	}
+}
+
+func TestRequestResetPasswordUnknownEmail(t *testing.T) {
+	assert := assert.New(t)
+	mailer := &mockMailer{}
+	auditLog := &mockAuditLog{}
+	h := httpapi.New(httpapi.Options{
+		RequestResetPassword: &mockTokenUsecase{err: passport.ErrUserNotFound},
+		Issuer:               newIssuer(),
+		Mailer:               mailer,
+		AuditLog:             auditLog,
+	})
+	w := do(h, "POST", "/passwords", `{"email": "john.doe@mail.com"}`, "")
+	assert.Equal(http.StatusNoContent, w.Code, "does not reveal if the email is registered")
+	assert.Equal(0, len(mailer.mails))
+
+	// The attempt is still audited as a failure.
+	assert.Equal(1, len(auditLog.entries))
+	assert.Equal(passport.AuditFailure, auditLog.entries[0].Outcome)
+	assert.Equal("john.doe@mail.com", auditLog.entries[0].Identifier)
+}
+
+func TestMailRequestThrottled(t *testing.T) {
+	throttled := passport.RetryAfterError{
+		Err:        passport.ErrTooManyRequests,
+		RetryAfter: 1500 * time.Millisecond,
+	}
+	for _, path := range []string{"/confirmations", "/passwords"} {
+		assert := assert.New(t)
+		mailer := &mockMailer{}
+		auditLog := &mockAuditLog{}
+		h := httpapi.New(httpapi.Options{
+			SendConfirmation:     &mockTokenUsecase{err: throttled},
+			RequestResetPassword: &mockTokenUsecase{err: throttled},
+			Issuer:               newIssuer(),
+			Mailer:               mailer,
+			AuditLog:             auditLog,
+		})
+		w := do(h, "POST", path, `{"email": "john.doe@mail.com"}`, "")
+		assert.Equal(http.StatusNoContent, w.Code, "does not reveal if the email is registered")
+		assert.Equal("", w.Header().Get("Retry-After"))
+		assert.Equal(0, len(mailer.mails))
+		assert.Equal(1, len(auditLog.entries))
+		assert.Equal(passport.AuditFailure, auditLog.entries[0].Outcome)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := ratelimit.New(ratelimit.Options{
+		Store:     ratelimit.NewMemoryStore(clock),
+		Algorithm: ratelimit.TokenBucket{Burst: 1, Interval: time.Minute},
+		Clock:     clock,
+	})
+	h := httpapi.New(httpapi.Options{
+		Login:  &mockUserUsecase{err: passport.ErrEmailOrPasswordInvalid},
+		Issuer: newIssuer(),
+		RateLimits: httpapi.RateLimits{
+			Login: httpapi.RateLimit{IP: limiter, Email: limiter},
+		},
+		ClientIP: func(r *http.Request) string {
+			return r.Header.Get("X-Real-IP")
+		},
+	})
+	login := func(ip, email string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/login", strings.NewReader(`{"email": "`+email+`"}`))
+		r.Header.Set("X-Real-IP", ip)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(http.StatusUnauthorized, login("1.2.3.4", "john.doe@mail.com").Code)
+
+	// Limited by IP.
+	w := login("1.2.3.4", "jane.doe@mail.com")
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal("60", w.Header().Get("Retry-After"))
+
+	// Limited by email, regardless of the case.
+	assert.Equal(http.StatusTooManyRequests, login("5.6.7.8", "John.Doe@mail.com").Code)
+
+	clock.Advance(time.Minute)
+	assert.Equal(http.StatusUnauthorized, login("1.2.3.4", "john.doe@mail.com").Code)
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{passport.ErrEmailRequired, http.StatusBadRequest},
+		{passport.ErrEmailOrPasswordInvalid, http.StatusUnauthorized},
+		{passport.ErrConfirmationRequired, http.StatusForbidden},
+		{passport.ErrUserNotFound, http.StatusNotFound},
+		{passport.ErrEmailExists, http.StatusConflict},
+		{passport.ErrTooManyRequests, http.StatusTooManyRequests},
+		{errors.New("bad db"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, httpapi.StatusCode(tt.err), tt.err.Error())
+	}
+}
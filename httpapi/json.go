@@ -1,12 +1,20 @@
-package api
+package httpapi
 
 import (
 	"encoding/json"
 	"net/http"
 )
 
+// JSON writes the body as JSON with the given status.
 func JSON(w http.ResponseWriter, body interface{}, status int) {
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(body)
 }
+
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return ErrBadRequest
+	}
+	return nil
+}
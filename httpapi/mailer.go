@@ -0,0 +1,60 @@
+package httpapi
+
+import (
+	"context"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	// Mail holds the token to be sent to the user. The type is one of the
+	// passport event types, e.g. passport.EventUserRegistered, and the
+	// locale is negotiated from the request.
+	Mail struct {
+		Type   string
+		Email  string
+		Token  string
+		Locale string
+	}
+
+	// Mailer sends the mail to the user. It is called within the
+	// transaction of the flow, so the mail can be recorded in an outbox
+	// with OutboxMailer and sent after the commit.
+	Mailer interface {
+		SendMail(ctx context.Context, mail Mail) error
+	}
+
+	// MailerFunc adapts a function to the Mailer interface.
+	MailerFunc func(ctx context.Context, mail Mail) error
+
+	// MailPayload is the payload of the events appended by OutboxMailer.
+	MailPayload struct {
+		Email  string `json:"email"`
+		Token  string `json:"token"`
+		Locale string `json:"locale,omitempty"`
+	}
+
+	outbox interface {
+		Append(ctx context.Context, event passport.Event) (string, error)
+	}
+)
+
+func (fn MailerFunc) SendMail(ctx context.Context, mail Mail) error {
+	return fn(ctx, mail)
+}
+
+// OutboxMailer records the mails as events in the outbox.
+func OutboxMailer(o outbox) Mailer {
+	return MailerFunc(func(ctx context.Context, mail Mail) error {
+		event, err := passport.NewEvent(mail.Type, MailPayload{
+			Email:  mail.Email,
+			Token:  mail.Token,
+			Locale: mail.Locale,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = o.Append(ctx, event)
+		return err
+	})
+}
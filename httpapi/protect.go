@@ -0,0 +1,107 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/i18n"
+	"github.com/alextanhongpin/passport/issuer"
+)
+
+type (
+	verifier interface {
+		Verify(token string) (*issuer.Claims, error)
+		Denied(ctx context.Context, claims *issuer.Claims) (bool, error)
+	}
+
+	contextKey string
+)
+
+var claimsContext = contextKey("claims")
+
+// ErrForbidden indicates the access token does not have the required role.
+var ErrForbidden = passport.NewError("forbidden", "forbidden", passport.CategoryForbidden)
+
+// ClaimsFromContext returns the claims of the access token set by Protect.
+func ClaimsFromContext(ctx context.Context) (*issuer.Claims, bool) {
+	claims, ok := ctx.Value(claimsContext).(*issuer.Claims)
+	return claims, ok
+}
+
+// Protect only allows requests with a valid bearer access token that is not
+// revoked. The verifier is either the issuer.Issuer of this service, or an
+// issuer.RemoteVerifier in other services.
+func Protect(v verifier, next http.Handler) http.Handler {
+	return protect(v, defaultCatalog, next)
+}
+
+func protect(v verifier, catalog *i18n.Catalog, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			writeError(w, r, catalog, passport.ErrTokenRequired, http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := v.Verify(token)
+		if err != nil {
+			writeError(w, r, catalog, err, http.StatusUnauthorized)
+			return
+		}
+
+		denied, err := v.Denied(r.Context(), claims)
+		if err != nil {
+			writeError(w, r, catalog, err, StatusCode(err))
+			return
+		}
+		if denied {
+			writeError(w, r, catalog, passport.ErrTokenInvalid, http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsContext, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireRole only allows the requests whose access token has the role. It
+// must be wrapped by protect.
+func requireRole(role string, catalog *i18n.Catalog, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		for _, granted := range claims.Roles {
+			if granted == role {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		writeError(w, r, catalog, ErrForbidden, http.StatusForbidden)
+	})
+}
+
+// ExtraRoles returns a Claims option that issues the roles stored in the
+// extra data of the user under the key, e.g. {"roles": ["admin"]}, so that
+// the admins can use the admin routes. The key must not be writable by the
+// users themselves.
+func ExtraRoles(key string) func(ctx context.Context, user *passport.User) (issuer.Claims, error) {
+	return func(ctx context.Context, user *passport.User) (issuer.Claims, error) {
+		var roles struct {
+			Roles []string `json:"roles"`
+		}
+		if err := (passport.Extra{"roles": user.Extra[key]}).Decode(&roles); err != nil {
+			return issuer.Claims{}, err
+		}
+		return issuer.Claims{Subject: user.ID, Roles: roles.Roles}, nil
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	const prefix = "bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
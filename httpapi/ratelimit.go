@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/ratelimit"
+)
+
+type (
+	limiter interface {
+		Allow(ctx context.Context, key string) (ratelimit.Result, error)
+	}
+
+	// RateLimit limits the requests to a route by the IP of the client,
+	// by the email of the request, and by both. On login, the username or
+	// phone is limited in place of the email. The limits that are not
+	// set are not applied.
+	RateLimit struct {
+		IP      limiter
+		Email   limiter
+		IPEmail limiter
+	}
+
+	// RateLimits are the limits of the routes that can be abused without
+	// an access token.
+	RateLimits struct {
+		Login                RateLimit
+		Register             RateLimit
+		Confirm              RateLimit
+		SendConfirmation     RateLimit
+		RequestResetPassword RateLimit
+		RevertEmailChange    RateLimit
+	}
+)
+
+// limit takes a request for the route from each limiter. The keys are
+// prefixed by the route, so that a limiter can be shared by the routes.
+func (h *Handler) limit(r *http.Request, route string, rl RateLimit, email string) error {
+	ip := h.options.ClientIP(r)
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	checks := []struct {
+		limiter limiter
+		key     string
+		skip    bool
+	}{
+		{rl.IP, route + ":ip:" + ip, false},
+		{rl.Email, route + ":email:" + email, email == ""},
+		{rl.IPEmail, route + ":ip_email:" + ip + ":" + email, email == ""},
+	}
+
+	var limited bool
+	var retryAfter time.Duration
+	for _, c := range checks {
+		if c.limiter == nil || c.skip {
+			continue
+		}
+		res, err := c.limiter.Allow(r.Context(), c.key)
+		if err != nil {
+			return err
+		}
+		if !res.Allowed {
+			limited = true
+			if res.RetryAfter > retryAfter {
+				retryAfter = res.RetryAfter
+			}
+		}
+	}
+	if limited {
+		return passport.RetryAfterError{Err: passport.ErrTooManyRequests, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RemoteIP returns the IP of the connection. Use a ClientIP that reads the
+// X-Forwarded-For header set by a trusted proxy instead when the server is
+// behind one.
+func RemoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,39 @@
+package httpapi
+
+type (
+	// CredentialRequest logs in with either the email, username or phone.
+	// On register, the username and phone are stored along with the email.
+	CredentialRequest struct {
+		Email    string `json:"email"`
+		Username string `json:"username,omitempty"`
+		Phone    string `json:"phone,omitempty"`
+		Password string `json:"password"`
+	}
+
+	RefreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	EmailRequest struct {
+		Email string `json:"email"`
+	}
+
+	TokenRequest struct {
+		Token string `json:"token"`
+	}
+
+	ChangePasswordRequest struct {
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirm_password"`
+	}
+
+	DeleteAccountRequest struct {
+		Password string `json:"password"`
+	}
+
+	ResetPasswordRequest struct {
+		Token           string `json:"token"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirm_password"`
+	}
+)
@@ -0,0 +1,199 @@
+// Package i18n translates the error and mail messages of passport.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// Locales of the built-in messages.
+const (
+	English = "en"
+	Malay   = "ms"
+	Chinese = "zh"
+)
+
+// Keys of the mail messages. The error messages are keyed by the error
+// code.
+const (
+	MailConfirmationSubject  = "mail.confirmation.subject"
+	MailConfirmationBody     = "mail.confirmation.body"
+	MailConfirmationAction   = "mail.confirmation.action"
+	MailChangeEmailSubject   = "mail.change_email.subject"
+	MailChangeEmailBody      = "mail.change_email.body"
+	MailChangeEmailAction    = "mail.change_email.action"
+	MailEmailChangedSubject  = "mail.email_changed.subject"
+	MailEmailChangedBody     = "mail.email_changed.body"
+	MailEmailChangedAction   = "mail.email_changed.action"
+	MailResetPasswordSubject = "mail.reset_password.subject"
+	MailResetPasswordBody    = "mail.reset_password.body"
+	MailResetPasswordAction  = "mail.reset_password.action"
+	MailIgnore               = "mail.ignore"
+)
+
+// Catalog holds the messages of each locale. The messages may have named
+// placeholders such as {token}, which are replaced by the params.
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+	fallback string
+}
+
+// Add adds or overrides the messages of the locale. A new locale is added
+// when it does not exist.
+func (c *Catalog) Add(locale string, messages map[string]string) {
+	locale = normalize(locale)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]string)
+	}
+	for key, msg := range messages {
+		c.messages[locale][key] = msg
+	}
+}
+
+// Locales returns the supported locales, sorted.
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Has checks if the locale has a message for the key.
+func (c *Catalog) Has(locale, key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.messages[normalize(locale)][key]
+	return ok
+}
+
+// Translate returns the message of the key in the locale. It falls back to
+// the base language, e.g. zh for zh-tw, then the fallback locale, and
+// finally the key itself.
+func (c *Catalog) Translate(locale, key string, params map[string]interface{}) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locale = normalize(locale)
+	for _, l := range []string{locale, base(locale), c.fallback} {
+		if msg, ok := c.messages[l][key]; ok {
+			return interpolate(msg, params)
+		}
+	}
+	return key
+}
+
+// Error returns the message of the error in the locale, with the params of
+// a FieldError or ParamsError. The message of the unknown errors is not
+// exposed.
+func (c *Catalog) Error(locale string, err error) string {
+	e := passport.AsError(err)
+	var params map[string]interface{}
+	var f passport.FieldError
+	var p passport.ParamsError
+	if errors.As(err, &f) {
+		params = f.Params
+	} else if errors.As(err, &p) {
+		params = p.Params
+	}
+	if msg := c.Translate(locale, e.Code, params); msg != e.Code {
+		return msg
+	}
+	return e.Message
+}
+
+// Negotiate returns the supported locale that best matches the
+// Accept-Language header, or the fallback locale.
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	type tag struct {
+		locale string
+		q      float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := normalize(fields[0])
+		if locale == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{locale, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		if _, ok := c.messages[t.locale]; ok {
+			return t.locale
+		}
+		if _, ok := c.messages[base(t.locale)]; ok {
+			return base(t.locale)
+		}
+	}
+	return c.fallback
+}
+
+// NewCatalog returns an empty Catalog with the given fallback locale.
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		messages: make(map[string]map[string]string),
+		fallback: normalize(fallback),
+	}
+}
+
+// Default returns a new Catalog with the built-in English, Malay and
+// Chinese messages, falling back to English. Apps can override the
+// messages or add languages with Add.
+func Default() *Catalog {
+	c := NewCatalog(English)
+	c.Add(English, english)
+	c.Add(Malay, malay)
+	c.Add(Chinese, chinese)
+	return c
+}
+
+func normalize(locale string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(locale), "_", "-", -1))
+}
+
+func base(locale string) string {
+	if i := strings.Index(locale, "-"); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+func interpolate(msg string, params map[string]interface{}) string {
+	for k, v := range params {
+		msg = strings.Replace(msg, "{"+k+"}", fmt.Sprint(v), -1)
+	}
+	return msg
+}
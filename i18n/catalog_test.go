@@ -0,0 +1,109 @@
+package i18n_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/httpapi"
+	"github.com/alextanhongpin/passport/i18n"
+	"github.com/alextanhongpin/passport/issuer"
+	"github.com/alextanhongpin/passport/mailer"
+	"github.com/alextanhongpin/passport/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Registers the errors of the other packages.
+var (
+	_ = httpapi.ErrBadRequest
+	_ = issuer.ErrIssuerInvalid
+	_ = mailer.ErrTemplateNotFound
+	_ = usecase.ErrTxRunnerRequired
+)
+
+func TestCatalogComplete(t *testing.T) {
+	catalog := i18n.Default()
+	keys := []string{
+		i18n.MailConfirmationSubject,
+		i18n.MailConfirmationBody,
+		i18n.MailChangeEmailSubject,
+		i18n.MailChangeEmailBody,
+		i18n.MailEmailChangedSubject,
+		i18n.MailEmailChangedBody,
+		i18n.MailResetPasswordSubject,
+		i18n.MailResetPasswordBody,
+		i18n.MailConfirmationAction,
+		i18n.MailChangeEmailAction,
+		i18n.MailEmailChangedAction,
+		i18n.MailResetPasswordAction,
+		i18n.MailIgnore,
+	}
+	for _, e := range passport.Errors() {
+		keys = append(keys, e.Code)
+	}
+
+	for _, locale := range []string{i18n.English, i18n.Malay, i18n.Chinese} {
+		for _, key := range keys {
+			assert.True(t, catalog.Has(locale, key), "%s: missing %s", locale, key)
+		}
+	}
+}
+
+func TestCatalogNegotiate(t *testing.T) {
+	catalog := i18n.Default()
+	tests := []struct {
+		acceptLanguage string
+		locale         string
+	}{
+		{"", i18n.English},
+		{"ms", i18n.Malay},
+		{"zh-CN,zh;q=0.9,en;q=0.8", i18n.Chinese},
+		{"fr-FR, ms;q=0.5, en;q=0.4", i18n.Malay},
+		{"en;q=0.1, zh-TW;q=0.9", i18n.Chinese},
+		{"fr, de", i18n.English},
+		{"ms;q=0, zh", i18n.Chinese},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.locale, catalog.Negotiate(tt.acceptLanguage), tt.acceptLanguage)
+	}
+}
+
+func TestCatalogTranslate(t *testing.T) {
+	catalog := i18n.Default()
+
+	t.Run("when translating errors", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal("emel diperlukan", catalog.Error(i18n.Malay, passport.ErrEmailRequired))
+		assert.Equal("电子邮件无效", catalog.Error("zh-TW", passport.ErrEmailInvalid))
+		assert.Equal("internal error", catalog.Error(i18n.English, errors.New("pq: connection refused")))
+
+		errs := passport.ValidationErrors{}.Add("password", passport.ErrPasswordTooShort, map[string]interface{}{"min": 8})
+		assert.Equal("kata laluan mestilah sekurang-kurangnya 8 aksara", catalog.Error(i18n.Malay, errs[0]))
+
+		err := passport.ErrPasswordTooShort.WithParams(map[string]interface{}{"min": 8})
+		assert.Equal("password must be at least 8 characters", catalog.Error(i18n.English, err))
+		assert.Equal("kata laluan mestilah sekurang-kurangnya 8 aksara", catalog.Error(i18n.Malay, err))
+		assert.Equal("密码至少需要 8 个字符", catalog.Error(i18n.Chinese, err))
+	})
+
+	t.Run("when translating mails", func(t *testing.T) {
+		assert.Equal(t, "Your reset password token: abc", catalog.Translate(i18n.English, i18n.MailResetPasswordBody, map[string]interface{}{"token": "abc"}))
+	})
+
+	t.Run("when overriding and adding languages", func(t *testing.T) {
+		assert := assert.New(t)
+		catalog.Add(i18n.English, map[string]string{"email_required": "please enter your email"})
+		catalog.Add("id", map[string]string{"email_required": "email wajib diisi"})
+
+		assert.Equal("please enter your email", catalog.Error(i18n.English, passport.ErrEmailRequired))
+		assert.Equal("id", catalog.Negotiate("id-ID"))
+		assert.Equal("email wajib diisi", catalog.Error("id", passport.ErrEmailRequired))
+		assert.Equal("email invalid", catalog.Error("id", passport.ErrEmailInvalid), "falls back to English")
+	})
+
+	t.Run("when the error code is unknown", func(t *testing.T) {
+		e := passport.NewError("custom", "custom error", passport.CategoryValidation)
+		assert.Equal(t, "custom error", catalog.Error(i18n.Malay, e))
+	})
+}
@@ -0,0 +1,199 @@
+package i18n
+
+var english = map[string]string{
+	"account_locked":              "account locked, reset your password to unlock",
+	"algorithm_unsupported":       "algorithm unsupported",
+	"audience_invalid":            "audience invalid",
+	"audit_chain_broken":          "audit chain broken",
+	"bad_request":                 "bad request",
+	"body_required":               "body required",
+	"concurrent_modification":     "concurrent modification",
+	"confirmation_required":       "confirmation required",
+	"confirmed":                   "already confirmed",
+	"denylist_required":           "internal error",
+	"email_change_not_found":      "email change not found",
+	"email_exists":                "email exists",
+	"email_invalid":               "email invalid",
+	"email_or_password_invalid":   "email or password is invalid",
+	"email_required":              "email required",
+	"email_verified":              "email verified",
+	"forbidden":                   "forbidden",
+	"header_invalid":              "header invalid",
+	"identifier_unsupported":      "identifier unsupported",
+	"internal":                    "internal error",
+	"invalid_credential":          "credential is invalid",
+	"issuer_invalid":              "issuer invalid",
+	"key_not_found":               "key not found",
+	"key_retired":                 "key retired",
+	"key_set_unavailable":         "key set unavailable",
+	"key_unsupported":             "key unsupported",
+	"password_change_not_allowed": "password change not allowed",
+	"password_do_not_match":       "password do not match",
+	"password_invalid":            "password invalid",
+	"password_required":           "password required",
+	"password_too_short":          "password must be at least {min} characters",
+	"password_used":               "password cannot be reused",
+	"phone_exists":                "phone exists",
+	"phone_invalid":               "phone invalid, use the international format e.g. +60123456789",
+	"phone_required":              "phone required",
+	"recipient_required":          "recipient required",
+	"starttls_unsupported":        "internal error",
+	"template_not_found":          "internal error",
+	"token_expired":               "token expired",
+	"token_invalid":               "token invalid",
+	"token_required":              "token required",
+	"too_many_requests":           "too many requests, try again later",
+	"tx_runner_required":          "internal error",
+	"user_id_required":            "user_id required",
+	"user_not_found":              "user not found",
+	"username_exists":             "username exists",
+	"username_invalid":            "username invalid",
+	"username_required":           "username required",
+	"validation_failed":           "validation failed",
+
+	MailConfirmationSubject:  "Confirm your Email",
+	MailConfirmationBody:     "Your confirm email token: {token}",
+	MailConfirmationAction:   "Confirm Email",
+	MailChangeEmailSubject:   "Change your Email",
+	MailChangeEmailBody:      "Your confirm email token: {token}",
+	MailChangeEmailAction:    "Confirm Email",
+	MailEmailChangedSubject:  "Your Email was Changed",
+	MailEmailChangedBody:     "The email of your account was changed. If this wasn't you, revert the change with this token: {token}",
+	MailEmailChangedAction:   "This Wasn't Me",
+	MailResetPasswordSubject: "Reset your Password",
+	MailResetPasswordBody:    "Your reset password token: {token}",
+	MailResetPasswordAction:  "Reset Password",
+	MailIgnore:               "If you did not request this, you can ignore this email.",
+}
+
+var malay = map[string]string{
+	"account_locked":              "akaun dikunci, tetapkan semula kata laluan anda untuk membuka kunci",
+	"algorithm_unsupported":       "algoritma tidak disokong",
+	"audience_invalid":            "audiens tidak sah",
+	"audit_chain_broken":          "rantaian audit rosak",
+	"bad_request":                 "permintaan tidak sah",
+	"body_required":               "kandungan diperlukan",
+	"concurrent_modification":     "akaun telah dikemas kini oleh permintaan lain",
+	"confirmation_required":       "pengesahan diperlukan",
+	"confirmed":                   "telah disahkan",
+	"denylist_required":           "ralat dalaman",
+	"email_change_not_found":      "tiada pertukaran emel",
+	"email_exists":                "emel telah wujud",
+	"email_invalid":               "emel tidak sah",
+	"email_or_password_invalid":   "emel atau kata laluan tidak sah",
+	"email_required":              "emel diperlukan",
+	"email_verified":              "emel telah disahkan",
+	"forbidden":                   "dilarang",
+	"header_invalid":              "pengepala tidak sah",
+	"identifier_unsupported":      "pengecam tidak disokong",
+	"internal":                    "ralat dalaman",
+	"invalid_credential":          "kelayakan tidak sah",
+	"issuer_invalid":              "penerbit tidak sah",
+	"key_not_found":               "kunci tidak dijumpai",
+	"key_retired":                 "kunci telah bersara",
+	"key_set_unavailable":         "set kunci tidak tersedia",
+	"key_unsupported":             "kunci tidak disokong",
+	"password_change_not_allowed": "pertukaran kata laluan tidak dibenarkan",
+	"password_do_not_match":       "kata laluan tidak sepadan",
+	"password_invalid":            "kata laluan tidak sah",
+	"password_required":           "kata laluan diperlukan",
+	"password_too_short":          "kata laluan mestilah sekurang-kurangnya {min} aksara",
+	"password_used":               "kata laluan tidak boleh digunakan semula",
+	"phone_exists":                "nombor telefon telah wujud",
+	"phone_invalid":               "nombor telefon tidak sah, gunakan format antarabangsa cth. +60123456789",
+	"phone_required":              "nombor telefon diperlukan",
+	"recipient_required":          "penerima diperlukan",
+	"starttls_unsupported":        "ralat dalaman",
+	"template_not_found":          "ralat dalaman",
+	"token_expired":               "token telah tamat tempoh",
+	"token_invalid":               "token tidak sah",
+	"token_required":              "token diperlukan",
+	"too_many_requests":           "terlalu banyak permintaan, cuba lagi kemudian",
+	"tx_runner_required":          "ralat dalaman",
+	"user_id_required":            "id pengguna diperlukan",
+	"user_not_found":              "pengguna tidak dijumpai",
+	"username_exists":             "nama pengguna telah wujud",
+	"username_invalid":            "nama pengguna tidak sah",
+	"username_required":           "nama pengguna diperlukan",
+	"validation_failed":           "pengesahan gagal",
+
+	MailConfirmationSubject:  "Sahkan Emel Anda",
+	MailConfirmationBody:     "Token pengesahan emel anda: {token}",
+	MailConfirmationAction:   "Sahkan Emel",
+	MailChangeEmailSubject:   "Tukar Emel Anda",
+	MailChangeEmailBody:      "Token pengesahan emel anda: {token}",
+	MailChangeEmailAction:    "Sahkan Emel",
+	MailEmailChangedSubject:  "Emel Anda Telah Ditukar",
+	MailEmailChangedBody:     "Emel akaun anda telah ditukar. Jika ini bukan anda, batalkan perubahan dengan token ini: {token}",
+	MailEmailChangedAction:   "Ini Bukan Saya",
+	MailResetPasswordSubject: "Set Semula Kata Laluan Anda",
+	MailResetPasswordBody:    "Token set semula kata laluan anda: {token}",
+	MailResetPasswordAction:  "Set Semula Kata Laluan",
+	MailIgnore:               "Jika anda tidak membuat permintaan ini, abaikan emel ini.",
+}
+
+var chinese = map[string]string{
+	"account_locked":              "账户已锁定，请重置密码以解锁",
+	"algorithm_unsupported":       "不支持此算法",
+	"audience_invalid":            "受众无效",
+	"audit_chain_broken":          "审计链已损坏",
+	"bad_request":                 "请求无效",
+	"body_required":               "请输入邮件内容",
+	"concurrent_modification":     "账户已被其他请求修改",
+	"confirmation_required":       "需要验证",
+	"confirmed":                   "已验证",
+	"denylist_required":           "内部错误",
+	"email_change_not_found":      "没有待处理的电子邮件更改",
+	"email_exists":                "电子邮件已存在",
+	"email_invalid":               "电子邮件无效",
+	"email_or_password_invalid":   "电子邮件或密码无效",
+	"email_required":              "请输入电子邮件",
+	"email_verified":              "电子邮件已验证",
+	"forbidden":                   "禁止访问",
+	"header_invalid":              "邮件头无效",
+	"identifier_unsupported":      "不支持此登录标识",
+	"internal":                    "内部错误",
+	"invalid_credential":          "凭证无效",
+	"issuer_invalid":              "签发者无效",
+	"key_not_found":               "找不到密钥",
+	"key_retired":                 "密钥已停用",
+	"key_set_unavailable":         "密钥集暂时不可用",
+	"key_unsupported":             "不支持此密钥",
+	"password_change_not_allowed": "不允许更改密码",
+	"password_do_not_match":       "密码不匹配",
+	"password_invalid":            "密码无效",
+	"password_required":           "请输入密码",
+	"password_too_short":          "密码至少需要 {min} 个字符",
+	"password_used":               "不能重复使用密码",
+	"phone_exists":                "电话号码已存在",
+	"phone_invalid":               "电话号码无效，请使用国际格式，例如 +60123456789",
+	"phone_required":              "请输入电话号码",
+	"recipient_required":          "请输入收件人",
+	"starttls_unsupported":        "内部错误",
+	"template_not_found":          "内部错误",
+	"token_expired":               "令牌已过期",
+	"token_invalid":               "令牌无效",
+	"token_required":              "请输入令牌",
+	"too_many_requests":           "请求过于频繁，请稍后再试",
+	"tx_runner_required":          "内部错误",
+	"user_id_required":            "请输入用户 ID",
+	"user_not_found":              "找不到用户",
+	"username_exists":             "用户名已存在",
+	"username_invalid":            "用户名无效",
+	"username_required":           "请输入用户名",
+	"validation_failed":           "验证失败",
+
+	MailConfirmationSubject:  "验证您的电子邮件",
+	MailConfirmationBody:     "您的电子邮件验证令牌：{token}",
+	MailConfirmationAction:   "验证电子邮件",
+	MailChangeEmailSubject:   "更改您的电子邮件",
+	MailChangeEmailBody:      "您的电子邮件验证令牌：{token}",
+	MailChangeEmailAction:    "验证电子邮件",
+	MailEmailChangedSubject:  "您的电子邮件已更改",
+	MailEmailChangedBody:     "您账户的电子邮件已更改。如果这不是您本人的操作，请使用此令牌撤销更改：{token}",
+	MailEmailChangedAction:   "这不是我",
+	MailResetPasswordSubject: "重置您的密码",
+	MailResetPasswordBody:    "您的密码重置令牌：{token}",
+	MailResetPasswordAction:  "重置密码",
+	MailIgnore:               "如果这不是您本人的操作，请忽略此邮件。",
+}
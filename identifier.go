@@ -0,0 +1,57 @@
+package passport
+
+// The kinds of identifiers the users can log in with.
+const (
+	IdentifierEmail    = "email"
+	IdentifierUsername = "username"
+	IdentifierPhone    = "phone"
+)
+
+// ErrIdentifierUnsupported indicates the identifier is not one of the
+// configured identifiers.
+var ErrIdentifierUnsupported = NewError("identifier_unsupported", "identifier unsupported", CategoryValidation)
+
+// Identifier identifies the user on login. Besides the email, the users can
+// log in with their username or phone number.
+type Identifier struct {
+	Kind  string
+	Value string
+}
+
+// Validate validates the value according to the kind of the identifier.
+func (i Identifier) Validate() error {
+	switch i.Kind {
+	case IdentifierEmail:
+		return Email(i.Value).Validate()
+	case IdentifierUsername:
+		return Username(i.Value).Validate()
+	case IdentifierPhone:
+		return Phone(i.Value).Validate()
+	default:
+		return ErrIdentifierUnsupported
+	}
+}
+
+// In returns true if the kind of the identifier is one of kinds.
+func (i Identifier) In(kinds ...string) bool {
+	for _, kind := range kinds {
+		if i.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// NewIdentifier returns the identifier with the value normalized according
+// to the kind.
+func NewIdentifier(kind, value string) Identifier {
+	switch kind {
+	case IdentifierEmail:
+		value = NewEmail(value).Value()
+	case IdentifierUsername:
+		value = NewUsername(value).Value()
+	case IdentifierPhone:
+		value = NewPhone(value).Value()
+	}
+	return Identifier{Kind: kind, Value: value}
+}
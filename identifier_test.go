@@ -0,0 +1,68 @@
+package passport_test
+
+import (
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsername(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(passport.Username("john.doe"), passport.NewUsername("  John.Doe "))
+	assert.Equal(passport.ErrUsernameRequired, passport.NewUsername(" ").Validate())
+	assert.Equal(passport.ErrUsernameInvalid, passport.NewUsername("jo").Validate())
+	assert.Equal(passport.ErrUsernameInvalid, passport.NewUsername("john@doe").Validate())
+	assert.Equal(passport.ErrUsernameInvalid, passport.NewUsername("_john").Validate())
+	assert.Nil(passport.NewUsername("john_doe-1").Validate())
+}
+
+func TestPhone(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(passport.Phone("+60123456789"), passport.NewPhone(" +60 (12) 345-6789 "))
+	assert.Equal(passport.ErrPhoneRequired, passport.NewPhone("").Validate())
+	assert.Equal(passport.ErrPhoneInvalid, passport.NewPhone("0123456789").Validate())
+	assert.Equal(passport.ErrPhoneInvalid, passport.NewPhone("+0123456789").Validate())
+	assert.Equal(passport.ErrPhoneInvalid, passport.NewPhone("+6012345678901234").Validate())
+	assert.Nil(passport.NewPhone("+60123456789").Validate())
+}
+
+func TestIdentifier(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("normalizes by kind", func(t *testing.T) {
+		assert.Equal("john", passport.NewIdentifier(passport.IdentifierUsername, " John ").Value)
+		assert.Equal("+60123456789", passport.NewIdentifier(passport.IdentifierPhone, "+60 12-345 6789").Value)
+		assert.Equal("John@mail.com", passport.NewIdentifier(passport.IdentifierEmail, " John@mail.com ").Value)
+	})
+
+	t.Run("validates by kind", func(t *testing.T) {
+		assert.Equal(passport.ErrEmailInvalid, passport.NewIdentifier(passport.IdentifierEmail, "john").Validate())
+		assert.Equal(passport.ErrUsernameInvalid, passport.NewIdentifier(passport.IdentifierUsername, "j").Validate())
+		assert.Equal(passport.ErrPhoneInvalid, passport.NewIdentifier(passport.IdentifierPhone, "123").Validate())
+		assert.Equal(passport.ErrIdentifierUnsupported, passport.NewIdentifier("nickname", "john").Validate())
+	})
+
+	t.Run("checks the kinds", func(t *testing.T) {
+		id := passport.NewIdentifier(passport.IdentifierPhone, "+60123456789")
+		assert.True(id.In(passport.IdentifierEmail, passport.IdentifierPhone))
+		assert.False(id.In(passport.IdentifierEmail))
+	})
+}
+
+func TestCredentialIdentifier(t *testing.T) {
+	assert := assert.New(t)
+
+	cred := passport.NewCredential("john.doe@mail.com", "12345678")
+	assert.Equal(passport.Identifier{Kind: passport.IdentifierEmail, Value: "john.doe@mail.com"}, cred.Identifier())
+
+	cred = passport.NewIdentifierCredential(passport.Identifier{Kind: passport.IdentifierUsername, Value: "John"}, "12345678")
+	assert.Equal(passport.Identifier{Kind: passport.IdentifierUsername, Value: "john"}, cred.Identifier())
+
+	cred = passport.NewIdentifierCredential(passport.Identifier{Kind: passport.IdentifierPhone, Value: "+60 123456789"}, "12345678")
+	assert.Equal(passport.Identifier{Kind: passport.IdentifierPhone, Value: "+60123456789"}, cred.Identifier())
+
+	cred.Email = passport.NewEmail("john.doe@mail.com")
+	cred.Phone = passport.NewPhone("123")
+	assert.Equal(passport.ErrPhoneInvalid, cred.Validate())
+}
@@ -0,0 +1,97 @@
+package issuer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+var ErrAlgorithmUnsupported = passport.NewError("algorithm_unsupported", "algorithm unsupported", passport.CategoryValidation)
+
+// algorithm returns the algorithm of the key, which defaults to HS256.
+func algorithm(key passport.Key) string {
+	if key.Algorithm == "" {
+		return HS256
+	}
+	return key.Algorithm
+}
+
+func sign(key passport.Key, payload []byte) ([]byte, error) {
+	switch algorithm(key) {
+	case HS256:
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write(payload)
+		return mac.Sum(nil), nil
+	case RS256:
+		priv, ok := key.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrAlgorithmUnsupported
+		}
+		digest := sha256.Sum256(payload)
+		return rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+	case EdDSA:
+		priv, ok := key.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, ErrAlgorithmUnsupported
+		}
+		return ed25519.Sign(priv, payload), nil
+	default:
+		return nil, ErrAlgorithmUnsupported
+	}
+}
+
+// verify checks the signature with the secret for HS256, or the public key
+// for the asymmetric algorithms.
+func verify(alg string, secret []byte, pub crypto.PublicKey, payload, signature []byte) bool {
+	switch alg {
+	case HS256:
+		if len(secret) == 0 {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(payload)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case RS256:
+		pub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(payload)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case EdDSA:
+		pub, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(pub, payload, signature)
+	default:
+		return false
+	}
+}
+
+// NewKey returns a key that signs with the private key, using RS256 for RSA
+// and EdDSA for Ed25519 keys.
+func NewKey(id string, priv crypto.Signer) (passport.Key, error) {
+	key := passport.Key{ID: id, PrivateKey: priv}
+	switch priv.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = RS256
+	case ed25519.PrivateKey:
+		key.Algorithm = EdDSA
+	default:
+		return passport.Key{}, ErrKeyUnsupported
+	}
+	return key, nil
+}
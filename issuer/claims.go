@@ -0,0 +1,67 @@
+package issuer
+
+import (
+	"encoding/json"
+)
+
+// Types of the issued tokens. Only access tokens are accepted by Verify,
+// and only refresh tokens by Refresh.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Claims are the claims of the issued JWT.
+type Claims struct {
+	Subject   string   `json:"sub,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+	Type      string   `json:"typ,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	MFALevel  int      `json:"mfa,omitempty"`
+
+	// IssuedAtMicros is the time the token is issued in microseconds,
+	// since iat is in seconds, and cannot tell apart the tokens issued
+	// within the same second as the subject is revoked.
+	IssuedAtMicros int64 `json:"iat_us,omitempty"`
+
+	// Extra holds the custom claims of the application. They are kept
+	// when the tokens are refreshed.
+	Extra map[string]interface{} `json:"ext,omitempty"`
+}
+
+// Audience is the aud claim, which may be encoded either as a single string
+// or an array of strings.
+type Audience []string
+
+// Contains checks if any of the given audiences is in the claim.
+func (a Audience) Contains(audiences ...string) bool {
+	for _, x := range a {
+		for _, y := range audiences {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = Audience(ss)
+	return nil
+}
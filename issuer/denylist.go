@@ -0,0 +1,146 @@
+package issuer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// Denylist holds the ids of the revoked tokens and sessions until they
+// expire. connector.Denylist is the Postgres backend.
+type Denylist interface {
+	Deny(ctx context.Context, id string, expiresAt time.Time) error
+	Denied(ctx context.Context, ids ...string) (bool, error)
+
+	// DenySubject denies the tokens of the subject issued before
+	// issuedBefore, until expiresAt.
+	DenySubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error
+
+	// SubjectDenied checks if the token of the subject issued at issuedAt
+	// is denied.
+	SubjectDenied(ctx context.Context, subject string, issuedAt time.Time) (bool, error)
+}
+
+// MemoryDenylist is a Denylist for a single process.
+type MemoryDenylist struct {
+	mu       sync.Mutex
+	ids      map[string]time.Time
+	subjects map[string]deniedSubject
+	clock    passport.Clock
+}
+
+type deniedSubject struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// Deny denies the id until it expires. The expired ids are removed.
+func (m *MemoryDenylist) Deny(ctx context.Context, id string, expiresAt time.Time) error {
+	if id == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	for id, exp := range m.ids {
+		if !now.Before(exp) {
+			delete(m.ids, id)
+		}
+	}
+	if exp, ok := m.ids[id]; !ok || expiresAt.After(exp) {
+		m.ids[id] = expiresAt
+	}
+	return nil
+}
+
+// Denied checks if any of the ids is denied.
+func (m *MemoryDenylist) Denied(ctx context.Context, ids ...string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	for _, id := range ids {
+		if exp, ok := m.ids[id]; ok && now.Before(exp) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DenySubject denies the tokens of the subject issued before issuedBefore.
+// Denying the subject again moves both times forward.
+func (m *MemoryDenylist) DenySubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	if subject == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	for subject, s := range m.subjects {
+		if !now.Before(s.expiresAt) {
+			delete(m.subjects, subject)
+		}
+	}
+	s := m.subjects[subject]
+	if issuedBefore.After(s.issuedBefore) {
+		s.issuedBefore = issuedBefore
+	}
+	if expiresAt.After(s.expiresAt) {
+		s.expiresAt = expiresAt
+	}
+	m.subjects[subject] = s
+	return nil
+}
+
+// SubjectDenied checks if the token of the subject was issued before the
+// subject was denied.
+func (m *MemoryDenylist) SubjectDenied(ctx context.Context, subject string, issuedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.subjects[subject]
+	if !ok || !m.clock.Now().Before(s.expiresAt) {
+		return false, nil
+	}
+	return issuedAt.Before(s.issuedBefore), nil
+}
+
+// denied checks if the token, its session or its subject is revoked.
+func denied(ctx context.Context, denylist Denylist, claims *Claims) (bool, error) {
+	denied, err := denylist.Denied(ctx, claims.ID, claims.SessionID)
+	if err != nil || denied {
+		return denied, err
+	}
+	if claims.Subject == "" {
+		return false, nil
+	}
+	return denylist.SubjectDenied(ctx, claims.Subject, issuedAt(claims))
+}
+
+// issuedAt returns the time the token is issued, in microseconds when the
+// token has the iat_us claim, and in seconds otherwise.
+func issuedAt(claims *Claims) time.Time {
+	if claims.IssuedAtMicros != 0 {
+		return time.Unix(0, claims.IssuedAtMicros*int64(time.Microsecond))
+	}
+	return time.Unix(claims.IssuedAt, 0)
+}
+
+// NewMemoryDenylist returns a new MemoryDenylist. The clock defaults to
+// SystemClock when nil.
+func NewMemoryDenylist(clock passport.Clock) *MemoryDenylist {
+	if clock == nil {
+		clock = passport.SystemClock{}
+	}
+	return &MemoryDenylist{
+		ids:      make(map[string]time.Time),
+		subjects: make(map[string]deniedSubject),
+		clock:    clock,
+	}
+}
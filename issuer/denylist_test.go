@@ -0,0 +1,153 @@
+package issuer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/issuer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDenylist(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(now)
+	denylist := issuer.NewMemoryDenylist(clock)
+
+	assert.Nil(denylist.Deny(context.TODO(), "1", now.Add(time.Minute)))
+	assert.Nil(denylist.Deny(context.TODO(), "", now.Add(time.Minute)))
+
+	denied, err := denylist.Denied(context.TODO(), "2", "1")
+	assert.Nil(err)
+	assert.True(denied)
+
+	denied, err = denylist.Denied(context.TODO(), "")
+	assert.Nil(err)
+	assert.False(denied)
+
+	clock.Advance(time.Minute)
+	denied, err = denylist.Denied(context.TODO(), "1")
+	assert.Nil(err)
+	assert.False(denied, "expired with the token")
+}
+
+func TestMemoryDenylistSubject(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(now)
+	denylist := issuer.NewMemoryDenylist(clock)
+
+	assert.Nil(denylist.DenySubject(context.TODO(), "1", now, now.Add(time.Hour)))
+
+	denied, err := denylist.SubjectDenied(context.TODO(), "1", now.Add(-time.Second))
+	assert.Nil(err)
+	assert.True(denied)
+
+	denied, err = denylist.SubjectDenied(context.TODO(), "1", now)
+	assert.Nil(err)
+	assert.False(denied, "issued after the revocation")
+
+	denied, err = denylist.SubjectDenied(context.TODO(), "2", now.Add(-time.Second))
+	assert.Nil(err)
+	assert.False(denied)
+
+	clock.Advance(time.Hour)
+	denied, err = denylist.SubjectDenied(context.TODO(), "1", now.Add(-time.Second))
+	assert.Nil(err)
+	assert.False(denied, "expired with the tokens")
+}
+
+func TestIssuerRevoke(t *testing.T) {
+	clock := passport.NewFakeClock(now)
+	iss := newIssuer(newKeys(t)[issuer.HS256], clock)
+
+	t.Run("when token is revoked", func(t *testing.T) {
+		assert := assert.New(t)
+		pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+		claims, err := iss.Verify(pair.AccessToken)
+		assert.Nil(err)
+
+		assert.Nil(iss.Revoke(context.TODO(), claims))
+		denied, err := iss.Denied(context.TODO(), claims)
+		assert.Nil(err)
+		assert.True(denied)
+
+		// Only the access token is revoked.
+		_, err = iss.Refresh(context.TODO(), pair.RefreshToken)
+		assert.Nil(err)
+	})
+
+	t.Run("when session is revoked", func(t *testing.T) {
+		assert := assert.New(t)
+		pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+		claims, err := iss.Verify(pair.AccessToken)
+		assert.Nil(err)
+
+		assert.Nil(iss.RevokeSession(context.TODO(), claims))
+		_, err = iss.Refresh(context.TODO(), pair.RefreshToken)
+		assert.Equal(passport.ErrTokenInvalid, err)
+
+		// Other sessions are not affected.
+		other, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+		otherClaims, err := iss.Verify(other.AccessToken)
+		assert.Nil(err)
+		denied, err := iss.Denied(context.TODO(), otherClaims)
+		assert.Nil(err)
+		assert.False(denied)
+	})
+
+	t.Run("when subject is revoked", func(t *testing.T) {
+		assert := assert.New(t)
+		first, err := iss.IssuePair(issuer.Claims{Subject: "2"})
+		assert.Nil(err)
+		second, err := iss.IssuePair(issuer.Claims{Subject: "2"})
+		assert.Nil(err)
+		other, err := iss.IssuePair(issuer.Claims{Subject: "3"})
+		assert.Nil(err)
+
+		assert.Nil(iss.RevokeSubject(context.TODO(), "2"))
+
+		// Every session of the subject is revoked.
+		for _, pair := range []*issuer.TokenPair{first, second} {
+			claims, err := iss.Verify(pair.AccessToken)
+			assert.Nil(err)
+			denied, err := iss.Denied(context.TODO(), claims)
+			assert.Nil(err)
+			assert.True(denied)
+
+			_, err = iss.Refresh(context.TODO(), pair.RefreshToken)
+			assert.Equal(passport.ErrTokenInvalid, err)
+		}
+
+		// Other subjects are not.
+		otherClaims, err := iss.Verify(other.AccessToken)
+		assert.Nil(err)
+		denied, err := iss.Denied(context.TODO(), otherClaims)
+		assert.Nil(err)
+		assert.False(denied)
+	})
+
+	t.Run("when a pair is issued right after the subject is revoked", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Nil(iss.RevokeSubject(context.TODO(), "4"))
+
+		// E.g. the login right after a password reset, within the same
+		// second.
+		clock.Advance(time.Millisecond)
+		next, err := iss.IssuePair(issuer.Claims{Subject: "4"})
+		assert.Nil(err)
+		claims, err := iss.Verify(next.AccessToken)
+		assert.Nil(err)
+		assert.Equal(now.Unix(), claims.IssuedAt)
+		denied, err := iss.Denied(context.TODO(), claims)
+		assert.Nil(err)
+		assert.False(denied)
+
+		_, err = iss.Refresh(context.TODO(), next.RefreshToken)
+		assert.Nil(err)
+	})
+}
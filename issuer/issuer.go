@@ -0,0 +1,281 @@
+package issuer
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+	DefaultLeeway          = 30 * time.Second
+)
+
+var (
+	ErrIssuerInvalid   = passport.NewError("issuer_invalid", "issuer invalid", passport.CategoryUnauthorized)
+	ErrAudienceInvalid = passport.NewError("audience_invalid", "audience invalid", passport.CategoryUnauthorized)
+)
+
+type (
+	idGenerator interface {
+		Generate() (string, error)
+	}
+
+	Options struct {
+		// Keyring holds the signing keys. The active key signs the
+		// tokens, and the algorithm is taken from the key.
+		Keyring *passport.Keyring
+
+		// Issuer and Audience are set as the iss and aud claims, and
+		// checked during verification when not empty.
+		Issuer   string
+		Audience []string
+
+		AccessTokenTTL  time.Duration
+		RefreshTokenTTL time.Duration
+
+		// Leeway is the clock skew tolerated when checking the time
+		// based claims.
+		Leeway time.Duration
+
+		// Denylist holds the revoked tokens and sessions. Revoked
+		// refresh tokens are rejected by Refresh, while the access
+		// tokens are checked by the middleware.
+		Denylist Denylist
+
+		// ValidateSubject is called by Refresh with the subject of the
+		// refresh token, so that the users that are locked or deleted
+		// since the token was issued cannot refresh it. Optional.
+		ValidateSubject func(ctx context.Context, subject string) error
+
+		IDGenerator idGenerator
+		Clock       passport.Clock
+	}
+
+	// Issuer issues and verifies the access and refresh tokens of the
+	// users.
+	Issuer struct {
+		options Options
+	}
+
+	// TokenPair is returned after login, register and refresh.
+	TokenPair struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+)
+
+// Issue returns a signed access token for the given claims. The registered
+// claims that are not set are filled in.
+func (i *Issuer) Issue(claims Claims) (string, error) {
+	claims.Type = TokenTypeAccess
+	return i.issue(claims, i.options.AccessTokenTTL)
+}
+
+// IssuePair returns an access and refresh token for the given claims. A new
+// session id is generated when the claims do not have one, so that all the
+// tokens refreshed from the pair share the same session.
+func (i *Issuer) IssuePair(claims Claims) (*TokenPair, error) {
+	if claims.SessionID == "" {
+		sid, err := i.options.IDGenerator.Generate()
+		if err != nil {
+			return nil, err
+		}
+		claims.SessionID = sid
+	}
+
+	accessToken, err := i.Issue(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	claims.Type = TokenTypeRefresh
+	refreshToken, err := i.issue(claims, i.options.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(i.options.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// Verify returns the claims of the access token.
+func (i *Issuer) Verify(token string) (*Claims, error) {
+	return i.verify(token, TokenTypeAccess)
+}
+
+// Refresh exchanges a refresh token for a new token pair of the same
+// session. The refresh token can only be exchanged once. When it is
+// presented again, it has likely been stolen, and the whole session is
+// revoked, including the pair issued for it.
+func (i *Issuer) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := i.verify(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	reused, err := i.options.Denylist.Denied(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		if err := i.RevokeSession(ctx, claims); err != nil {
+			return nil, err
+		}
+		return nil, passport.ErrTokenInvalid
+	}
+
+	denied, err := i.Denied(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, passport.ErrTokenInvalid
+	}
+
+	if i.options.ValidateSubject != nil {
+		if err := i.options.ValidateSubject(ctx, claims.Subject); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rotate the refresh token. Concurrent requests with the same token
+	// may still both pass the check above.
+	if err := i.Revoke(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return i.IssuePair(Claims{
+		Subject:   claims.Subject,
+		SessionID: claims.SessionID,
+		Roles:     claims.Roles,
+		MFALevel:  claims.MFALevel,
+		Extra:     claims.Extra,
+	})
+}
+
+// Revoke denies the token until it expires, e.g. when the token is
+// compromised.
+func (i *Issuer) Revoke(ctx context.Context, claims *Claims) error {
+	return i.options.Denylist.Deny(ctx, claims.ID, time.Unix(claims.ExpiresAt, 0))
+}
+
+// RevokeSession denies all the access and refresh tokens of the session,
+// e.g. on logout or password change. The session is denied until the
+// latest refresh token issued for it expires.
+func (i *Issuer) RevokeSession(ctx context.Context, claims *Claims) error {
+	if claims.SessionID == "" {
+		return i.Revoke(ctx, claims)
+	}
+	expiresAt := i.options.Clock.Now().Add(i.options.RefreshTokenTTL)
+	return i.options.Denylist.Deny(ctx, claims.SessionID, expiresAt)
+}
+
+// RevokeSubject denies all the access and refresh tokens issued to the
+// subject so far, in every session, e.g. on password change or when the
+// account is locked. The tokens are compared by the time they are issued in
+// microseconds, so only the tokens issued within the same microsecond as
+// the revocation are denied too.
+func (i *Issuer) RevokeSubject(ctx context.Context, subject string) error {
+	now := i.options.Clock.Now()
+	issuedBefore := now.Truncate(time.Microsecond).Add(time.Microsecond)
+	return i.options.Denylist.DenySubject(ctx, subject, issuedBefore, now.Add(i.options.RefreshTokenTTL))
+}
+
+// Denied checks if the token, its session or its subject is revoked.
+func (i *Issuer) Denied(ctx context.Context, claims *Claims) (bool, error) {
+	return denied(ctx, i.options.Denylist, claims)
+}
+
+func (i *Issuer) validator() validator {
+	return validator{
+		issuer:   i.options.Issuer,
+		audience: i.options.Audience,
+		leeway:   i.options.Leeway,
+		clock:    i.options.Clock,
+	}
+}
+
+func (i *Issuer) issue(claims Claims, ttl time.Duration) (string, error) {
+	key, err := i.options.Keyring.Active()
+	if err != nil {
+		return "", err
+	}
+
+	now := i.options.Clock.Now()
+	if claims.Issuer == "" {
+		claims.Issuer = i.options.Issuer
+	}
+	if len(claims.Audience) == 0 {
+		claims.Audience = i.options.Audience
+	}
+	claims.IssuedAt = now.Unix()
+	claims.IssuedAtMicros = now.UnixNano() / int64(time.Microsecond)
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	id, err := i.options.IDGenerator.Generate()
+	if err != nil {
+		return "", err
+	}
+	claims.ID = id
+
+	return encode(key, claims)
+}
+
+func (i *Issuer) verify(raw, typ string) (*Claims, error) {
+	t, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens signed with unknown or retired keys are simply invalid.
+	key, err := i.options.Keyring.Key(t.header.KeyID)
+	if err != nil {
+		return nil, passport.ErrTokenInvalid
+	}
+
+	// The algorithm is taken from the key and not the header, so that a
+	// token cannot downgrade the algorithm.
+	alg := algorithm(key)
+	if t.header.Algorithm != alg {
+		return nil, passport.ErrTokenInvalid
+	}
+	if !verify(alg, key.Secret, key.PublicKey(), t.payload, t.signature) {
+		return nil, passport.ErrTokenInvalid
+	}
+
+	if err := i.validator().validate(t.claims, typ); err != nil {
+		return nil, err
+	}
+	return &t.claims, nil
+}
+
+// New returns a new Issuer. The clock defaults to the clock of the
+// keyring.
+func New(options Options) *Issuer {
+	if options.AccessTokenTTL == 0 {
+		options.AccessTokenTTL = DefaultAccessTokenTTL
+	}
+	if options.RefreshTokenTTL == 0 {
+		options.RefreshTokenTTL = DefaultRefreshTokenTTL
+	}
+	if options.Leeway == 0 {
+		options.Leeway = DefaultLeeway
+	}
+	if options.IDGenerator == nil {
+		options.IDGenerator = passport.NewTokenGenerator()
+	}
+	if options.Clock == nil {
+		options.Clock = options.Keyring.Clock()
+	}
+	if options.Denylist == nil {
+		options.Denylist = NewMemoryDenylist(options.Clock)
+	}
+	return &Issuer{options}
+}
@@ -0,0 +1,205 @@
+package issuer_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/issuer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newKeys(t *testing.T) map[string]passport.Key {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]passport.Key{
+		issuer.HS256: {ID: "hs", Secret: []byte("secret")},
+		issuer.RS256: {ID: "rs", Algorithm: issuer.RS256, PrivateKey: rsaKey},
+		issuer.EdDSA: {ID: "ed", Algorithm: issuer.EdDSA, PrivateKey: edKey},
+	}
+}
+
+func newIssuer(key passport.Key, clock passport.Clock) *issuer.Issuer {
+	return issuer.New(issuer.Options{
+		Keyring:  passport.NewKeyring(clock, key),
+		Issuer:   "passport",
+		Audience: []string{"api"},
+	})
+}
+
+func TestIssuerAlgorithms(t *testing.T) {
+	for alg, key := range newKeys(t) {
+		alg, key := alg, key
+		t.Run(alg, func(t *testing.T) {
+			assert := assert.New(t)
+			iss := newIssuer(key, passport.NewFakeClock(now))
+			token, err := iss.Issue(issuer.Claims{
+				Subject:  "1",
+				Roles:    []string{"admin"},
+				MFALevel: 2,
+			})
+			assert.Nil(err)
+
+			claims, err := iss.Verify(token)
+			assert.Nil(err)
+			assert.Equal("1", claims.Subject)
+			assert.Equal("passport", claims.Issuer)
+			assert.Equal(issuer.Audience{"api"}, claims.Audience)
+			assert.Equal(now.Unix(), claims.IssuedAt)
+			assert.Equal(now.Add(issuer.DefaultAccessTokenTTL).Unix(), claims.ExpiresAt)
+			assert.NotEmpty(claims.ID)
+			assert.Equal([]string{"admin"}, claims.Roles)
+			assert.Equal(2, claims.MFALevel)
+		})
+	}
+}
+
+func TestIssuerVerify(t *testing.T) {
+	keys := newKeys(t)
+	clock := passport.NewFakeClock(now)
+	iss := newIssuer(keys[issuer.HS256], clock)
+	token, err := iss.Issue(issuer.Claims{Subject: "1"})
+	assert.Nil(t, err)
+
+	t.Run("when expired within leeway", func(t *testing.T) {
+		clock.Set(now.Add(issuer.DefaultAccessTokenTTL + issuer.DefaultLeeway - time.Second))
+		defer clock.Set(now)
+		_, err := iss.Verify(token)
+		assert.Nil(t, err)
+	})
+
+	t.Run("when expired", func(t *testing.T) {
+		clock.Set(now.Add(issuer.DefaultAccessTokenTTL + issuer.DefaultLeeway))
+		defer clock.Set(now)
+		_, err := iss.Verify(token)
+		assert.Equal(t, passport.ErrTokenExpired, err)
+	})
+
+	t.Run("when audience is different", func(t *testing.T) {
+		other := issuer.New(issuer.Options{
+			Keyring:  passport.NewKeyring(clock, keys[issuer.HS256]),
+			Issuer:   "passport",
+			Audience: []string{"admin"},
+		})
+		_, err := other.Verify(token)
+		assert.Equal(t, issuer.ErrAudienceInvalid, err)
+	})
+
+	t.Run("when issuer is different", func(t *testing.T) {
+		other := issuer.New(issuer.Options{
+			Keyring: passport.NewKeyring(clock, keys[issuer.HS256]),
+			Issuer:  "other",
+		})
+		_, err := other.Verify(token)
+		assert.Equal(t, issuer.ErrIssuerInvalid, err)
+	})
+
+	t.Run("when algorithm is different", func(t *testing.T) {
+		// The HS256 token is presented with the kid of an RS256 key.
+		rs := keys[issuer.RS256]
+		rs.ID = "hs"
+		other := newIssuer(rs, clock)
+		_, err := other.Verify(token)
+		assert.Equal(t, passport.ErrTokenInvalid, err)
+	})
+
+	t.Run("when token is malformed", func(t *testing.T) {
+		_, err := iss.Verify("a.b")
+		assert.Equal(t, passport.ErrTokenInvalid, err)
+	})
+}
+
+func TestIssuerRefresh(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(now)
+	iss := newIssuer(newKeys(t)[issuer.EdDSA], clock)
+
+	pair, err := iss.IssuePair(issuer.Claims{
+		Subject: "1",
+		Roles:   []string{"admin"},
+		Extra:   map[string]interface{}{"plan": "pro"},
+	})
+	assert.Nil(err)
+	assert.Equal(int64(issuer.DefaultAccessTokenTTL/time.Second), pair.ExpiresIn)
+
+	access, err := iss.Verify(pair.AccessToken)
+	assert.Nil(err)
+	assert.NotEmpty(access.SessionID)
+
+	_, err = iss.Verify(pair.RefreshToken)
+	assert.Equal(passport.ErrTokenInvalid, err, "refresh token is not an access token")
+	_, err = iss.Refresh(context.TODO(), pair.AccessToken)
+	assert.Equal(passport.ErrTokenInvalid, err, "access token is not a refresh token")
+
+	clock.Advance(time.Hour)
+	refreshed, err := iss.Refresh(context.TODO(), pair.RefreshToken)
+	assert.Nil(err)
+
+	claims, err := iss.Verify(refreshed.AccessToken)
+	assert.Nil(err)
+	assert.Equal("1", claims.Subject)
+	assert.Equal(access.SessionID, claims.SessionID)
+	assert.NotEqual(access.ID, claims.ID)
+	assert.Equal([]string{"admin"}, claims.Roles)
+	assert.Equal(map[string]interface{}{"plan": "pro"}, claims.Extra)
+}
+
+func TestIssuerRefreshReuse(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(now)
+	iss := newIssuer(newKeys(t)[issuer.HS256], clock)
+
+	pair, err := iss.IssuePair(issuer.Claims{Subject: "1"})
+	assert.Nil(err)
+
+	refreshed, err := iss.Refresh(context.TODO(), pair.RefreshToken)
+	assert.Nil(err)
+
+	_, err = iss.Refresh(context.TODO(), pair.RefreshToken)
+	assert.Equal(passport.ErrTokenInvalid, err, "the refresh token is rotated")
+
+	// The reuse revokes the session, including the refreshed pair.
+	_, err = iss.Refresh(context.TODO(), refreshed.RefreshToken)
+	assert.Equal(passport.ErrTokenInvalid, err)
+	claims, err := iss.Verify(refreshed.AccessToken)
+	assert.Nil(err)
+	denied, err := iss.Denied(context.TODO(), claims)
+	assert.Nil(err)
+	assert.True(denied)
+}
+
+func TestIssuerRefreshValidateSubject(t *testing.T) {
+	assert := assert.New(t)
+	iss := issuer.New(issuer.Options{
+		Keyring: passport.NewKeyring(passport.NewFakeClock(now), newKeys(t)[issuer.HS256]),
+		ValidateSubject: func(ctx context.Context, subject string) error {
+			if subject == "locked" {
+				return passport.ErrAccountLocked
+			}
+			return nil
+		},
+	})
+
+	pair, err := iss.IssuePair(issuer.Claims{Subject: "locked"})
+	assert.Nil(err)
+	_, err = iss.Refresh(context.TODO(), pair.RefreshToken)
+	assert.Equal(passport.ErrAccountLocked, err)
+
+	pair, err = iss.IssuePair(issuer.Claims{Subject: "active"})
+	assert.Nil(err)
+	_, err = iss.Refresh(context.TODO(), pair.RefreshToken)
+	assert.Nil(err)
+}
@@ -0,0 +1,123 @@
+package issuer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// JWKSPath is the well-known path the key set is served at.
+const JWKSPath = "/.well-known/jwks.json"
+
+var ErrKeyUnsupported = passport.NewError("key_unsupported", "key unsupported", passport.CategoryValidation)
+
+type (
+	// JWK is the JSON Web Key representation of a public key.
+	JWK struct {
+		KeyType   string `json:"kty"`
+		KeyID     string `json:"kid"`
+		Algorithm string `json:"alg"`
+		Use       string `json:"use"`
+
+		// RSA public key.
+		N string `json:"n,omitempty"`
+		E string `json:"e,omitempty"`
+
+		// Ed25519 public key.
+		Curve string `json:"crv,omitempty"`
+		X     string `json:"x,omitempty"`
+	}
+
+	// JWKS is the JSON Web Key Set.
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+)
+
+// NewJWK returns the public JWK of the key. Symmetric keys cannot be
+// published.
+func NewJWK(key passport.Key) (JWK, error) {
+	jwk := JWK{
+		KeyID:     key.ID,
+		Algorithm: algorithm(key),
+		Use:       "sig",
+	}
+	switch pub := key.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, ErrKeyUnsupported
+	}
+	return jwk, nil
+}
+
+// PublicKey returns the public key of the JWK.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, ErrKeyUnsupported
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, ErrKeyUnsupported
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrKeyUnsupported
+	}
+}
+
+// NewJWKS returns the key set of the asymmetric keys in the keyring that
+// are not retired. The HMAC secrets are never published.
+func NewJWKS(keyring *passport.Keyring) JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range keyring.Keys() {
+		jwk, err := NewJWK(key)
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// JWKSHandler serves the key set of the keyring. The keys are read on every
+// request, so rotated keys are published immediately.
+func JWKSHandler(keyring *passport.Keyring, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(maxAge/time.Second), 10))
+		json.NewEncoder(w).Encode(NewJWKS(keyring))
+	})
+}
@@ -0,0 +1,192 @@
+package issuer_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/issuer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	assert := assert.New(t)
+	keys := newKeys(t)
+	keyring := passport.NewKeyring(nil, keys[issuer.RS256], keys[issuer.EdDSA], keys[issuer.HS256])
+
+	rec := httptest.NewRecorder()
+	issuer.JWKSHandler(keyring, time.Minute).ServeHTTP(rec, httptest.NewRequest("GET", issuer.JWKSPath, nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("public, max-age=60", rec.Header().Get("Cache-Control"))
+
+	var jwks issuer.JWKS
+	assert.Nil(json.NewDecoder(rec.Body).Decode(&jwks))
+	assert.Len(jwks.Keys, 2, "the HMAC secret is not published")
+	assert.Equal("ed", jwks.Keys[0].KeyID)
+	assert.Equal("OKP", jwks.Keys[0].KeyType)
+	assert.Equal("rs", jwks.Keys[1].KeyID)
+	assert.Equal("RSA", jwks.Keys[1].KeyType)
+}
+
+func TestRemoteVerifier(t *testing.T) {
+	var (
+		keys    = newKeys(t)
+		clock   = passport.NewFakeClock(now)
+		keyring = passport.NewKeyring(clock, keys[issuer.RS256])
+		iss     = issuer.New(issuer.Options{
+			Keyring:  keyring,
+			Issuer:   "passport",
+			Audience: []string{"api"},
+		})
+		fetches int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		issuer.JWKSHandler(keyring, time.Minute).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	verifier := issuer.NewRemoteVerifier(issuer.RemoteVerifierOptions{
+		URL:      server.URL + issuer.JWKSPath,
+		Issuer:   "passport",
+		Audience: []string{"api"},
+		Clock:    clock,
+	})
+
+	t.Run("when verifying with the fetched keys", func(t *testing.T) {
+		assert := assert.New(t)
+		token, err := iss.Issue(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+
+		for i := 0; i < 3; i++ {
+			claims, err := verifier.Verify(token)
+			assert.Nil(err)
+			assert.Equal("1", claims.Subject)
+		}
+		assert.Equal(int32(1), atomic.LoadInt32(&fetches), "the key set is cached")
+	})
+
+	t.Run("when the key is rotated", func(t *testing.T) {
+		assert := assert.New(t)
+		keyring.Add(keys[issuer.EdDSA])
+		assert.Nil(keyring.Activate("ed"))
+		token, err := iss.Issue(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+
+		_, err = verifier.Verify(token)
+		assert.Equal(passport.ErrTokenInvalid, err, "refreshes are rate limited")
+
+		clock.Advance(issuer.DefaultMinRefreshInterval)
+		_, err = verifier.Verify(token)
+		assert.Nil(err)
+		assert.Equal(int32(2), atomic.LoadInt32(&fetches))
+	})
+
+	t.Run("when the token is signed with an HMAC secret", func(t *testing.T) {
+		assert := assert.New(t)
+		hs := issuer.New(issuer.Options{
+			Keyring: passport.NewKeyring(clock, keys[issuer.HS256]),
+			Issuer:  "passport",
+		})
+		token, err := hs.Issue(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+
+		_, err = verifier.Verify(token)
+		assert.Equal(passport.ErrTokenInvalid, err)
+	})
+}
+
+func TestRemoteVerifierFetch(t *testing.T) {
+	var (
+		keys    = newKeys(t)
+		clock   = passport.NewFakeClock(now)
+		keyring = passport.NewKeyring(clock, keys[issuer.RS256])
+		iss     = issuer.New(issuer.Options{Keyring: keyring})
+		fetches int32
+		down    int32 = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// Holds the fetch in flight, so that the other callers wait.
+		time.Sleep(50 * time.Millisecond)
+		issuer.JWKSHandler(keyring, time.Minute).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	verifier := issuer.NewRemoteVerifier(issuer.RemoteVerifierOptions{
+		URL:   server.URL + issuer.JWKSPath,
+		Clock: clock,
+	})
+	token, err := iss.Issue(issuer.Claims{Subject: "1"})
+	assert.Nil(t, err)
+
+	t.Run("when the fetch fails", func(t *testing.T) {
+		assert := assert.New(t)
+		for i := 0; i < 3; i++ {
+			_, err := verifier.Verify(token)
+			assert.NotNil(err)
+		}
+		assert.Equal(int32(1), atomic.LoadInt32(&fetches), "the failed fetches are rate limited")
+	})
+
+	t.Run("when verifying concurrently", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&down, 0)
+		clock.Advance(issuer.DefaultMinRefreshInterval)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 10)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = verifier.Verify(token)
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			assert.Nil(err)
+		}
+		assert.Equal(int32(2), atomic.LoadInt32(&fetches), "only one fetch is in flight")
+	})
+
+	t.Run("when the denylist is not set", func(t *testing.T) {
+		claims, err := verifier.Verify(token)
+		assert.Nil(t, err)
+		_, err = verifier.Denied(context.TODO(), claims)
+		assert.Equal(t, issuer.ErrDenylistRequired, err)
+	})
+
+	t.Run("when the fetch of an expired key set fails", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&down, 1)
+		clock.Advance(issuer.DefaultCacheTTL)
+
+		// The stale keys are served while the endpoint is down.
+		token, err := iss.Issue(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+		_, err = verifier.Verify(token)
+		assert.Nil(err)
+		assert.Equal(int32(3), atomic.LoadInt32(&fetches))
+
+		// Only the unknown kids fail.
+		rotated := keys[issuer.RS256]
+		rotated.ID = "rs_2"
+		other, err := issuer.New(issuer.Options{Keyring: passport.NewKeyring(clock, rotated)}).Issue(issuer.Claims{Subject: "1"})
+		assert.Nil(err)
+		_, err = verifier.Verify(other)
+		assert.True(errors.Is(err, issuer.ErrKeySetUnavailable))
+	})
+}
@@ -0,0 +1,75 @@
+package issuer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// token is a decoded JWT whose signature is not verified yet.
+type token struct {
+	header    header
+	claims    Claims
+	payload   []byte
+	signature []byte
+}
+
+func encode(key passport.Key, claims Claims) (string, error) {
+	h, err := json.Marshal(header{
+		Algorithm: algorithm(key),
+		Type:      "JWT",
+		KeyID:     key.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	signature, err := sign(key, []byte(payload))
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func decode(raw string) (*token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, passport.ErrTokenInvalid
+	}
+
+	var t token
+	if err := decodeSegment(parts[0], &t.header); err != nil {
+		return nil, passport.ErrTokenInvalid
+	}
+	if err := decodeSegment(parts[1], &t.claims); err != nil {
+		return nil, passport.ErrTokenInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, passport.ErrTokenInvalid
+	}
+	t.payload = []byte(parts[0] + "." + parts[1])
+	t.signature = signature
+	return &t, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,225 @@
+package issuer
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// ErrDenylistRequired is returned by RemoteVerifier.Denied when no Denylist
+// is set, so that the revoked tokens are never accepted silently.
+var ErrDenylistRequired = passport.NewError("denylist_required", "denylist required", passport.CategoryInternal)
+
+// ErrKeySetUnavailable is returned by RemoteVerifier.Verify when the key set
+// cannot be fetched, and the kid of the token is not cached.
+var ErrKeySetUnavailable = passport.NewError("key_set_unavailable", "key set unavailable", passport.CategoryUnavailable)
+
+const (
+	DefaultCacheTTL           = 1 * time.Hour
+	DefaultMinRefreshInterval = 1 * time.Minute
+	DefaultFetchTimeout       = 10 * time.Second
+)
+
+type (
+	RemoteVerifierOptions struct {
+		// URL of the key set, e.g. https://auth.example.com/.well-known/jwks.json.
+		URL    string
+		Client *http.Client
+
+		Issuer   string
+		Audience []string
+		Leeway   time.Duration
+
+		// CacheTTL is how long the fetched key set is used before it is
+		// fetched again.
+		CacheTTL time.Duration
+
+		// MinRefreshInterval limits how often the key set is fetched
+		// when a token has an unknown kid, e.g. after a key rotation.
+		// The failed fetches count too, so the endpoint is not flooded
+		// while it is down.
+		MinRefreshInterval time.Duration
+
+		// Denylist is required, and must be shared with the issuing
+		// service, usually the connector.Denylist. There is no default,
+		// since a local denylist never sees the revocations of the
+		// issuing service: Denied returns ErrDenylistRequired instead.
+		Denylist Denylist
+
+		Clock passport.Clock
+	}
+
+	// RemoteVerifier verifies the access tokens issued by another service
+	// with the public keys fetched from its JWKS endpoint.
+	RemoteVerifier struct {
+		options RemoteVerifierOptions
+
+		mu          sync.Mutex
+		keys        map[string]remoteKey
+		fetchedAt   time.Time
+		attemptedAt time.Time
+		fetchErr    error
+
+		// fetching is closed when the fetch in flight completes.
+		fetching chan struct{}
+	}
+
+	remoteKey struct {
+		algorithm string
+		publicKey crypto.PublicKey
+	}
+)
+
+// Verify returns the claims of the access token.
+func (r *RemoteVerifier) Verify(token string) (*Claims, error) {
+	t, err := decode(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := r.key(t.header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if t.header.Algorithm != key.algorithm {
+		return nil, passport.ErrTokenInvalid
+	}
+	if !verify(key.algorithm, nil, key.publicKey, t.payload, t.signature) {
+		return nil, passport.ErrTokenInvalid
+	}
+
+	v := validator{
+		issuer:   r.options.Issuer,
+		audience: r.options.Audience,
+		leeway:   r.options.Leeway,
+		clock:    r.options.Clock,
+	}
+	if err := v.validate(t.claims, TokenTypeAccess); err != nil {
+		return nil, err
+	}
+	return &t.claims, nil
+}
+
+// Denied checks if the token, its session or its subject is revoked.
+func (r *RemoteVerifier) Denied(ctx context.Context, claims *Claims) (bool, error) {
+	if r.options.Denylist == nil {
+		return false, ErrDenylistRequired
+	}
+	return denied(ctx, r.options.Denylist, claims)
+}
+
+// key returns the key of the kid, fetching the key set when it has expired
+// or does not have the kid. The key set is fetched outside of the lock, and
+// the concurrent callers wait for the fetch in flight instead of fetching
+// again.
+func (r *RemoteVerifier) key(kid string) (remoteKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		now := r.options.Clock.Now()
+		key, ok := r.keys[kid]
+		expired := !now.Before(r.fetchedAt.Add(r.options.CacheTTL))
+		if ok && !expired {
+			return key, nil
+		}
+		if now.Before(r.attemptedAt.Add(r.options.MinRefreshInterval)) {
+			return r.cachedKey(kid)
+		}
+		if r.fetching == nil {
+			break
+		}
+
+		fetching := r.fetching
+		r.mu.Unlock()
+		<-fetching
+		r.mu.Lock()
+	}
+
+	fetching := make(chan struct{})
+	r.fetching = fetching
+	r.mu.Unlock()
+	keys, err := r.fetch()
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+	}
+	r.mu.Lock()
+
+	now := r.options.Clock.Now()
+	r.attemptedAt, r.fetchErr = now, err
+	if err == nil {
+		r.keys, r.fetchedAt = keys, now
+	}
+	r.fetching = nil
+	close(fetching)
+	return r.cachedKey(kid)
+}
+
+// cachedKey returns the key of the kid from the latest successful fetch,
+// even when it has expired, so that the tokens are still verified while the
+// endpoint is down. The error of the latest fetch is only returned when the
+// kid is unknown.
+func (r *RemoteVerifier) cachedKey(kid string) (remoteKey, error) {
+	if key, ok := r.keys[kid]; ok {
+		return key, nil
+	}
+	if r.fetchErr != nil {
+		return remoteKey{}, r.fetchErr
+	}
+	return remoteKey{}, passport.ErrTokenInvalid
+}
+
+func (r *RemoteVerifier) fetch() (map[string]remoteKey, error) {
+	res, err := r.options.Client.Get(r.options.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks failed: %s", res.Status)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]remoteKey)
+	for _, jwk := range jwks.Keys {
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			// Skip the keys we do not understand.
+			continue
+		}
+		keys[jwk.KeyID] = remoteKey{jwk.Algorithm, pub}
+	}
+	return keys, nil
+}
+
+// NewRemoteVerifier returns a new RemoteVerifier. The key set is fetched
+// lazily on the first verification.
+func NewRemoteVerifier(options RemoteVerifierOptions) *RemoteVerifier {
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: DefaultFetchTimeout}
+	}
+	if options.Leeway == 0 {
+		options.Leeway = DefaultLeeway
+	}
+	if options.CacheTTL == 0 {
+		options.CacheTTL = DefaultCacheTTL
+	}
+	if options.MinRefreshInterval == 0 {
+		options.MinRefreshInterval = DefaultMinRefreshInterval
+	}
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	return &RemoteVerifier{options: options}
+}
@@ -0,0 +1,40 @@
+package issuer
+
+import (
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// validator checks the claims of a token whose signature is verified.
+type validator struct {
+	issuer   string
+	audience []string
+	leeway   time.Duration
+	clock    passport.Clock
+}
+
+func (v validator) validate(claims Claims, typ string) error {
+	if claims.Type != typ {
+		return passport.ErrTokenInvalid
+	}
+
+	now := v.clock.Now()
+	if claims.ExpiresAt == 0 || !now.Add(-v.leeway).Before(time.Unix(claims.ExpiresAt, 0)) {
+		return passport.ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(v.leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return passport.ErrTokenInvalid
+	}
+	if claims.IssuedAt != 0 && now.Add(v.leeway).Before(time.Unix(claims.IssuedAt, 0)) {
+		return passport.ErrTokenInvalid
+	}
+
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return ErrIssuerInvalid
+	}
+	if len(v.audience) > 0 && !claims.Audience.Contains(v.audience...) {
+		return ErrAudienceInvalid
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package passport
+
+import (
+	"crypto"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrKeyNotFound = NewError("key_not_found", "key not found", CategoryNotFound)
+	ErrKeyRetired  = NewError("key_retired", "key retired", CategoryConflict)
+)
+
+// Key is a secret identified by its id. The id is embedded in the signed
+// outputs, so that the key can be looked up during verification.
+type Key struct {
+	ID     string
+	Secret []byte
+
+	// Algorithm is the JWT algorithm the key is used with, e.g. HS256,
+	// RS256 or EdDSA. Asymmetric algorithms sign with the PrivateKey
+	// instead of the Secret.
+	Algorithm  string
+	PrivateKey crypto.Signer
+
+	// RetiresAt is the time after which the key is no longer accepted
+	// for verification. The zero value means the key never retires.
+	RetiresAt time.Time
+}
+
+// RetiredAt checks if the key is retired at the given time.
+func (k Key) RetiredAt(now time.Time) bool {
+	return !k.RetiresAt.IsZero() && !now.Before(k.RetiresAt)
+}
+
+// PublicKey returns the public half of the asymmetric key, or nil for
+// symmetric keys.
+func (k Key) PublicKey() crypto.PublicKey {
+	if k.PrivateKey == nil {
+		return nil
+	}
+	return k.PrivateKey.Public()
+}
+
+// Keyring holds multiple keys, one of which is active for signing, while
+// all keys that are not retired are valid for verification. Rotating a
+// secret is done by adding a new key, activating it and setting the
+// retirement date of the previous key to after the longest lived token
+// signed with it expires.
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string]Key
+	active string
+	clock  Clock
+}
+
+// Add adds or replaces the key in the keyring.
+func (k *Keyring) Add(key Key) {
+	k.mu.Lock()
+	k.keys[key.ID] = key
+	k.mu.Unlock()
+}
+
+// Activate makes the key with the given id the active signing key.
+func (k *Keyring) Activate(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if key.RetiredAt(k.clock.Now()) {
+		return ErrKeyRetired
+	}
+	k.active = id
+	return nil
+}
+
+// Retire sets the retirement date of the key with the given id.
+func (k *Keyring) Retire(id string, at time.Time) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	key.RetiresAt = at
+	k.keys[id] = key
+	return nil
+}
+
+// Active returns the key used for signing.
+func (k *Keyring) Active() (Key, error) {
+	k.mu.RLock()
+	id := k.active
+	k.mu.RUnlock()
+	return k.Key(id)
+}
+
+// Key returns the key with the given id for verification.
+func (k *Keyring) Key(id string) (Key, error) {
+	k.mu.RLock()
+	key, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	if key.RetiredAt(k.clock.Now()) {
+		return Key{}, ErrKeyRetired
+	}
+	return key, nil
+}
+
+// Keys returns all keys that are not retired, sorted by id.
+func (k *Keyring) Keys() []Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := k.clock.Now()
+	keys := make([]Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.RetiredAt(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// Clock returns the clock used to check the key retirement.
+func (k *Keyring) Clock() Clock {
+	return k.clock
+}
+
+// NewKeyring returns a new Keyring with the given active key. The clock
+// defaults to SystemClock when nil.
+func NewKeyring(clock Clock, active Key, keys ...Key) *Keyring {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	k := &Keyring{
+		keys:   make(map[string]Key),
+		active: active.ID,
+		clock:  clock,
+	}
+	k.keys[active.ID] = active
+	for _, key := range keys {
+		k.keys[key.ID] = key
+	}
+	return k
+}
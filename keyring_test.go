@@ -0,0 +1,65 @@
+package passport_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	var (
+		now     = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock   = passport.NewFakeClock(now)
+		keyring = passport.NewKeyring(clock, passport.Key{ID: "key_1", Secret: []byte("secret_1")})
+		signer  = passport.NewHMACTokenSigner(keyring)
+		claims  = passport.SignedTokenClaims{
+			UserID:    "1",
+			Purpose:   passport.TokenPurposeConfirmation,
+			ExpiresAt: now.Add(48 * time.Hour).Unix(),
+		}
+	)
+	oldToken, err := signer.Sign(claims)
+	assert.Nil(t, err)
+
+	keyring.Add(passport.Key{ID: "key_2", Secret: []byte("secret_2")})
+	assert.Nil(t, keyring.Activate("key_2"))
+	assert.Nil(t, keyring.Retire("key_1", now.Add(24*time.Hour)))
+
+	newToken, err := signer.Sign(claims)
+	assert.Nil(t, err)
+
+	t.Run("when signing after rotation", func(t *testing.T) {
+		assert := assert.New(t)
+		key, err := keyring.Active()
+		assert.Nil(err)
+		assert.Equal("key_2", key.ID)
+		assert.NotEqual(oldToken, newToken)
+	})
+
+	t.Run("when verifying with the previous key", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := signer.Verify(oldToken, passport.TokenPurposeConfirmation)
+		assert.Nil(err)
+		_, err = signer.Verify(newToken, passport.TokenPurposeConfirmation)
+		assert.Nil(err)
+	})
+
+	t.Run("when the previous key is retired", func(t *testing.T) {
+		assert := assert.New(t)
+		clock.Advance(24 * time.Hour)
+		defer clock.Set(now)
+
+		_, err := signer.Verify(oldToken, passport.TokenPurposeConfirmation)
+		assert.Equal(passport.ErrTokenInvalid, err)
+		_, err = signer.Verify(newToken, passport.TokenPurposeConfirmation)
+		assert.Nil(err)
+		assert.Len(keyring.Keys(), 1)
+		assert.Equal(passport.ErrKeyRetired, keyring.Activate("key_1"))
+	})
+
+	t.Run("when activating an unknown key", func(t *testing.T) {
+		assert.Equal(t, passport.ErrKeyNotFound, keyring.Activate("key_3"))
+	})
+}
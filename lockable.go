@@ -0,0 +1,28 @@
+package passport
+
+import (
+	"time"
+)
+
+// ErrAccountLocked indicates that the account is locked until the password
+// is reset.
+var ErrAccountLocked = NewError("account_locked", "account locked", CategoryLocked)
+
+// Lockable holds the data to lock the User's account, e.g. after an email
+// change is reverted by the previous owner.
+type Lockable struct {
+	LockedAt time.Time `json:"locked_at,omitempty"`
+}
+
+// Locked checks if the account is locked.
+func (l Lockable) Locked() bool {
+	return !l.LockedAt.IsZero()
+}
+
+// ValidateUnlocked returns an error indicating the account is locked.
+func (l Lockable) ValidateUnlocked() error {
+	if l.Locked() {
+		return ErrAccountLocked
+	}
+	return nil
+}
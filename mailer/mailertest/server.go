@@ -0,0 +1,300 @@
+// Package mailertest provides an in-process SMTP server that captures the
+// messages, for testing the mail flows end to end.
+package mailertest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a message received by the Server.
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Parse parses the headers and body of the message.
+func (m Message) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+type (
+	// Option configures the Server.
+	Option func(*Server)
+
+	// Server is an SMTP server listening on a local port.
+	Server struct {
+		Host string
+		Port int
+
+		listener    net.Listener
+		tlsConfig   *tls.Config
+		startTLS    bool
+		implicitTLS bool
+		username    string
+		password    string
+
+		mu          sync.Mutex
+		messages    []Message
+		connections int
+		wg          sync.WaitGroup
+	}
+)
+
+// WithAuth requires the clients to authenticate with AUTH PLAIN.
+func WithAuth(username, password string) Option {
+	return func(s *Server) {
+		s.username, s.password = username, password
+	}
+}
+
+// WithStartTLS advertises the STARTTLS extension.
+func WithStartTLS() Option {
+	return func(s *Server) {
+		s.startTLS = true
+	}
+}
+
+// WithImplicitTLS accepts TLS connections only.
+func WithImplicitTLS() Option {
+	return func(s *Server) {
+		s.implicitTLS = true
+	}
+}
+
+// NewServer starts a new Server. It panics when the server cannot listen,
+// like httptest.NewServer.
+func NewServer(opts ...Option) *Server {
+	s := &Server{tlsConfig: &tls.Config{Certificates: []tls.Certificate{newCertificate()}}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mailertest: failed to listen: %v", err))
+	}
+	if s.implicitTLS {
+		l = tls.NewListener(l, s.tlsConfig)
+	}
+	s.listener = l
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	s.Host = host
+	s.Port, _ = strconv.Atoi(port)
+
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// ClientTLSConfig returns the TLS config that trusts the certificate of the
+// server.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	cert, _ := x509.ParseCertificate(s.tlsConfig.Certificates[0].Certificate[0])
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{RootCAs: pool, ServerName: s.Host}
+}
+
+// Messages returns the messages received.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Connections returns the number of connections accepted.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+type session struct {
+	from          string
+	to            []string
+	authenticated bool
+	tls           bool
+}
+
+func (s *Server) handle(conn net.Conn) {
+	_, isTLS := conn.(*tls.Conn)
+	sess := session{tls: isTLS}
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 %s ESMTP mailertest", s.Host)
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			exts := []string{"mailertest", "8BITMIME"}
+			if s.startTLS && !sess.tls {
+				exts = append(exts, "STARTTLS")
+			}
+			if s.username != "" {
+				exts = append(exts, "AUTH PLAIN")
+			}
+			for i, ext := range exts {
+				sep := "-"
+				if i == len(exts)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, ext)
+			}
+		case "STARTTLS":
+			if !s.startTLS || sess.tls {
+				tp.PrintfLine("502 not supported")
+				continue
+			}
+			tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			sess = session{tls: true}
+		case "AUTH":
+			if s.auth(arg) {
+				sess.authenticated = true
+				tp.PrintfLine("235 authenticated")
+			} else {
+				tp.PrintfLine("535 authentication failed")
+			}
+		case "MAIL":
+			if s.username != "" && !sess.authenticated {
+				tp.PrintfLine("530 authentication required")
+				continue
+			}
+			sess.from = address(arg)
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			sess.to = append(sess.to, address(arg))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			if sess.from == "" || len(sess.to) == 0 {
+				tp.PrintfLine("503 bad sequence")
+				continue
+			}
+			tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := ioutil.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, Message{From: sess.from, To: sess.to, Data: data})
+			s.mu.Unlock()
+			sess.from, sess.to = "", nil
+			tp.PrintfLine("250 ok queued")
+		case "RSET":
+			sess.from, sess.to = "", nil
+			tp.PrintfLine("250 ok")
+		case "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+func (s *Server) auth(arg string) bool {
+	fields := strings.Fields(arg)
+	if len(fields) != 2 || strings.ToUpper(fields[0]) != "PLAIN" {
+		return false
+	}
+	b, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(b), "\x00")
+	return len(parts) == 3 && parts[1] == s.username && parts[2] == s.password
+}
+
+// address extracts the address from FROM:<a@b> and TO:<a@b>.
+func address(arg string) string {
+	start, end := strings.IndexByte(arg, '<'), strings.IndexByte(arg, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+func newCertificate() tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mailertest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
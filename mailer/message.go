@@ -0,0 +1,157 @@
+// Package mailer sends the passport mails over SMTP.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+var (
+	ErrRecipientRequired = passport.NewError("recipient_required", "recipient required", passport.CategoryValidation)
+	ErrBodyRequired      = passport.NewError("body_required", "body required", passport.CategoryValidation)
+	ErrHeaderInvalid     = passport.NewError("header_invalid", "header invalid", passport.CategoryValidation)
+)
+
+// Message is a mail with a plain text body, and an optional HTML
+// alternative.
+type Message struct {
+	From    string   `json:"from,omitempty"`
+	To      []string `json:"to"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+
+	// Headers are additional headers, e.g. List-Unsubscribe.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Build returns the MIME encoded message with the Date and Message-ID
+// headers set.
+func (m Message) Build(now time.Time) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, ErrRecipientRequired
+	}
+	if m.Text == "" && m.HTML == "" {
+		return nil, ErrBodyRequired
+	}
+
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, err
+	}
+	to := make([]string, len(m.To))
+	for i, addr := range m.To {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		to[i] = a.String()
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) error {
+		if strings.ContainsAny(key, "\r\n:") || strings.ContainsAny(value, "\r\n") {
+			return ErrHeaderInvalid
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+		return nil
+	}
+
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", messageID(from.Address)},
+		{"MIME-Version", "1.0"},
+	}
+	if m.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(m.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, [2]string{"Reply-To", replyTo.String()})
+	}
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		headers = append(headers, [2]string{textproto.CanonicalMIMEHeaderKey(k), m.Headers[k]})
+	}
+	for _, h := range headers {
+		if err := header(h[0], h[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.HTML == "" {
+		fmt.Fprintf(&buf, "Content-Type: text/plain; charset=utf-8\r\n")
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, m.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+	for _, p := range parts {
+		if p.body == "" {
+			continue
+		}
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, p.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,107 @@
+package mailer_test
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMessageBuild(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := mailer.Message{
+		From:    "Passport <noreply@passport.dev>",
+		To:      []string{"john.doe@mail.com"},
+		Subject: "Confirm your email",
+		Text:    "token: abc",
+		HTML:    "<p>token: abc</p>",
+		Headers: map[string]string{"X-Mail-Type": "confirmation"},
+	}
+	b, err := msg.Build(now)
+	assert.Nil(err)
+
+	m, err := mail.ReadMessage(strings.NewReader(string(b)))
+	assert.Nil(err)
+	assert.Equal(`"Passport" <noreply@passport.dev>`, m.Header.Get("From"))
+	assert.Equal("<john.doe@mail.com>", m.Header.Get("To"))
+	assert.Equal("Confirm your email", m.Header.Get("Subject"))
+	assert.Equal("confirmation", m.Header.Get("X-Mail-Type"))
+	assert.True(strings.HasSuffix(m.Header.Get("Message-ID"), "@passport.dev>"))
+
+	date, err := m.Header.Date()
+	assert.Nil(err)
+	assert.True(now.Equal(date))
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	assert.Nil(err)
+	assert.Equal("multipart/alternative", mediaType)
+
+	r := multipart.NewReader(m.Body, params["boundary"])
+	var bodies []string
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		b, _ := ioutil.ReadAll(p)
+		bodies = append(bodies, p.Header.Get("Content-Type")+" "+string(b))
+	}
+	assert.Equal([]string{
+		"text/plain; charset=utf-8 token: abc",
+		"text/html; charset=utf-8 <p>token: abc</p>",
+	}, bodies)
+}
+
+func TestMessageBuildTextOnly(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := mailer.Message{
+		From:    "noreply@passport.dev",
+		To:      []string{"john.doe@mail.com"},
+		Subject: "Réinitialiser",
+		Text:    "token: abc",
+	}
+	b, err := msg.Build(now)
+	assert.Nil(err)
+
+	m, err := mail.ReadMessage(strings.NewReader(string(b)))
+	assert.Nil(err)
+	assert.Equal("text/plain; charset=utf-8", m.Header.Get("Content-Type"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	assert.Nil(err)
+	assert.Equal("Réinitialiser", subject)
+}
+
+func TestMessageBuildInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  mailer.Message
+		err  error
+	}{
+		{"no recipient", mailer.Message{From: "a@mail.com", Text: "hi"}, mailer.ErrRecipientRequired},
+		{"no body", mailer.Message{From: "a@mail.com", To: []string{"b@mail.com"}}, mailer.ErrBodyRequired},
+		{"header injection", mailer.Message{
+			From:    "a@mail.com",
+			To:      []string{"b@mail.com"},
+			Text:    "hi",
+			Headers: map[string]string{"X-Ref": "1\r\nBcc: c@mail.com"},
+		}, mailer.ErrHeaderInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.msg.Build(now)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultIdleTimeout = 30 * time.Second
+)
+
+var ErrStartTLSUnsupported = passport.NewError("starttls_unsupported", "smtp server does not support STARTTLS", passport.CategoryInternal)
+
+// Security is how the connection to the SMTP server is secured.
+type Security int
+
+const (
+	// StartTLS upgrades the plain connection, usually on port 587. The
+	// mail is not sent when the server does not support STARTTLS.
+	StartTLS Security = iota
+
+	// ImplicitTLS connects with TLS, usually on port 465.
+	ImplicitTLS
+
+	// Plain does not use TLS, and should only be used for local servers.
+	Plain
+)
+
+type (
+	SMTPOptions struct {
+		Host     string
+		Port     int
+		Username string
+		Password string
+		Security Security
+
+		// TLSConfig defaults to verifying the certificate of the Host.
+		TLSConfig *tls.Config
+
+		// From is the sender of the messages without one.
+		From string
+
+		// LocalName is sent in the EHLO command.
+		LocalName string
+
+		// Timeout limits the time to connect and to send a message.
+		Timeout time.Duration
+
+		// IdleTimeout is how long a connection is kept for reuse.
+		IdleTimeout time.Duration
+
+		Clock passport.Clock
+	}
+
+	// SMTPMailer sends the messages over a single SMTP connection, which
+	// is reused for the messages sent within the IdleTimeout.
+	SMTPMailer struct {
+		options SMTPOptions
+
+		mu       sync.Mutex
+		conn     net.Conn
+		client   *smtp.Client
+		lastUsed time.Time
+	}
+)
+
+// Send sends the message. The connection is discarded when sending fails,
+// so that the next message is sent on a new connection.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = m.options.From
+	}
+	now := m.options.Clock.Now()
+	data, err := msg.Build(now)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.connect(ctx, now); err != nil {
+		return err
+	}
+	if err := m.send(ctx, msg, data); err != nil {
+		m.discard()
+		return err
+	}
+	m.lastUsed = m.options.Clock.Now()
+	return nil
+}
+
+// Close quits the connection.
+func (m *SMTPMailer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.client == nil {
+		return nil
+	}
+	err := m.client.Quit()
+	m.discard()
+	return err
+}
+
+func (m *SMTPMailer) send(ctx context.Context, msg Message, data []byte) error {
+	deadline := time.Now().Add(m.options.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := m.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+	if err := m.client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return err
+		}
+		if err := m.client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := m.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// connect reuses the idle connection when it is still alive, or dials a new
+// one.
+func (m *SMTPMailer) connect(ctx context.Context, now time.Time) error {
+	if m.client != nil {
+		idle := now.Sub(m.lastUsed) < m.options.IdleTimeout
+		if idle && m.conn.SetDeadline(time.Now().Add(m.options.Timeout)) == nil && m.client.Reset() == nil {
+			return nil
+		}
+		m.client.Quit()
+		m.discard()
+	}
+	return m.dial(ctx)
+}
+
+func (m *SMTPMailer) dial(ctx context.Context) error {
+	var (
+		o      = m.options
+		addr   = net.JoinHostPort(o.Host, strconv.Itoa(o.Port))
+		dialer = net.Dialer{Timeout: o.Timeout}
+	)
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(o.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	if o.Security == ImplicitTLS {
+		tlsConn := tls.Client(conn, m.tlsConfig())
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, o.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := m.setup(client); err != nil {
+		client.Close()
+		return err
+	}
+
+	m.conn, m.client = conn, client
+	return nil
+}
+
+func (m *SMTPMailer) setup(client *smtp.Client) error {
+	o := m.options
+	if o.LocalName != "" {
+		if err := client.Hello(o.LocalName); err != nil {
+			return err
+		}
+	}
+	if o.Security == StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return ErrStartTLSUnsupported
+		}
+		if err := client.StartTLS(m.tlsConfig()); err != nil {
+			return err
+		}
+	}
+	if o.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", o.Username, o.Password, o.Host)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *SMTPMailer) tlsConfig() *tls.Config {
+	if m.options.TLSConfig != nil {
+		return m.options.TLSConfig
+	}
+	return &tls.Config{ServerName: m.options.Host}
+}
+
+func (m *SMTPMailer) discard() {
+	if m.client != nil {
+		m.client.Close()
+	}
+	m.conn, m.client = nil, nil
+}
+
+// NewSMTPMailer returns a new SMTPMailer. The connection is established
+// lazily on the first message.
+func NewSMTPMailer(options SMTPOptions) *SMTPMailer {
+	if options.Port == 0 {
+		switch options.Security {
+		case ImplicitTLS:
+			options.Port = 465
+		case Plain:
+			options.Port = 25
+		default:
+			options.Port = 587
+		}
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.IdleTimeout == 0 {
+		options.IdleTimeout = DefaultIdleTimeout
+	}
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	return &SMTPMailer{options: options}
+}
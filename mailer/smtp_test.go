@@ -0,0 +1,116 @@
+package mailer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alextanhongpin/passport/mailer"
+	"github.com/alextanhongpin/passport/mailer/mailertest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMessage(to string) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Confirm your email",
+		Text:    "token: abc",
+		HTML:    "<p>token: abc</p>",
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := mailertest.NewServer(mailertest.WithAuth("user", "secret"))
+	defer srv.Close()
+
+	m := mailer.NewSMTPMailer(mailer.SMTPOptions{
+		Host:     srv.Host,
+		Port:     srv.Port,
+		Username: "user",
+		Password: "secret",
+		Security: mailer.Plain,
+		From:     "noreply@passport.dev",
+	})
+	defer m.Close()
+
+	assert.Nil(m.Send(context.TODO(), newMessage("john.doe@mail.com")))
+	assert.Nil(m.Send(context.TODO(), newMessage("jane.doe@mail.com")))
+	assert.Equal(1, srv.Connections())
+
+	msgs := srv.Messages()
+	assert.Equal(2, len(msgs))
+	assert.Equal("noreply@passport.dev", msgs[0].From)
+	assert.Equal([]string{"jane.doe@mail.com"}, msgs[1].To)
+
+	parsed, err := msgs[0].Parse()
+	assert.Nil(err)
+	assert.Equal("Confirm your email", parsed.Header.Get("Subject"))
+}
+
+func TestSMTPMailerAuthFailed(t *testing.T) {
+	srv := mailertest.NewServer(mailertest.WithAuth("user", "secret"))
+	defer srv.Close()
+
+	m := mailer.NewSMTPMailer(mailer.SMTPOptions{
+		Host:     srv.Host,
+		Port:     srv.Port,
+		Username: "user",
+		Password: "wrong",
+		Security: mailer.Plain,
+		From:     "noreply@passport.dev",
+	})
+	defer m.Close()
+
+	assert.NotNil(t, m.Send(context.TODO(), newMessage("john.doe@mail.com")))
+	assert.Equal(t, 0, len(srv.Messages()))
+}
+
+func TestSMTPMailerTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		opt      mailertest.Option
+		security mailer.Security
+	}{
+		{"starttls", mailertest.WithStartTLS(), mailer.StartTLS},
+		{"implicit tls", mailertest.WithImplicitTLS(), mailer.ImplicitTLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			srv := mailertest.NewServer(tt.opt, mailertest.WithAuth("user", "secret"))
+			defer srv.Close()
+
+			m := mailer.NewSMTPMailer(mailer.SMTPOptions{
+				Host:      srv.Host,
+				Port:      srv.Port,
+				Username:  "user",
+				Password:  "secret",
+				Security:  tt.security,
+				TLSConfig: srv.ClientTLSConfig(),
+				From:      "noreply@passport.dev",
+			})
+			defer m.Close()
+
+			assert.Nil(m.Send(context.TODO(), newMessage("john.doe@mail.com")))
+			assert.Equal(1, len(srv.Messages()))
+		})
+	}
+}
+
+func TestSMTPMailerStartTLSUnsupported(t *testing.T) {
+	srv := mailertest.NewServer()
+	defer srv.Close()
+
+	m := mailer.NewSMTPMailer(mailer.SMTPOptions{
+		Host: srv.Host,
+		Port: srv.Port,
+		From: "noreply@passport.dev",
+	})
+	defer m.Close()
+
+	assert.Equal(t, mailer.ErrStartTLSUnsupported, m.Send(context.TODO(), newMessage("john.doe@mail.com")))
+	assert.Equal(t, 0, len(srv.Messages()))
+}
@@ -0,0 +1,251 @@
+package mailer
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/i18n"
+)
+
+var ErrTemplateNotFound = passport.NewError("template_not_found", "template not found", passport.CategoryInternal)
+
+// Names of the mail templates. A template is made of the <name>.html and
+// <name>.txt files, which define the content block rendered by the
+// layout.html and layout.txt files.
+const (
+	Layout        = "layout"
+	Confirmation  = "confirmation"
+	ChangeEmail   = "change_email"
+	EmailChanged  = "email_changed"
+	ResetPassword = "reset_password"
+)
+
+// DefaultActions are the paths of the pages that handle the token of each
+// template.
+var DefaultActions = map[string]string{
+	Confirmation:  "/confirm",
+	ChangeEmail:   "/confirm",
+	EmailChanged:  "/revert-email",
+	ResetPassword: "/reset-password",
+}
+
+// templateNames maps the mail events to the templates.
+var templateNames = map[string]string{
+	passport.EventUserRegistered:         Confirmation,
+	passport.EventConfirmationRequested:  Confirmation,
+	passport.EventEmailChangeRequested:   ChangeEmail,
+	passport.EventEmailChanged:           EmailChanged,
+	passport.EventResetPasswordRequested: ResetPassword,
+}
+
+// messageKeys are the catalog keys of the subject, body and action label
+// of each template.
+var messageKeys = map[string][3]string{
+	Confirmation:  {i18n.MailConfirmationSubject, i18n.MailConfirmationBody, i18n.MailConfirmationAction},
+	ChangeEmail:   {i18n.MailChangeEmailSubject, i18n.MailChangeEmailBody, i18n.MailChangeEmailAction},
+	EmailChanged:  {i18n.MailEmailChangedSubject, i18n.MailEmailChangedBody, i18n.MailEmailChangedAction},
+	ResetPassword: {i18n.MailResetPasswordSubject, i18n.MailResetPasswordBody, i18n.MailResetPasswordAction},
+}
+
+type (
+	// Brand is the branding of the mails.
+	Brand struct {
+		Name    string
+		URL     string
+		LogoURL string
+
+		// Color is the color of the action button, e.g. #0066ff.
+		Color string
+
+		// Footer is shown at the end of the mails, e.g. the address of
+		// the company.
+		Footer string
+	}
+
+	TemplateOptions struct {
+		Catalog *i18n.Catalog
+		Brand   Brand
+
+		// BaseURL is the URL of the app the action paths are joined to.
+		BaseURL string
+
+		// Actions are the paths of the action URLs by the template name.
+		// The token is set as the token query parameter.
+		Actions map[string]string
+
+		// Dir overrides the built-in templates with the files of the same
+		// name, e.g. layout.html or reset_password.txt.
+		Dir string
+	}
+
+	// TemplateData is the data the templates are executed with. T
+	// translates the other messages of the catalog, e.g.
+	// {{call .T "mail.ignore"}}.
+	TemplateData struct {
+		Brand     Brand
+		Locale    string
+		Email     string
+		Token     string
+		ActionURL string
+		Subject   string
+		Body      string
+		Action    string
+		T         func(key string) string
+	}
+
+	// Templates renders the mails in the locale of the user.
+	Templates struct {
+		options TemplateOptions
+		html    map[string]*htmltemplate.Template
+		text    map[string]*texttemplate.Template
+	}
+)
+
+// Render renders the mail of the event to the recipient.
+func (t *Templates) Render(locale, mailType, to, token string) (Message, error) {
+	name, ok := templateNames[mailType]
+	if !ok {
+		return Message{}, ErrTemplateNotFound
+	}
+	return t.render(locale, name, to, token)
+}
+
+// Preview writes each template rendered in each locale of the catalog to
+// <dir>/<locale>/<name>.html and .txt, for design review.
+func (t *Templates) Preview(dir string) error {
+	names := make([]string, 0, len(messageKeys))
+	for name := range messageKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, locale := range t.options.Catalog.Locales() {
+		if err := os.MkdirAll(filepath.Join(dir, locale), 0755); err != nil {
+			return err
+		}
+		for _, name := range names {
+			msg, err := t.render(locale, name, "john.doe@mail.com", "preview-token")
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(dir, locale, name)
+			if err := ioutil.WriteFile(path+".html", []byte(msg.HTML), 0644); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path+".txt", []byte(msg.Text), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Templates) render(locale, name, to, token string) (Message, error) {
+	catalog := t.options.Catalog
+	params := map[string]interface{}{"token": token}
+	translate := func(key string) string {
+		return catalog.Translate(locale, key, params)
+	}
+	keys := messageKeys[name]
+	data := TemplateData{
+		Brand:     t.options.Brand,
+		Locale:    locale,
+		Email:     to,
+		Token:     token,
+		ActionURL: t.actionURL(name, token),
+		Subject:   translate(keys[0]),
+		Body:      translate(keys[1]),
+		Action:    translate(keys[2]),
+		T:         translate,
+	}
+
+	var html, text bytes.Buffer
+	if err := t.html[name].ExecuteTemplate(&html, Layout, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.text[name].ExecuteTemplate(&text, Layout, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      []string{to},
+		Subject: data.Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func (t *Templates) actionURL(name, token string) string {
+	path, ok := t.options.Actions[name]
+	if !ok {
+		return ""
+	}
+	return strings.TrimRight(t.options.BaseURL, "/") + path + "?" + url.Values{"token": {token}}.Encode()
+}
+
+// source returns the template file from the Dir, or the built-in template.
+func (t *Templates) source(file string) (string, error) {
+	if t.options.Dir != "" {
+		b, err := ioutil.ReadFile(filepath.Join(t.options.Dir, file))
+		if err == nil {
+			return string(b), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return defaultTemplates[file], nil
+}
+
+// NewTemplates parses the templates. The built-in templates are used for
+// the files missing from the Dir.
+func NewTemplates(options TemplateOptions) (*Templates, error) {
+	if options.Catalog == nil {
+		options.Catalog = i18n.Default()
+	}
+	if options.Actions == nil {
+		options.Actions = DefaultActions
+	}
+	t := &Templates{
+		options: options,
+		html:    make(map[string]*htmltemplate.Template),
+		text:    make(map[string]*texttemplate.Template),
+	}
+
+	sources := make(map[string]string)
+	for _, name := range []string{Layout, Confirmation, ChangeEmail, EmailChanged, ResetPassword} {
+		for _, ext := range []string{".html", ".txt"} {
+			src, err := t.source(name + ext)
+			if err != nil {
+				return nil, err
+			}
+			sources[name+ext] = src
+		}
+	}
+
+	for name := range messageKeys {
+		html, err := htmltemplate.New(Layout).Parse(sources[Layout+".html"])
+		if err != nil {
+			return nil, err
+		}
+		if _, err := html.New(name).Parse(sources[name+".html"]); err != nil {
+			return nil, err
+		}
+		text, err := texttemplate.New(Layout).Parse(sources[Layout+".txt"])
+		if err != nil {
+			return nil, err
+		}
+		if _, err := text.New(name).Parse(sources[name+".txt"]); err != nil {
+			return nil, err
+		}
+		t.html[name], t.text[name] = html, text
+	}
+	return t, nil
+}
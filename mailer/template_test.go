@@ -0,0 +1,94 @@
+package mailer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/i18n"
+	"github.com/alextanhongpin/passport/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	assert := assert.New(t)
+
+	templates, err := mailer.NewTemplates(mailer.TemplateOptions{
+		BaseURL: "https://app.passport.dev/",
+		Brand:   mailer.Brand{Name: "Passport", Footer: "Passport Inc."},
+	})
+	assert.Nil(err)
+
+	msg, err := templates.Render(i18n.English, passport.EventResetPasswordRequested, "john.doe@mail.com", "a+b")
+	assert.Nil(err)
+	assert.Equal([]string{"john.doe@mail.com"}, msg.To)
+	assert.Equal("Reset your Password", msg.Subject)
+	assert.Equal(`Passport
+
+Your reset password token: a+b
+
+Reset Password: https://app.passport.dev/reset-password?token=a%2Bb
+
+If you did not request this, you can ignore this email.
+
+--
+Passport Inc.
+`, msg.Text)
+	assert.True(strings.Contains(msg.HTML, `<html lang="en">`))
+	assert.True(strings.Contains(msg.HTML, `href="https://app.passport.dev/reset-password?token=a%2Bb"`))
+	assert.True(strings.Contains(msg.HTML, "<strong>Passport</strong>"))
+
+	msg, err = templates.Render(i18n.Malay, passport.EventUserRegistered, "john.doe@mail.com", "abc")
+	assert.Nil(err)
+	assert.Equal("Sahkan Emel Anda", msg.Subject)
+	assert.True(strings.Contains(msg.Text, "Sahkan Emel: https://app.passport.dev/confirm?token=abc"))
+
+	_, err = templates.Render(i18n.English, "user.logged_in", "john.doe@mail.com", "abc")
+	assert.Equal(mailer.ErrTemplateNotFound, err)
+}
+
+func TestTemplatesOverride(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "templates")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "reset_password.txt"), []byte(`{{define "content"}}Reset at {{.ActionURL}}{{end}}`), 0644))
+
+	templates, err := mailer.NewTemplates(mailer.TemplateOptions{
+		BaseURL: "https://app.passport.dev",
+		Dir:     dir,
+	})
+	assert.Nil(err)
+
+	msg, err := templates.Render(i18n.English, passport.EventResetPasswordRequested, "john.doe@mail.com", "abc")
+	assert.Nil(err)
+	assert.Equal("Reset at https://app.passport.dev/reset-password?token=abc\n", msg.Text)
+	assert.True(strings.Contains(msg.HTML, "Your reset password token: abc"))
+}
+
+func TestTemplatesPreview(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "preview")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	templates, err := mailer.NewTemplates(mailer.TemplateOptions{})
+	assert.Nil(err)
+	assert.Nil(templates.Preview(dir))
+
+	for _, locale := range []string{i18n.English, i18n.Malay, i18n.Chinese} {
+		for _, name := range []string{mailer.Confirmation, mailer.ChangeEmail, mailer.EmailChanged, mailer.ResetPassword} {
+			for _, ext := range []string{".html", ".txt"} {
+				_, err := os.Stat(filepath.Join(dir, locale, name+ext))
+				assert.Nil(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package mailer
+
+// defaultTemplates are the built-in templates. The mails share the same
+// content, which shows the body and the action link. The email changed
+// notice is sent without being requested, so it cannot be ignored.
+var defaultTemplates = map[string]string{
+	Layout + ".html":        layoutHTML,
+	Layout + ".txt":         layoutText,
+	Confirmation + ".html":  contentHTML,
+	Confirmation + ".txt":   contentText,
+	ChangeEmail + ".html":   contentHTML,
+	ChangeEmail + ".txt":    contentText,
+	EmailChanged + ".html":  noticeHTML,
+	EmailChanged + ".txt":   noticeText,
+	ResetPassword + ".html": contentHTML,
+	ResetPassword + ".txt":  contentText,
+}
+
+const layoutHTML = `<!DOCTYPE html>
+<html lang="{{.Locale}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Subject}}</title>
+</head>
+<body style="margin:0;padding:24px;background:#f4f4f5;font-family:Helvetica,Arial,sans-serif;color:#18181b;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0">
+<tr><td align="center">
+<table role="presentation" width="560" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:8px;padding:32px;">
+{{- if .Brand.Name}}
+<tr><td style="padding-bottom:24px;">
+{{- if .Brand.LogoURL}}<img src="{{.Brand.LogoURL}}" alt="{{.Brand.Name}}" height="32">{{else}}<strong>{{.Brand.Name}}</strong>{{end -}}
+</td></tr>
+{{- end}}
+<tr><td>
+{{template "content" .}}
+</td></tr>
+</table>
+{{- if .Brand.Footer}}
+<p style="font-size:12px;color:#71717a;">{{.Brand.Footer}}</p>
+{{- end}}
+</td></tr>
+</table>
+</body>
+</html>
+`
+
+const layoutText = `{{if .Brand.Name}}{{.Brand.Name}}
+
+{{end}}{{template "content" .}}
+{{- if .Brand.Footer}}
+
+--
+{{.Brand.Footer}}
+{{- end}}
+`
+
+const contentHTML = `{{define "content" -}}
+<h1 style="font-size:20px;">{{.Subject}}</h1>
+<p>{{.Body}}</p>
+{{- if .ActionURL}}
+<p><a href="{{.ActionURL}}" style="display:inline-block;padding:12px 24px;border-radius:6px;color:#ffffff;text-decoration:none;background:{{with .Brand.Color}}{{.}}{{else}}#2563eb{{end}};">{{.Action}}</a></p>
+{{- end}}
+<p style="font-size:12px;color:#71717a;">{{call .T "mail.ignore"}}</p>
+{{- end}}`
+
+const contentText = `{{define "content" -}}
+{{.Body}}
+{{- if .ActionURL}}
+
+{{.Action}}: {{.ActionURL}}
+{{- end}}
+
+{{call .T "mail.ignore"}}
+{{- end}}`
+
+const noticeHTML = `{{define "content" -}}
+<h1 style="font-size:20px;">{{.Subject}}</h1>
+<p>{{.Body}}</p>
+{{- if .ActionURL}}
+<p><a href="{{.ActionURL}}" style="display:inline-block;padding:12px 24px;border-radius:6px;color:#ffffff;text-decoration:none;background:{{with .Brand.Color}}{{.}}{{else}}#dc2626{{end}};">{{.Action}}</a></p>
+{{- end}}
+{{- end}}`
+
+const noticeText = `{{define "content" -}}
+{{.Body}}
+{{- if .ActionURL}}
+
+{{.Action}}: {{.ActionURL}}
+{{- end}}
+{{- end}}`
@@ -0,0 +1,217 @@
+package mailer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+const (
+	DefaultConcurrency  = 4
+	DefaultBatchSize    = 100
+	DefaultPollInterval = 1 * time.Second
+	DefaultMinBackoff   = 30 * time.Second
+	DefaultMaxBackoff   = 1 * time.Hour
+	DefaultMaxAttempts  = 10
+	DefaultLease        = 5 * time.Minute
+)
+
+type (
+	// Job is a message in the mail queue.
+	Job struct {
+		ID        string
+		Key       string
+		Message   Message
+		Attempts  int
+		CreatedAt time.Time
+	}
+
+	queue interface {
+		// Claim returns the jobs that are due, and hides them from the
+		// other workers until the lease expires, so that the jobs of a
+		// crashed worker are retried.
+		Claim(ctx context.Context, limit int, lease time.Duration) ([]Job, error)
+		MarkSent(ctx context.Context, id string) (bool, error)
+		MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) (bool, error)
+		MarkDead(ctx context.Context, id, reason string) (bool, error)
+	}
+
+	sender interface {
+		Send(ctx context.Context, msg Message) error
+	}
+
+	WorkerOptions struct {
+		Queue  queue
+		Sender sender
+
+		// Concurrency is the number of messages sent at the same time.
+		Concurrency  int
+		BatchSize    int
+		PollInterval time.Duration
+		MinBackoff   time.Duration
+		MaxBackoff   time.Duration
+		Lease        time.Duration
+
+		// MaxAttempts is the number of attempts before the message is
+		// dead-lettered.
+		MaxAttempts int
+		Clock       passport.Clock
+	}
+
+	// Worker sends the messages in the mail queue, and retries the failed
+	// ones with exponential backoff. Messages that fail permanently, or
+	// for MaxAttempts, are dead-lettered.
+	Worker struct {
+		options WorkerOptions
+	}
+)
+
+// Run polls the queue until the context is cancelled.
+func (w *Worker) Run(ctx context.Context) error {
+	t := time.NewTicker(w.options.PollInterval)
+	defer t.Stop()
+
+	for {
+		// When the queue is unavailable, the batch is retried on
+		// the next tick.
+		w.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce sends a single batch of messages, and returns the number of
+// messages sent successfully.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	jobs, err := w.options.Queue.Claim(ctx, w.options.BatchSize, w.options.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		sent     int
+		firstErr error
+		sem      = make(chan struct{}, w.options.Concurrency)
+	)
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(job Job) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			ok, err := w.send(ctx, job)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				sent++
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(job)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return sent, firstErr
+}
+
+// send sends the job and records the result. The error is only returned
+// when the result cannot be recorded.
+func (w *Worker) send(ctx context.Context, job Job) (bool, error) {
+	err := w.options.Sender.Send(ctx, job.Message)
+	if err == nil {
+		_, err := w.options.Queue.MarkSent(ctx, job.ID)
+		return err == nil, err
+	}
+
+	if IsPermanent(err) || job.Attempts+1 >= w.options.MaxAttempts {
+		_, err = w.options.Queue.MarkDead(ctx, job.ID, err.Error())
+		return false, err
+	}
+	retryAt := w.options.Clock.Now().Add(w.backoff(job.Attempts))
+	_, err = w.options.Queue.MarkFailed(ctx, job.ID, err.Error(), retryAt)
+	return false, err
+}
+
+// backoff returns the exponential delay before the next attempt.
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.options.MinBackoff
+	for i := 0; i < attempts; i++ {
+		d *= 2
+		if d >= w.options.MaxBackoff {
+			return w.options.MaxBackoff
+		}
+	}
+	return d
+}
+
+// IsPermanent checks if the message cannot be sent by retrying, e.g. when
+// the message is invalid or the server rejects the recipient.
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrRecipientRequired) ||
+		errors.Is(err, ErrBodyRequired) ||
+		errors.Is(err, ErrHeaderInvalid) {
+		return true
+	}
+
+	// 5xx replies are permanent, while 4xx replies are transient.
+	var te *textproto.Error
+	return errors.As(err, &te) && te.Code >= 500 && te.Code < 600
+}
+
+// IdempotencyKey returns the key that identifies the message, e.g. of the
+// mail type and the token, so that the same mail is only queued once.
+func IdempotencyKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewWorker(options WorkerOptions) *Worker {
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultPollInterval
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = DefaultMinBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = DefaultMaxBackoff
+	}
+	if options.Lease <= 0 {
+		options.Lease = DefaultLease
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	return &Worker{options}
+}
@@ -0,0 +1,153 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerSent(t *testing.T) {
+	assert := assert.New(t)
+	queue := &mockQueue{
+		claimResponse: []mailer.Job{
+			{ID: "1", Message: newMessage("john.doe@mail.com")},
+			{ID: "2", Message: newMessage("jane.doe@mail.com")},
+		},
+	}
+	var mu sync.Mutex
+	var to []string
+	worker := mailer.NewWorker(mailer.WorkerOptions{
+		Queue: queue,
+		Sender: senderFunc(func(ctx context.Context, msg mailer.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			to = append(to, msg.To...)
+			return nil
+		}),
+	})
+
+	n, err := worker.RunOnce(context.TODO())
+	assert.Nil(err)
+	assert.Equal(2, n)
+	sort.Strings(queue.sent)
+	assert.Equal([]string{"1", "2"}, queue.sent)
+	sort.Strings(to)
+	assert.Equal([]string{"jane.doe@mail.com", "john.doe@mail.com"}, to)
+}
+
+func TestWorkerFailed(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		failed   []string
+		dead     []string
+	}{
+		{"transient", 2, errors.New("connection reset"), []string{"1"}, nil},
+		{"transient reply", 0, &textproto.Error{Code: 421, Msg: "try again later"}, []string{"1"}, nil},
+		{"permanent reply", 0, &textproto.Error{Code: 550, Msg: "mailbox unavailable"}, nil, []string{"1"}},
+		{"invalid message", 0, mailer.ErrRecipientRequired, nil, []string{"1"}},
+		{"max attempts", 4, errors.New("connection reset"), nil, []string{"1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			queue := &mockQueue{
+				claimResponse: []mailer.Job{{ID: "1", Attempts: tt.attempts}},
+			}
+			worker := mailer.NewWorker(mailer.WorkerOptions{
+				Queue: queue,
+				Sender: senderFunc(func(ctx context.Context, msg mailer.Message) error {
+					return tt.err
+				}),
+				MinBackoff:  time.Minute,
+				MaxAttempts: 5,
+				Clock:       passport.NewFakeClock(now),
+			})
+
+			n, err := worker.RunOnce(context.TODO())
+			assert.Nil(err)
+			assert.Equal(0, n)
+			assert.Nil(queue.sent)
+			assert.Equal(tt.failed, queue.failed)
+			assert.Equal(tt.dead, queue.dead)
+			assert.Equal(tt.err.Error(), queue.reason)
+			if tt.failed != nil {
+				assert.Equal(now.Add(time.Minute<<uint(tt.attempts)), queue.retryAt)
+			}
+		})
+	}
+}
+
+func TestWorkerQueueError(t *testing.T) {
+	assert := assert.New(t)
+	worker := mailer.NewWorker(mailer.WorkerOptions{
+		Queue: &mockQueue{claimError: errors.New("bad queue")},
+	})
+	n, err := worker.RunOnce(context.TODO())
+	assert.Equal(0, n)
+	assert.Equal("bad queue", err.Error())
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	assert := assert.New(t)
+	key := mailer.IdempotencyKey(passport.EventUserRegistered, "abc")
+	assert.Equal(key, mailer.IdempotencyKey(passport.EventUserRegistered, "abc"))
+	assert.NotEqual(key, mailer.IdempotencyKey(passport.EventConfirmationRequested, "abc"))
+	assert.NotEqual(mailer.IdempotencyKey("ab", "c"), mailer.IdempotencyKey("a", "bc"))
+}
+
+type senderFunc func(ctx context.Context, msg mailer.Message) error
+
+func (fn senderFunc) Send(ctx context.Context, msg mailer.Message) error {
+	return fn(ctx, msg)
+}
+
+type mockQueue struct {
+	mu            sync.Mutex
+	claimResponse []mailer.Job
+	claimError    error
+	sent          []string
+	failed        []string
+	dead          []string
+	reason        string
+	retryAt       time.Time
+}
+
+func (m *mockQueue) Claim(ctx context.Context, limit int, lease time.Duration) ([]mailer.Job, error) {
+	return m.claimResponse, m.claimError
+}
+
+func (m *mockQueue) MarkSent(ctx context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, id)
+	return true, nil
+}
+
+func (m *mockQueue) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed = append(m.failed, id)
+	m.reason = reason
+	m.retryAt = retryAt
+	return true, nil
+}
+
+func (m *mockQueue) MarkDead(ctx context.Context, id, reason string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dead = append(m.dead, id)
+	m.reason = reason
+	return true, nil
+}
@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = 1 * time.Second
+	DefaultMinBackoff   = 1 * time.Second
+	DefaultMaxBackoff   = 1 * time.Hour
+	DefaultMaxAttempts  = 10
+	DefaultLease        = 5 * time.Minute
+)
+
+type (
+	store interface {
+		// Claim returns the events that are due, and hides them from
+		// the other relays until the lease expires, so that the events
+		// of a crashed relay are retried.
+		Claim(ctx context.Context, limit int, lease time.Duration) ([]passport.Event, error)
+		MarkDelivered(ctx context.Context, id string) (bool, error)
+		MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) (bool, error)
+		MarkDead(ctx context.Context, id, reason string) (bool, error)
+	}
+
+	RelayOptions struct {
+		Store        store
+		Sink         Sink
+		BatchSize    int
+		PollInterval time.Duration
+		MinBackoff   time.Duration
+		MaxBackoff   time.Duration
+		Lease        time.Duration
+
+		// MaxAttempts is the number of attempts before the event is
+		// dead-lettered.
+		MaxAttempts int
+		Clock       passport.Clock
+	}
+
+	// Relay delivers the pending events in the outbox to the sink. An
+	// event is only marked as delivered after the sink accepts it, hence
+	// the sink may receive the same event more than once. Events that
+	// fail for MaxAttempts are dead-lettered.
+	Relay struct {
+		options RelayOptions
+	}
+)
+
+// Run polls the outbox until the context is cancelled.
+func (r *Relay) Run(ctx context.Context) error {
+	t := time.NewTicker(r.options.PollInterval)
+	defer t.Stop()
+
+	for {
+		// When the store is unavailable, the batch is retried on
+		// the next tick.
+		r.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce delivers a single batch of pending events, and returns the number
+// of events delivered successfully.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	events, err := r.options.Store.Claim(ctx, r.options.BatchSize, r.options.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var delivered int
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+
+		if err := r.options.Sink.Deliver(ctx, event); err != nil {
+			if err := r.fail(ctx, event, err); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		if _, err := r.options.Store.MarkDelivered(ctx, event.ID); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// fail records the failed delivery, and dead-letters the event after
+// MaxAttempts.
+func (r *Relay) fail(ctx context.Context, event passport.Event, err error) error {
+	if event.Attempts+1 >= r.options.MaxAttempts {
+		_, err = r.options.Store.MarkDead(ctx, event.ID, err.Error())
+		return err
+	}
+	retryAt := r.options.Clock.Now().Add(r.backoff(event.Attempts))
+	_, err = r.options.Store.MarkFailed(ctx, event.ID, err.Error(), retryAt)
+	return err
+}
+
+// backoff returns the exponential delay before the next attempt.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.options.MinBackoff
+	for i := 0; i < attempts; i++ {
+		d *= 2
+		if d >= r.options.MaxBackoff {
+			return r.options.MaxBackoff
+		}
+	}
+	return d
+}
+
+func NewRelay(options RelayOptions) *Relay {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultPollInterval
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = DefaultMinBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = DefaultMaxBackoff
+	}
+	if options.Lease <= 0 {
+		options.Lease = DefaultLease
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	return &Relay{options}
+}
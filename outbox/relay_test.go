@@ -0,0 +1,130 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/outbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayDelivered(t *testing.T) {
+	assert := assert.New(t)
+	store := &mockStore{
+		pendingResponse: []passport.Event{
+			{ID: "1", Type: passport.EventUserRegistered},
+			{ID: "2", Type: passport.EventConfirmationRequested},
+		},
+	}
+	ch := make(chan passport.Event, 2)
+	relay := outbox.NewRelay(outbox.RelayOptions{
+		Store: store,
+		Sink:  outbox.ChannelSink(ch),
+	})
+
+	n, err := relay.RunOnce(context.TODO())
+	assert.Nil(err)
+	assert.Equal(2, n)
+	assert.Equal([]string{"1", "2"}, store.delivered)
+	assert.Equal(outbox.DefaultLease, store.lease)
+	assert.Equal("1", (<-ch).ID)
+	assert.Equal("2", (<-ch).ID)
+}
+
+func TestRelayFailed(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &mockStore{
+		pendingResponse: []passport.Event{
+			{ID: "1", Type: passport.EventUserRegistered, Attempts: 2},
+		},
+	}
+	relay := outbox.NewRelay(outbox.RelayOptions{
+		Store: store,
+		Sink: outbox.SinkFunc(func(ctx context.Context, event passport.Event) error {
+			return errors.New("bad sink")
+		}),
+		MinBackoff: 1 * time.Minute,
+		Clock:      passport.NewFakeClock(now),
+	})
+
+	n, err := relay.RunOnce(context.TODO())
+	assert.Nil(err)
+	assert.Equal(0, n)
+	assert.Nil(store.delivered)
+	assert.Equal([]string{"1"}, store.failed)
+	assert.Equal("bad sink", store.reason)
+	assert.Equal(now.Add(4*time.Minute), store.retryAt)
+	assert.Nil(store.dead)
+}
+
+func TestRelayDead(t *testing.T) {
+	assert := assert.New(t)
+	store := &mockStore{
+		pendingResponse: []passport.Event{
+			{ID: "1", Type: passport.EventUserRegistered, Attempts: 2},
+		},
+	}
+	relay := outbox.NewRelay(outbox.RelayOptions{
+		Store: store,
+		Sink: outbox.SinkFunc(func(ctx context.Context, event passport.Event) error {
+			return errors.New("bad sink")
+		}),
+		MaxAttempts: 3,
+	})
+
+	n, err := relay.RunOnce(context.TODO())
+	assert.Nil(err)
+	assert.Equal(0, n)
+	assert.Nil(store.failed)
+	assert.Equal([]string{"1"}, store.dead)
+	assert.Equal("bad sink", store.reason)
+}
+
+func TestRelayStoreError(t *testing.T) {
+	assert := assert.New(t)
+	relay := outbox.NewRelay(outbox.RelayOptions{
+		Store: &mockStore{pendingError: errors.New("bad store")},
+	})
+	n, err := relay.RunOnce(context.TODO())
+	assert.Equal(0, n)
+	assert.Equal("bad store", err.Error())
+}
+
+type mockStore struct {
+	pendingResponse []passport.Event
+	pendingError    error
+	lease           time.Duration
+	delivered       []string
+	failed          []string
+	dead            []string
+	reason          string
+	retryAt         time.Time
+}
+
+func (m *mockStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]passport.Event, error) {
+	m.lease = lease
+	return m.pendingResponse, m.pendingError
+}
+
+func (m *mockStore) MarkDelivered(ctx context.Context, id string) (bool, error) {
+	m.delivered = append(m.delivered, id)
+	return true, nil
+}
+
+func (m *mockStore) MarkDead(ctx context.Context, id, reason string) (bool, error) {
+	m.dead = append(m.dead, id)
+	m.reason = reason
+	return true, nil
+}
+
+func (m *mockStore) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) (bool, error) {
+	m.failed = append(m.failed, id)
+	m.reason = reason
+	m.retryAt = retryAt
+	return true, nil
+}
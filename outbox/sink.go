@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// Sink receives the events relayed from the outbox. Since delivery is
+// at-least-once, implementations should be idempotent, e.g. by
+// deduplicating on the event id.
+type Sink interface {
+	Deliver(ctx context.Context, event passport.Event) error
+}
+
+// SinkFunc allows ordinary functions, such as a mailer, to be used as Sink.
+type SinkFunc func(ctx context.Context, event passport.Event) error
+
+func (fn SinkFunc) Deliver(ctx context.Context, event passport.Event) error {
+	return fn(ctx, event)
+}
+
+// ChannelSink forwards the events to a channel.
+type ChannelSink chan<- passport.Event
+
+func (ch ChannelSink) Deliver(ctx context.Context, event passport.Event) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case ch <- event:
+		return nil
+	}
+}
+
+// WebhookSink posts the events as json to the given url. Any non-2xx
+// response is treated as a failed delivery.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (w *WebhookSink) Deliver(ctx context.Context, event passport.Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+	req.Header.Set("Idempotency-Key", event.ID)
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookSink{url, client}
+}
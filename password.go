@@ -1,16 +1,12 @@
 package passport
 
-import (
-	"errors"
-)
-
 var (
-	ErrPasswordChangeNotAllowed = errors.New("password change not allowed")
-	ErrPasswordDoNotMatch       = errors.New("password do not match")
-	ErrPasswordRequired         = errors.New("password required")
-	ErrPasswordTooShort         = errors.New("password too short")
-	ErrPasswordUsed             = errors.New("password cannot be reused")
-	ErrPasswordInvalid          = errors.New("password invalid")
+	ErrPasswordChangeNotAllowed = NewError("password_change_not_allowed", "password change not allowed", CategoryForbidden)
+	ErrPasswordDoNotMatch       = NewError("password_do_not_match", "password do not match", CategoryValidation)
+	ErrPasswordRequired         = NewError("password_required", "password required", CategoryValidation)
+	ErrPasswordTooShort         = NewError("password_too_short", "password too short", CategoryValidation)
+	ErrPasswordUsed             = NewError("password_used", "password cannot be reused", CategoryValidation)
+	ErrPasswordInvalid          = NewError("password_invalid", "password invalid", CategoryUnauthorized)
 )
 
 const PasswordMinLen = 8
@@ -34,6 +30,16 @@ func (p Password) Validate() error {
 	return nil
 }
 
+// ValidateField adds the validation error of the password to errs, along
+// with the min length when the password is too short.
+func (p Password) ValidateField(field string, errs ValidationErrors) ValidationErrors {
+	err := p.Validate()
+	if err == ErrPasswordTooShort {
+		return errs.Add(field, err, map[string]interface{}{"min": p.minLen})
+	}
+	return errs.Add(field, err, nil)
+}
+
 func (p Password) Value() string {
 	return p.password
 }
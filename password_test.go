@@ -20,4 +20,12 @@ func TestPassword(t *testing.T) {
 		assert.Equal("", pwd.Value())
 		assert.Nil(pwd.Equal(pwd))
 	})
+
+	t.Run("when password is too short", func(t *testing.T) {
+		err := passport.NewPassword("12345", passport.MinLen(10)).Validate()
+		assert.Equal(passport.ErrPasswordTooShort, err)
+
+		errs := passport.NewPassword("12345", passport.MinLen(10)).ValidateField("password", nil)
+		assert.Equal(map[string]interface{}{"min": 10}, errs[0].Params)
+	})
 }
@@ -0,0 +1,52 @@
+package passport
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrPhoneExists   = NewError("phone_exists", "phone exists", CategoryConflict)
+	ErrPhoneInvalid  = NewError("phone_invalid", "phone invalid", CategoryValidation)
+	ErrPhoneRequired = NewError("phone_required", "phone required", CategoryValidation)
+)
+
+// phoneRegex matches the E.164 format, a + followed by the country code and
+// the subscriber number, up to 15 digits.
+var phoneRegex = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// phoneReplacer removes the separators commonly used to format phone
+// numbers.
+var phoneReplacer = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// Phone is the phone number in E.164 format, e.g. +60123456789.
+type Phone string
+
+func (p Phone) Valid() bool {
+	return phoneRegex.MatchString(p.Value())
+}
+
+func (p Phone) Validate() error {
+	if p.Value() == "" {
+		return ErrPhoneRequired
+	}
+	if !p.Valid() {
+		return ErrPhoneInvalid
+	}
+	return nil
+}
+
+func (p Phone) String() string {
+	return string(p)
+}
+
+func (p Phone) Value() string {
+	return string(p)
+}
+
+// NewPhone returns the phone number without the spaces, dashes, dots and
+// parentheses, e.g. "+60 12-345 6789" becomes "+60123456789". The country
+// code is not inferred, so numbers without a leading + are invalid.
+func NewPhone(phone string) Phone {
+	return Phone(phoneReplacer.Replace(strings.TrimSpace(phone)))
+}
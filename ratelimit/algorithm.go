@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// SlidingWindow allows Limit requests in any Window. The requests of the
+// previous window are weighted by its overlap with the sliding window, so
+// that only two counters are kept for each key.
+type SlidingWindow struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (w SlidingWindow) Take(s State, now time.Time) (State, Result) {
+	start := now.Truncate(w.Window)
+	if !s.Since.Equal(start) {
+		if s.Since.Equal(start.Add(-w.Window)) {
+			s.Previous = s.Count
+		} else {
+			s.Previous = 0
+		}
+		s.Count = 0
+		s.Since = start
+	}
+
+	limit := float64(w.Limit)
+	elapsed := float64(now.Sub(start)) / float64(w.Window)
+	used := s.Previous*(1-elapsed) + s.Count
+	if used+1 > limit {
+		// The previous requests are weighted down over time, until
+		// the request fits. When the current window is full, it
+		// becomes the previous window first.
+		since, previous, count := start, s.Previous, s.Count
+		if count+1 > limit {
+			since, previous, count = start.Add(w.Window), count, 0
+		}
+		var overlap float64
+		if previous > 0 {
+			overlap = 1 - (limit-count-1)/previous
+		}
+		retryAt := since.Add(time.Duration(math.Round(overlap * float64(w.Window))))
+		return s, Result{RetryAfter: retryAt.Sub(now)}
+	}
+
+	s.Count++
+	return s, Result{
+		Allowed:   true,
+		Remaining: int(math.Floor(limit - used - 1)),
+	}
+}
+
+func (w SlidingWindow) TTL() time.Duration {
+	return 2 * w.Window
+}
+
+// TokenBucket allows bursts of up to Burst requests, and adds a token to
+// the bucket every Interval.
+type TokenBucket struct {
+	Burst    int
+	Interval time.Duration
+}
+
+func (b TokenBucket) Take(s State, now time.Time) (State, Result) {
+	burst := float64(b.Burst)
+	if s.Since.IsZero() {
+		s.Count = burst
+	} else if elapsed := now.Sub(s.Since); elapsed > 0 {
+		s.Count = math.Min(burst, s.Count+float64(elapsed)/float64(b.Interval))
+	}
+	s.Since = now
+
+	if s.Count < 1 {
+		return s, Result{
+			RetryAfter: time.Duration((1 - s.Count) * float64(b.Interval)),
+		}
+	}
+
+	s.Count--
+	return s, Result{
+		Allowed:   true,
+		Remaining: int(s.Count),
+	}
+}
+
+// TTL is the time to refill the bucket, after which the state is the same
+// as a new key.
+func (b TokenBucket) TTL() time.Duration {
+	return time.Duration(b.Burst) * b.Interval
+}
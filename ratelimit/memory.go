@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// sweepInterval is how often the expired keys are removed.
+const sweepInterval = time.Minute
+
+type memoryState struct {
+	state     State
+	expiresAt time.Time
+}
+
+// MemoryStore is a Store for a single process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	states    map[string]memoryState
+	lastSweep time.Time
+	clock     passport.Clock
+}
+
+func (m *MemoryStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(State) State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for key, s := range m.states {
+			if !now.Before(s.expiresAt) {
+				delete(m.states, key)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	var state State
+	if s, ok := m.states[key]; ok && now.Before(s.expiresAt) {
+		state = s.state
+	}
+	m.states[key] = memoryState{
+		state:     fn(state),
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+// NewMemoryStore returns a new MemoryStore. The clock defaults to
+// SystemClock when nil.
+func NewMemoryStore(clock passport.Clock) *MemoryStore {
+	if clock == nil {
+		clock = passport.SystemClock{}
+	}
+	return &MemoryStore{
+		states: make(map[string]memoryState),
+		clock:  clock,
+	}
+}
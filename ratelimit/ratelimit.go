@@ -0,0 +1,82 @@
+// Package ratelimit limits the requests by key, e.g. by IP or email, with
+// the sliding window or the token bucket algorithm. The state of the keys is
+// kept in a Store, which is shared by all the instances when backed by a
+// database.
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	// State is the state of a key.
+	State struct {
+		// Count is the number of requests in the current window, or the
+		// tokens left in the bucket.
+		Count float64
+
+		// Previous is the number of requests in the previous window.
+		Previous float64
+
+		// Since is the start of the current window, or the time the
+		// bucket was last refilled. It is zero for new keys.
+		Since time.Time
+	}
+
+	// Result is the outcome of a request.
+	Result struct {
+		Allowed   bool
+		Remaining int
+
+		// RetryAfter is the time to wait before the next request is
+		// allowed, when the request is not allowed.
+		RetryAfter time.Duration
+	}
+
+	// Algorithm decides if a request is allowed given the state of the
+	// key.
+	Algorithm interface {
+		Take(state State, now time.Time) (State, Result)
+
+		// TTL is how long the state is kept after the last request.
+		TTL() time.Duration
+	}
+
+	// Store keeps the state of the keys.
+	Store interface {
+		// Update replaces the state of the key with the state returned
+		// by fn atomically. The state is zero for new and expired keys.
+		Update(ctx context.Context, key string, ttl time.Duration, fn func(State) State) error
+	}
+
+	Options struct {
+		Store     Store
+		Algorithm Algorithm
+		Clock     passport.Clock
+	}
+
+	// Limiter limits the requests of each key.
+	Limiter struct {
+		options Options
+	}
+)
+
+// Allow takes a request for the key.
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	var res Result
+	err := l.options.Store.Update(ctx, key, l.options.Algorithm.TTL(), func(s State) State {
+		s, res = l.options.Algorithm.Take(s, l.options.Clock.Now())
+		return s
+	})
+	return res, err
+}
+
+func New(options Options) *Limiter {
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	return &Limiter{options}
+}
@@ -0,0 +1,95 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSlidingWindow(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(now)
+	limiter := ratelimit.New(ratelimit.Options{
+		Store:     ratelimit.NewMemoryStore(clock),
+		Algorithm: ratelimit.SlidingWindow{Limit: 3, Window: time.Minute},
+		Clock:     clock,
+	})
+	ctx := context.TODO()
+
+	for i := 2; i >= 0; i-- {
+		res, err := limiter.Allow(ctx, "1.2.3.4")
+		assert.Nil(err)
+		assert.True(res.Allowed)
+		assert.Equal(i, res.Remaining)
+	}
+
+	// The current window is full, and is weighted down in the next
+	// window until a request fits.
+	res, err := limiter.Allow(ctx, "1.2.3.4")
+	assert.Nil(err)
+	assert.False(res.Allowed)
+	assert.Equal(80*time.Second, res.RetryAfter)
+
+	// Other keys are not limited.
+	res, err = limiter.Allow(ctx, "5.6.7.8")
+	assert.Nil(err)
+	assert.True(res.Allowed)
+
+	clock.Advance(80 * time.Second)
+	res, err = limiter.Allow(ctx, "1.2.3.4")
+	assert.Nil(err)
+	assert.True(res.Allowed)
+
+	res, err = limiter.Allow(ctx, "1.2.3.4")
+	assert.Nil(err)
+	assert.False(res.Allowed)
+	assert.Equal(20*time.Second, res.RetryAfter)
+
+	// The state expires after two windows.
+	clock.Advance(2 * time.Minute)
+	res, err = limiter.Allow(ctx, "1.2.3.4")
+	assert.Nil(err)
+	assert.Equal(2, res.Remaining)
+}
+
+func TestTokenBucket(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(now)
+	limiter := ratelimit.New(ratelimit.Options{
+		Store:     ratelimit.NewMemoryStore(clock),
+		Algorithm: ratelimit.TokenBucket{Burst: 2, Interval: 10 * time.Second},
+		Clock:     clock,
+	})
+	ctx := context.TODO()
+
+	for i := 1; i >= 0; i-- {
+		res, err := limiter.Allow(ctx, "john.doe@mail.com")
+		assert.Nil(err)
+		assert.True(res.Allowed)
+		assert.Equal(i, res.Remaining)
+	}
+
+	res, err := limiter.Allow(ctx, "john.doe@mail.com")
+	assert.Nil(err)
+	assert.False(res.Allowed)
+	assert.Equal(10*time.Second, res.RetryAfter)
+
+	clock.Advance(5 * time.Second)
+	res, err = limiter.Allow(ctx, "john.doe@mail.com")
+	assert.Nil(err)
+	assert.False(res.Allowed)
+	assert.Equal(5*time.Second, res.RetryAfter)
+
+	clock.Advance(5 * time.Second)
+	res, err = limiter.Allow(ctx, "john.doe@mail.com")
+	assert.Nil(err)
+	assert.True(res.Allowed)
+	assert.Equal(0, res.Remaining)
+}
@@ -13,15 +13,31 @@ type Recoverable struct {
 	ResetPasswordToken  string    `json:"reset_password_token,omitempty"`
 	ResetPasswordSentAt time.Time `json:"reset_password_sent_at,omitempty"`
 	AllowPasswordChange bool      `json:"allow_password_change,omitempty"`
+
+	// ResetPasswordSentCount is the number of tokens sent on the day of
+	// ResetPasswordSentAt, for throttling.
+	ResetPasswordSentCount int `json:"reset_password_sent_count,omitempty"`
 }
 
 // Valid checks if the reset password token is within the validity period.
 func (r Recoverable) Valid(ttl time.Duration) bool {
-	return time.Since(r.ResetPasswordSentAt) < ttl
+	return r.ValidAt(time.Now(), ttl)
+}
+
+// ValidAt is like Valid, except that the validity is checked against the
+// given time.
+func (r Recoverable) ValidAt(now time.Time, ttl time.Duration) bool {
+	return now.Sub(r.ResetPasswordSentAt) < ttl
 }
 
 func (r Recoverable) ValidateExpiry(ttl time.Duration) error {
-	if valid := r.Valid(ttl); !valid {
+	return r.ValidateExpiryAt(time.Now(), ttl)
+}
+
+// ValidateExpiryAt is like ValidateExpiry, except that the expiry is checked
+// against the given time.
+func (r Recoverable) ValidateExpiryAt(now time.Time, ttl time.Duration) error {
+	if valid := r.ValidAt(now, ttl); !valid {
 		return ErrTokenExpired
 	}
 	return nil
@@ -29,12 +45,17 @@ func (r Recoverable) ValidateExpiry(ttl time.Duration) error {
 
 // NewRecoverable returns a new Recoverable.
 func NewRecoverable(token string) Recoverable {
+	return NewRecoverableAt(token, time.Now())
+}
+
+// NewRecoverableAt returns a new Recoverable sent at the given time.
+func NewRecoverableAt(token string, sentAt time.Time) Recoverable {
 	return Recoverable{
 		// Instead of using the Postgres UUID, we set it here.
 		// This allows us to change the implementation at the
 		// application level.
 		ResetPasswordToken:  token,
-		ResetPasswordSentAt: time.Now(),
+		ResetPasswordSentAt: sentAt,
 		AllowPasswordChange: true,
 	}
 }
@@ -0,0 +1,54 @@
+package passport
+
+import (
+	"time"
+)
+
+// ErrEmailChangeNotFound indicates that there is no email change to cancel.
+var ErrEmailChangeNotFound = NewError("email_change_not_found", "email change not found", CategoryNotFound)
+
+// RevertEmailTokenValidity represents the duration the revert email token is
+// valid. It is longer than the confirmation token, since the previous email
+// may not be checked as often.
+const RevertEmailTokenValidity = 7 * 24 * time.Hour
+
+// Revertable holds the data to revert an email change from the previous
+// email, in case the change was not made by the owner.
+type Revertable struct {
+	PreviousEmail     string    `json:"previous_email,omitempty"`
+	RevertEmailToken  string    `json:"revert_email_token,omitempty"`
+	RevertEmailSentAt time.Time `json:"revert_email_sent_at,omitempty"`
+}
+
+// ValidAt checks if the revert email token is within the validity period at
+// the given time.
+func (r Revertable) ValidAt(now time.Time, ttl time.Duration) bool {
+	return r.RevertEmailToken != "" && now.Sub(r.RevertEmailSentAt) < ttl
+}
+
+// ValidateExpiryAt returns an error indicating the token has expired at the
+// given time.
+func (r Revertable) ValidateExpiryAt(now time.Time, ttl time.Duration) error {
+	if valid := r.ValidAt(now, ttl); !valid {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// EmailChange holds the tokens of a requested email change. The confirmation
+// token is sent to the new email, and the revert email token to the previous
+// email.
+type EmailChange struct {
+	ConfirmationToken string
+	PreviousEmail     string
+	RevertEmailToken  string
+}
+
+// NewRevertableAt returns a new Revertable sent at the given time.
+func NewRevertableAt(token, previousEmail string, sentAt time.Time) Revertable {
+	return Revertable{
+		PreviousEmail:     previousEmail,
+		RevertEmailToken:  token,
+		RevertEmailSentAt: sentAt,
+	}
+}
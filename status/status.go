@@ -0,0 +1,77 @@
+// Package status maps the passport errors to the HTTP status and gRPC codes,
+// so that the clients get consistent responses from both transports.
+package status
+
+import (
+	"net/http"
+
+	"github.com/alextanhongpin/passport"
+)
+
+// Code is a gRPC status code. The values are the same as
+// google.golang.org/grpc/codes, and can be converted with codes.Code(c).
+type Code uint32
+
+const (
+	OK                 Code = 0
+	InvalidArgument    Code = 3
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	Internal           Code = 13
+	Unavailable        Code = 14
+	Unauthenticated    Code = 16
+)
+
+var httpStatuses = map[passport.Category]int{
+	passport.CategoryValidation:      http.StatusBadRequest,
+	passport.CategoryNotFound:        http.StatusNotFound,
+	passport.CategoryConflict:        http.StatusConflict,
+	passport.CategoryAborted:         http.StatusConflict,
+	passport.CategoryUnauthorized:    http.StatusUnauthorized,
+	passport.CategoryForbidden:       http.StatusForbidden,
+	passport.CategoryLocked:          http.StatusLocked,
+	passport.CategoryInternal:        http.StatusInternalServerError,
+	passport.CategoryTooManyRequests: http.StatusTooManyRequests,
+	passport.CategoryUnavailable:     http.StatusServiceUnavailable,
+}
+
+var grpcCodes = map[passport.Category]Code{
+	passport.CategoryValidation:      InvalidArgument,
+	passport.CategoryNotFound:        NotFound,
+	passport.CategoryConflict:        AlreadyExists,
+	passport.CategoryAborted:         Aborted,
+	passport.CategoryUnauthorized:    Unauthenticated,
+	passport.CategoryForbidden:       PermissionDenied,
+	passport.CategoryLocked:          FailedPrecondition,
+	passport.CategoryInternal:        Internal,
+	passport.CategoryTooManyRequests: ResourceExhausted,
+	passport.CategoryUnavailable:     Unavailable,
+}
+
+// HTTP returns the HTTP status code of the error. Errors that are not a
+// passport.Error are internal server errors.
+func HTTP(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if code, ok := httpStatuses[passport.AsError(err).Category]; ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPC returns the gRPC status code of the error. Errors that are not a
+// passport.Error are internal errors.
+func GRPC(err error) Code {
+	if err == nil {
+		return OK
+	}
+	if code, ok := grpcCodes[passport.AsError(err).Category]; ok {
+		return code
+	}
+	return Internal
+}
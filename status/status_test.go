@@ -0,0 +1,61 @@
+package status_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/httpapi"
+	"github.com/alextanhongpin/passport/issuer"
+	"github.com/alextanhongpin/passport/mailer"
+	"github.com/alextanhongpin/passport/status"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		http int
+		grpc status.Code
+	}{
+		{nil, http.StatusOK, status.OK},
+		{passport.ErrEmailRequired, http.StatusBadRequest, status.InvalidArgument},
+		{passport.ErrUserNotFound, http.StatusNotFound, status.NotFound},
+		{passport.ErrEmailExists, http.StatusConflict, status.AlreadyExists},
+		{passport.ErrConcurrentModification, http.StatusConflict, status.Aborted},
+		{issuer.ErrKeySetUnavailable, http.StatusServiceUnavailable, status.Unavailable},
+		{passport.ErrEmailOrPasswordInvalid, http.StatusUnauthorized, status.Unauthenticated},
+		{passport.ErrConfirmationRequired, http.StatusForbidden, status.PermissionDenied},
+		{passport.NewError("locked", "locked", passport.CategoryLocked), http.StatusLocked, status.FailedPrecondition},
+		{passport.ErrTooManyRequests, http.StatusTooManyRequests, status.ResourceExhausted},
+		{passport.RetryAfterError{Err: passport.ErrTooManyRequests, RetryAfter: time.Minute}, http.StatusTooManyRequests, status.ResourceExhausted},
+		{fmt.Errorf("find user: %w", passport.ErrUserNotFound), http.StatusNotFound, status.NotFound},
+		{errors.New("connection refused"), http.StatusInternalServerError, status.Internal},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.http, status.HTTP(tt.err), fmt.Sprint(tt.err))
+		assert.Equal(t, tt.grpc, status.GRPC(tt.err), fmt.Sprint(tt.err))
+	}
+}
+
+// Registers the errors of the other packages.
+var (
+	_ = httpapi.ErrBadRequest
+	_ = mailer.ErrRecipientRequired
+)
+
+// TestStatusCategories checks that only the internal errors are mapped to
+// the internal status.
+func TestStatusCategories(t *testing.T) {
+	for _, e := range passport.Errors() {
+		if e.Category == passport.CategoryInternal {
+			continue
+		}
+		assert.NotEqual(t, http.StatusInternalServerError, status.HTTP(e), e.Code)
+		assert.NotEqual(t, status.Internal, status.GRPC(e), e.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package passport
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTooManyRequests indicates that the request is throttled. It is
+// returned wrapped in a RetryAfterError.
+var ErrTooManyRequests = NewError("too_many_requests", "too many requests", CategoryTooManyRequests)
+
+// Default limits of the tokens sent to the same email.
+const (
+	DefaultCooldown   = 1 * time.Minute
+	DefaultDailyLimit = 5
+)
+
+// RetryAfterError is an error that can be retried after a duration.
+type RetryAfterError struct {
+	Err        *Error
+	RetryAfter time.Duration
+}
+
+func (r RetryAfterError) Error() string {
+	return r.Err.Message
+}
+
+func (r RetryAfterError) Unwrap() error {
+	return r.Err
+}
+
+// RetryAfter returns the duration to wait before retrying the request that
+// failed with the error.
+func RetryAfter(err error) (time.Duration, bool) {
+	var r RetryAfterError
+	if errors.As(err, &r) {
+		return r.RetryAfter, true
+	}
+	return 0, false
+}
+
+// Throttle limits how often the confirmation and reset password tokens are
+// sent to the same email, so that the inbox of the user cannot be flooded.
+type Throttle struct {
+	// Cooldown is the minimum time between two tokens. A negative value
+	// means no cooldown.
+	Cooldown time.Duration
+
+	// DailyLimit is the maximum number of tokens sent per day, in UTC.
+	// A negative value means no limit.
+	DailyLimit int
+}
+
+// Allow checks if another token can be sent at now, given the time the last
+// token was sent and the number of tokens sent on that day. It returns the
+// number of tokens sent on the day of now, including the new token.
+func (t Throttle) Allow(sentAt time.Time, count int, now time.Time) (int, error) {
+	if sentAt.IsZero() {
+		return 1, nil
+	}
+	if next := sentAt.Add(t.Cooldown); now.Before(next) {
+		return count, RetryAfterError{ErrTooManyRequests, next.Sub(now)}
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	if sentAt.UTC().Before(today) {
+		count = 0
+	}
+	if t.DailyLimit > 0 && count >= t.DailyLimit {
+		tomorrow := today.Add(24 * time.Hour)
+		return count, RetryAfterError{ErrTooManyRequests, tomorrow.Sub(now)}
+	}
+	return count + 1, nil
+}
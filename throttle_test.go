@@ -0,0 +1,49 @@
+package passport_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleAllow(t *testing.T) {
+	now := time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC)
+	throttle := passport.Throttle{Cooldown: time.Minute, DailyLimit: 3}
+	tests := []struct {
+		name       string
+		sentAt     time.Time
+		count      int
+		next       int
+		retryAfter time.Duration
+	}{
+		{"never sent", time.Time{}, 0, 1, 0},
+		{"within cooldown", now.Add(-20 * time.Second), 1, 1, 40 * time.Second},
+		{"after cooldown", now.Add(-time.Minute), 1, 2, 0},
+		{"daily limit reached", now.Add(-time.Hour), 3, 3, 6 * time.Hour},
+		{"sent yesterday", now.Add(-20 * time.Hour), 3, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			next, err := throttle.Allow(tt.sentAt, tt.count, now)
+			assert.Equal(tt.next, next)
+
+			retryAfter, ok := passport.RetryAfter(err)
+			assert.Equal(tt.retryAfter, retryAfter)
+			assert.Equal(tt.retryAfter > 0, ok)
+			assert.Equal(tt.retryAfter > 0, errors.Is(err, passport.ErrTooManyRequests))
+		})
+	}
+}
+
+func TestThrottleDisabled(t *testing.T) {
+	now := time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC)
+	throttle := passport.Throttle{Cooldown: -1, DailyLimit: -1}
+	next, err := throttle.Allow(now, 100, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 101, next)
+}
@@ -1,14 +1,13 @@
 package passport
 
 import (
-	"errors"
 	"strings"
 )
 
 var (
-	ErrTokenExpired  = errors.New("token expired")
-	ErrTokenInvalid  = errors.New("token invalid")
-	ErrTokenRequired = errors.New("token required")
+	ErrTokenExpired  = NewError("token_expired", "token expired", CategoryValidation)
+	ErrTokenInvalid  = NewError("token_invalid", "token invalid", CategoryValidation)
+	ErrTokenRequired = NewError("token_required", "token required", CategoryValidation)
 )
 
 // Token represents the value object for token.
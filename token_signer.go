@@ -0,0 +1,115 @@
+package passport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Purposes of the tokens. A token signed for one purpose cannot be used for
+// another. The revert email token is always stored, and is never signed.
+const (
+	TokenPurposeConfirmation  = "confirmation"
+	TokenPurposeResetPassword = "reset_password"
+	TokenPurposeRevertEmail   = "revert_email"
+)
+
+// SignedTokenClaims are the claims encoded in a signed token.
+type SignedTokenClaims struct {
+	UserID      string `json:"sub"`
+	Purpose     string `json:"pur"`
+	ExpiresAt   int64  `json:"exp"`
+	Fingerprint string `json:"fpr"`
+}
+
+// Fingerprint returns a digest of the user's state that is changed by
+// using a token for the given purpose. Embedding it in a signed token makes
+// the token invalid once it has been used, without storing the token.
+// Locking the account also invalidates the reset password tokens, so that
+// the lock can only be cleared with a token signed after it.
+func (u *User) Fingerprint(purpose string) string {
+	var state []string
+	switch purpose {
+	case TokenPurposeConfirmation:
+		state = []string{u.ID, u.Email, u.UnconfirmedEmail}
+	case TokenPurposeResetPassword:
+		state = []string{u.ID, u.Email, u.EncryptedPassword.Value(), u.LockedAt.UTC().Format(time.RFC3339Nano)}
+	default:
+		state = []string{u.ID}
+	}
+	h := sha256.Sum256([]byte(purpose + "\x1f" + strings.Join(state, "\x1f")))
+	return hex.EncodeToString(h[:16])
+}
+
+// HMACTokenSigner signs stateless tokens with HMAC-SHA256, as an alternative
+// to storing tokens generated by UUIDTokenGenerator. The tokens carry the id
+// of the key they are signed with, so that the secrets can be rotated.
+type HMACTokenSigner struct {
+	keyring *Keyring
+}
+
+// Sign returns the token for the given claims, signed with the active key.
+func (h *HMACTokenSigner) Sign(claims SignedTokenClaims) (string, error) {
+	key, err := h.keyring.Active()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := key.ID + "." + base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + signature(key.Secret, payload), nil
+}
+
+// Verify checks the signature, purpose and expiry of the token, and returns
+// the claims. The caller is responsible for comparing the fingerprint.
+func (h *HMACTokenSigner) Verify(token, purpose string) (*SignedTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenInvalid
+	}
+	kid, payload, sig := parts[0], parts[1], parts[2]
+
+	// Tokens signed with unknown or retired keys are simply invalid.
+	key, err := h.keyring.Key(kid)
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(key.Secret, kid+"."+payload))) {
+		return nil, ErrTokenInvalid
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+	var claims SignedTokenClaims
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, ErrTokenInvalid
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrTokenInvalid
+	}
+	if !h.keyring.Clock().Now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrTokenExpired
+	}
+	return &claims, nil
+}
+
+func signature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewHMACTokenSigner returns a new HMACTokenSigner that signs with the
+// active key of the keyring.
+func NewHMACTokenSigner(keyring *Keyring) *HMACTokenSigner {
+	return &HMACTokenSigner{keyring}
+}
@@ -0,0 +1,75 @@
+package passport_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACTokenSigner(t *testing.T) {
+	var (
+		now    = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock  = passport.NewFakeClock(now)
+		signer = passport.NewHMACTokenSigner(passport.NewKeyring(clock, passport.Key{ID: "key_1", Secret: []byte("secret")}))
+		user   = &passport.User{ID: "1", Email: "john.doe@mail.com"}
+	)
+	claims := passport.SignedTokenClaims{
+		UserID:      user.ID,
+		Purpose:     passport.TokenPurposeResetPassword,
+		ExpiresAt:   now.Add(time.Hour).Unix(),
+		Fingerprint: user.Fingerprint(passport.TokenPurposeResetPassword),
+	}
+	token, err := signer.Sign(claims)
+	assert.Nil(t, err)
+
+	t.Run("when token is valid", func(t *testing.T) {
+		assert := assert.New(t)
+		got, err := signer.Verify(token, passport.TokenPurposeResetPassword)
+		assert.Nil(err)
+		assert.Equal(claims, *got)
+	})
+
+	t.Run("when purpose is different", func(t *testing.T) {
+		assert := assert.New(t)
+		got, err := signer.Verify(token, passport.TokenPurposeConfirmation)
+		assert.Nil(got)
+		assert.Equal(passport.ErrTokenInvalid, err)
+	})
+
+	t.Run("when signed with another secret", func(t *testing.T) {
+		assert := assert.New(t)
+		other := passport.NewHMACTokenSigner(passport.NewKeyring(clock, passport.Key{ID: "key_2", Secret: []byte("other")}))
+		got, err := other.Verify(token, passport.TokenPurposeResetPassword)
+		assert.Nil(got)
+		assert.Equal(passport.ErrTokenInvalid, err)
+	})
+
+	t.Run("when token expired", func(t *testing.T) {
+		assert := assert.New(t)
+		expired := passport.NewHMACTokenSigner(passport.NewKeyring(passport.NewFakeClock(now.Add(time.Hour)), passport.Key{ID: "key_1", Secret: []byte("secret")}))
+		got, err := expired.Verify(token, passport.TokenPurposeResetPassword)
+		assert.Nil(got)
+		assert.Equal(passport.ErrTokenExpired, err)
+	})
+}
+
+func TestUserFingerprint(t *testing.T) {
+	assert := assert.New(t)
+	user := &passport.User{
+		ID:                "1",
+		Email:             "john.doe@mail.com",
+		EncryptedPassword: passport.NewPassword("hash_1"),
+	}
+	before := user.Fingerprint(passport.TokenPurposeResetPassword)
+	assert.NotEqual(before, user.Fingerprint(passport.TokenPurposeConfirmation))
+
+	user.EncryptedPassword = passport.NewPassword("hash_2")
+	assert.NotEqual(before, user.Fingerprint(passport.TokenPurposeResetPassword))
+
+	// Locking the account invalidates the reset password tokens.
+	before = user.Fingerprint(passport.TokenPurposeResetPassword)
+	user.LockedAt = time.Date(2020, 4, 12, 0, 0, 0, 0, time.UTC)
+	assert.NotEqual(before, user.Fingerprint(passport.TokenPurposeResetPassword))
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	cancelEmailChangeRepository interface {
+		Find(ctx context.Context, id string) (*passport.User, error)
+		RestoreEmail(ctx context.Context, userID string, version int) (bool, error)
+	}
+
+	CancelEmailChangeOptions struct {
+		Repository cancelEmailChangeRepository
+		TxRunner   txRunner
+	}
+
+	// CancelEmailChange restores the previous email of a pending email
+	// change, and invalidates the confirmation and revert email tokens.
+	CancelEmailChange struct {
+		options CancelEmailChangeOptions
+	}
+)
+
+func (c *CancelEmailChange) Exec(ctx context.Context, currentUserID passport.UserID) error {
+	return runInTx(ctx, c.options.TxRunner, func(ctx context.Context) error {
+		return c.exec(ctx, currentUserID)
+	})
+}
+
+func (c *CancelEmailChange) exec(ctx context.Context, currentUserID passport.UserID) error {
+	if err := currentUserID.Validate(); err != nil {
+		return err
+	}
+
+	user, err := c.findUser(ctx, currentUserID)
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkEmailChangePending(user); err != nil {
+		return err
+	}
+
+	_, err = c.options.Repository.RestoreEmail(ctx, user.ID, user.Version)
+	return err
+}
+
+func (c *CancelEmailChange) findUser(ctx context.Context, userID passport.UserID) (*passport.User, error) {
+	user, err := c.options.Repository.Find(ctx, userID.Value())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkEmailChangePending only allows changes that have not been confirmed
+// to be cancelled. Confirmed changes can still be reverted from the previous
+// email.
+func (c *CancelEmailChange) checkEmailChangePending(user *passport.User) error {
+	if user.Confirmable.UnconfirmedEmail == "" || user.Revertable.PreviousEmail == "" {
+		return passport.ErrEmailChangeNotFound
+	}
+
+	return nil
+}
+
+func NewCancelEmailChange(options CancelEmailChangeOptions) *CancelEmailChange {
+	return &CancelEmailChange{options}
+}
@@ -0,0 +1,92 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancelEmailChangeValidation(t *testing.T) {
+	assert := assert.New(t)
+	err := cancelEmailChange(&mockCancelEmailChangeRepository{}, "")
+	assert.Equal(passport.ErrUserIDRequired, err)
+}
+
+func TestCancelEmailChangeNewUser(t *testing.T) {
+	assert := assert.New(t)
+	err := cancelEmailChange(&mockCancelEmailChangeRepository{
+		findError: sql.ErrNoRows,
+	}, "123456")
+	assert.Equal(passport.ErrUserNotFound, err)
+}
+
+func TestCancelEmailChangeNotPending(t *testing.T) {
+	tests := []struct {
+		name string
+		user *passport.User
+	}{
+		{"when email has not been changed", &passport.User{
+			Email: "john.doe@mail.com",
+		}},
+		{"when email change has been confirmed", &passport.User{
+			Email:      "jane.doe@mail.com",
+			Revertable: passport.NewRevertableAt("xyz", "john.doe@mail.com", fixedNow),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			repo := &mockCancelEmailChangeRepository{findResponse: tt.user}
+			err := cancelEmailChange(repo, "123456")
+			assert.Equal(passport.ErrEmailChangeNotFound, err)
+			assert.False(repo.restored)
+		})
+	}
+}
+
+func TestCancelEmailChangeSuccess(t *testing.T) {
+	assert := assert.New(t)
+	repo := &mockCancelEmailChangeRepository{
+		findResponse: &passport.User{
+			ID:          "123456",
+			Email:       "jane.doe@mail.com",
+			Version:     2,
+			Confirmable: passport.NewConfirmableAt("abc", "jane.doe@mail.com", fixedNow),
+			Revertable:  passport.NewRevertableAt("xyz", "john.doe@mail.com", fixedNow),
+		},
+	}
+	err := cancelEmailChange(repo, "123456")
+	assert.Nil(err)
+	assert.True(repo.restored)
+	assert.Equal(2, repo.version)
+}
+
+type mockCancelEmailChangeRepository struct {
+	findResponse *passport.User
+	findError    error
+	restoreError error
+	restored     bool
+	version      int
+}
+
+func (m *mockCancelEmailChangeRepository) Find(ctx context.Context, id string) (*passport.User, error) {
+	return m.findResponse, m.findError
+}
+
+func (m *mockCancelEmailChangeRepository) RestoreEmail(ctx context.Context, userID string, version int) (bool, error) {
+	m.restored = true
+	m.version = version
+	return m.restoreError == nil, m.restoreError
+}
+
+func cancelEmailChange(r *mockCancelEmailChangeRepository, userID string) error {
+	return usecase.NewCancelEmailChange(usecase.CancelEmailChangeOptions{
+		Repository: r,
+	}).Exec(context.TODO(), passport.UserID(userID))
+}
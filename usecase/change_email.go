@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/alextanhongpin/passport"
 )
@@ -12,12 +13,31 @@ type (
 	changeEmailRepository interface {
 		Find(ctx context.Context, id string) (*passport.User, error)
 		HasEmail(ctx context.Context, email string) (bool, error)
-		UpdateConfirmable(ctx context.Context, email string, confirmable passport.Confirmable) (bool, error)
+		UpdateConfirmable(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error)
+		UpdateRevertable(ctx context.Context, userID string, version int, revertable passport.Revertable) (bool, error)
+
+		// Used with signed tokens.
+		UpdateConfirmableSigned(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error)
 	}
 
 	ChangeEmailOptions struct {
 		Repository     changeEmailRepository
 		TokenGenerator tokenGenerator
+		Clock          passport.Clock
+
+		// TxRunner is required, so that the revert email token is not
+		// stored without the email change.
+		TxRunner txRunner
+
+		// TokenSigner is optional. When set, a signed token is returned
+		// instead of a generated token, and no token is stored.
+		TokenSigner               tokenSigner
+		ConfirmationTokenValidity time.Duration
+
+		// RevertEmailTokenValidity is the duration the previous email can
+		// revert the change. The revert email token is always generated
+		// by the TokenGenerator and stored.
+		RevertEmailTokenValidity time.Duration
 	}
 
 	ChangeEmail struct {
@@ -25,26 +45,54 @@ type (
 	}
 )
 
-func (c *ChangeEmail) Exec(ctx context.Context, currentUserID passport.UserID, email passport.Email) (string, error) {
+func (c *ChangeEmail) Exec(ctx context.Context, currentUserID passport.UserID, email passport.Email) (*passport.EmailChange, error) {
+	var change *passport.EmailChange
+	err := runInTx(ctx, c.options.TxRunner, func(ctx context.Context) error {
+		var err error
+		change, err = c.exec(ctx, currentUserID, email)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+func (c *ChangeEmail) exec(ctx context.Context, currentUserID passport.UserID, email passport.Email) (*passport.EmailChange, error) {
 	if err := c.validate(currentUserID, email); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if err := c.checkEmailExists(ctx, email); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	user, err := c.findUser(ctx, currentUserID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	oldEmail, err := c.checkEmailPresent(user)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	revertable, err := c.createRevertEmailToken(ctx, user, oldEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.createConfirmationToken(ctx, user, oldEmail, email)
+	if err != nil {
+		return nil, err
 	}
 
-	return c.createConfirmationToken(ctx, oldEmail, email)
+	return &passport.EmailChange{
+		ConfirmationToken: token,
+		PreviousEmail:     revertable.PreviousEmail,
+		RevertEmailToken:  revertable.RevertEmailToken,
+	}, nil
 }
 
 func (c *ChangeEmail) validate(userID passport.UserID, email passport.Email) error {
@@ -91,20 +139,80 @@ func (c *ChangeEmail) checkEmailPresent(user *passport.User) (passport.Email, er
 	return email, nil
 }
 
-func (c *ChangeEmail) createConfirmationToken(ctx context.Context, oldEmail, newEmail passport.Email) (string, error) {
+// createRevertEmailToken allows the previous email to revert the change. A
+// revert email token that is still valid is kept, so that a chain of changes
+// can still be reverted by the email before the first change.
+func (c *ChangeEmail) createRevertEmailToken(ctx context.Context, user *passport.User, oldEmail passport.Email) (passport.Revertable, error) {
+	now := c.options.Clock.Now()
+	if user.Revertable.ValidAt(now, c.options.RevertEmailTokenValidity) {
+		return user.Revertable, nil
+	}
+
+	token, err := c.options.TokenGenerator.Generate()
+	if err != nil {
+		return passport.Revertable{}, err
+	}
+
+	revertable := passport.NewRevertableAt(token, oldEmail.Value(), now)
+	if _, err := c.options.Repository.UpdateRevertable(ctx, user.ID, user.Version, revertable); err != nil {
+		return passport.Revertable{}, err
+	}
+	user.Version++
+
+	return revertable, nil
+}
+
+func (c *ChangeEmail) createConfirmationToken(ctx context.Context, user *passport.User, oldEmail, newEmail passport.Email) (string, error) {
+	if c.options.TokenSigner != nil {
+		return c.signConfirmationToken(ctx, user, oldEmail, newEmail)
+	}
+
 	token, err := c.options.TokenGenerator.Generate()
 	if err != nil {
 		return "", err
 	}
 
-	confirmable := passport.NewConfirmable(token, newEmail.Value())
-	if _, err = c.options.Repository.UpdateConfirmable(ctx, oldEmail.Value(), confirmable); err != nil {
+	confirmable := passport.NewConfirmableAt(token, newEmail.Value(), c.options.Clock.Now())
+	if _, err = c.options.Repository.UpdateConfirmable(ctx, oldEmail.Value(), user.Version, confirmable); err != nil {
 		return "", err
 	}
 
 	return confirmable.ConfirmationToken, nil
 }
 
-func NewChangeEmail(opts ChangeEmailOptions) *ChangeEmail {
-	return &ChangeEmail{opts}
+func (c *ChangeEmail) signConfirmationToken(ctx context.Context, user *passport.User, oldEmail, newEmail passport.Email) (string, error) {
+	now := c.options.Clock.Now()
+
+	// Only the unconfirmed email is stored, the token is signed against
+	// the updated user.
+	confirmable := passport.NewConfirmableAt("", newEmail.Value(), now)
+	if _, err := c.options.Repository.UpdateConfirmableSigned(ctx, oldEmail.Value(), user.Version, confirmable); err != nil {
+		return "", err
+	}
+
+	user, err := c.findUser(ctx, user.UserID())
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := now.Add(c.options.ConfirmationTokenValidity)
+	return signToken(c.options.TokenSigner, user, passport.TokenPurposeConfirmation, expiresAt)
+}
+
+// NewChangeEmail returns a new ChangeEmail, or ErrTxRunnerRequired when the
+// TxRunner is missing.
+func NewChangeEmail(opts ChangeEmailOptions) (*ChangeEmail, error) {
+	if opts.TxRunner == nil {
+		return nil, ErrTxRunnerRequired
+	}
+	if opts.Clock == nil {
+		opts.Clock = passport.SystemClock{}
+	}
+	if opts.ConfirmationTokenValidity == 0 {
+		opts.ConfirmationTokenValidity = passport.ConfirmationTokenValidity
+	}
+	if opts.RevertEmailTokenValidity == 0 {
+		opts.RevertEmailTokenValidity = passport.RevertEmailTokenValidity
+	}
+	return &ChangeEmail{opts}, nil
 }
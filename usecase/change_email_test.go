@@ -78,12 +78,70 @@ func TestChangeEmailSuccess(t *testing.T) {
 			},
 		},
 		updateConfirmableResponse: true,
+		updateRevertableResponse:  true,
 	}
 	token, err := changeEmail(repo, userID, email)
 	assert.Nil(err)
 	assert.True(token != "")
 }
 
+func TestChangeEmailRevertable(t *testing.T) {
+	var (
+		userID = "123456"
+		email  = "jane.doe@mail.com"
+		clock  = passport.NewFakeClock(fixedNow)
+	)
+	changeEmailAt := func(r *mockChangeEmailRepository) (*passport.EmailChange, error) {
+		opts := changeEmailOptions(r)
+		opts.Clock = clock
+		uc, err := usecase.NewChangeEmail(opts)
+		if err != nil {
+			return nil, err
+		}
+		return uc.Exec(context.TODO(), passport.UserID(userID), passport.NewEmail(email))
+	}
+
+	t.Run("when there is no email change", func(t *testing.T) {
+		assert := assert.New(t)
+		repo := &mockChangeEmailRepository{
+			findResponse: &passport.User{ID: userID, Email: "john.doe@mail.com"},
+		}
+		change, err := changeEmailAt(repo)
+		assert.Nil(err)
+		assert.Equal("john.doe@mail.com", change.PreviousEmail)
+		assert.True(change.RevertEmailToken != "")
+		assert.Equal(change.RevertEmailToken, repo.revertable.RevertEmailToken)
+		assert.Equal(fixedNow, repo.revertable.RevertEmailSentAt)
+		// The confirmable is updated after the revertable.
+		assert.Equal(1, repo.version)
+	})
+
+	t.Run("when the previous email can still revert", func(t *testing.T) {
+		assert := assert.New(t)
+		revertable := passport.NewRevertableAt("xyz", "john.doe@mail.com", fixedNow.Add(-time.Hour))
+		repo := &mockChangeEmailRepository{
+			findResponse: &passport.User{ID: userID, Email: "mallory@mail.com", Revertable: revertable},
+		}
+		change, err := changeEmailAt(repo)
+		assert.Nil(err)
+		assert.Equal("john.doe@mail.com", change.PreviousEmail)
+		assert.Equal("xyz", change.RevertEmailToken)
+		assert.Equal(passport.Revertable{}, repo.revertable)
+	})
+
+	t.Run("when the revert email token has expired", func(t *testing.T) {
+		assert := assert.New(t)
+		revertable := passport.NewRevertableAt("xyz", "john.doe@mail.com", fixedNow.Add(-passport.RevertEmailTokenValidity))
+		repo := &mockChangeEmailRepository{
+			findResponse: &passport.User{ID: userID, Email: "mallory@mail.com", Revertable: revertable},
+		}
+		change, err := changeEmailAt(repo)
+		assert.Nil(err)
+		assert.Equal("mallory@mail.com", change.PreviousEmail)
+		assert.NotEqual("xyz", change.RevertEmailToken)
+	})
+}
+
 type mockChangeEmailRepository struct {
 	hasEmailResponse          bool
 	hasEmailError             error
@@ -91,6 +149,10 @@ type mockChangeEmailRepository struct {
 	findError                 error
 	updateConfirmableResponse bool
 	updateConfirmableError    error
+	updateRevertableResponse  bool
+	updateRevertableError     error
+	revertable                passport.Revertable
+	version                   int
 }
 
 func (m *mockChangeEmailRepository) HasEmail(ctx context.Context, email string) (bool, error) {
@@ -101,21 +163,52 @@ func (m *mockChangeEmailRepository) Find(ctx context.Context, id string) (*passp
 	return m.findResponse, m.findError
 }
 
-func (m *mockChangeEmailRepository) UpdateConfirmable(ctx context.Context, email string, confirmable passport.Confirmable) (bool, error) {
+func (m *mockChangeEmailRepository) UpdateConfirmable(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error) {
+	m.version = version
+	return m.updateConfirmableResponse, m.updateConfirmableError
+}
+
+func (m *mockChangeEmailRepository) UpdateConfirmableSigned(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error) {
+	m.version = version
 	return m.updateConfirmableResponse, m.updateConfirmableError
 }
 
+func (m *mockChangeEmailRepository) UpdateRevertable(ctx context.Context, userID string, version int, revertable passport.Revertable) (bool, error) {
+	m.revertable = revertable
+	return m.updateRevertableResponse, m.updateRevertableError
+}
+
 func changeEmailOptions(r *mockChangeEmailRepository) usecase.ChangeEmailOptions {
 	return usecase.ChangeEmailOptions{
 		Repository:     r,
 		TokenGenerator: passport.NewTokenGenerator(),
+		TxRunner:       &mockTxRunner{},
 	}
 }
 
+// changeEmail returns the confirmation token of the email change.
 func changeEmail(r *mockChangeEmailRepository, userID, email string) (string, error) {
-	return usecase.NewChangeEmail(changeEmailOptions(r)).Exec(
+	uc, err := usecase.NewChangeEmail(changeEmailOptions(r))
+	if err != nil {
+		return "", err
+	}
+	change, err := uc.Exec(
 		context.TODO(),
 		passport.UserID(userID),
 		passport.NewEmail(email),
 	)
+	if err != nil {
+		return "", err
+	}
+	return change.ConfirmationToken, nil
+}
+
+func TestChangeEmailTxRunnerRequired(t *testing.T) {
+	assert := assert.New(t)
+	opts := changeEmailOptions(&mockChangeEmailRepository{})
+	opts.TxRunner = nil
+
+	uc, err := usecase.NewChangeEmail(opts)
+	assert.Nil(uc)
+	assert.Equal(usecase.ErrTxRunnerRequired, err)
 }
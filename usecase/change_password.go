@@ -11,12 +11,17 @@ import (
 type (
 	changePasswordRepository interface {
 		Find(ctx context.Context, id string) (*passport.User, error)
-		UpdatePassword(ctx context.Context, userID, encryptedPassword string) (bool, error)
+		UpdatePassword(ctx context.Context, userID string, version int, encryptedPassword string) (bool, error)
 	}
 
 	ChangePasswordOptions struct {
 		Repository      changePasswordRepository
 		EncoderComparer passwordEncoderComparer
+		TxRunner        txRunner
+
+		// ValidateAll returns the errors of all the fields as
+		// passport.ValidationErrors, instead of the first error.
+		ValidateAll bool
 	}
 
 	ChangePassword struct {
@@ -25,6 +30,12 @@ type (
 )
 
 func (c *ChangePassword) Exec(ctx context.Context, currentUserID passport.UserID, password, confirmPassword passport.Password) error {
+	return runInTx(ctx, c.options.TxRunner, func(ctx context.Context) error {
+		return c.exec(ctx, currentUserID, password, confirmPassword)
+	})
+}
+
+func (c *ChangePassword) exec(ctx context.Context, currentUserID passport.UserID, password, confirmPassword passport.Password) error {
 	if err := c.validate(currentUserID, password, confirmPassword); err != nil {
 		return err
 	}
@@ -46,11 +57,19 @@ func (c *ChangePassword) Exec(ctx context.Context, currentUserID passport.UserID
 		return err
 	}
 
-	_, err = c.options.Repository.UpdatePassword(ctx, currentUserID.Value(), cipherText)
+	_, err = c.options.Repository.UpdatePassword(ctx, currentUserID.Value(), user.Version, cipherText)
 	return err
 }
 
 func (c *ChangePassword) validate(userID passport.UserID, password, confirmPassword passport.Password) error {
+	if c.options.ValidateAll {
+		var errs passport.ValidationErrors
+		errs = errs.Add("user_id", userID.Validate(), nil)
+		errs = password.ValidateField("password", errs)
+		errs = errs.Add("confirm_password", password.Equal(confirmPassword), nil)
+		return errs.Err()
+	}
+
 	if err := password.Equal(confirmPassword); err != nil {
 		return err
 	}
@@ -103,7 +103,7 @@ func (m *mockChangePasswordRepository) Find(ctx context.Context, id string) (*pa
 	return m.findResponse, m.findError
 }
 
-func (m *mockChangePasswordRepository) UpdatePassword(ctx context.Context, userID, encryptedPassword string) (bool, error) {
+func (m *mockChangePasswordRepository) UpdatePassword(ctx context.Context, userID string, version int, encryptedPassword string) (bool, error) {
 	return m.updatePasswordResponse, m.updatePasswordError
 }
 
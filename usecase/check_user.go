@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	CheckUserOptions struct {
+		Repository userFinder
+	}
+
+	// CheckUser checks that the user can still be issued tokens, i.e. that
+	// the user is neither deleted nor locked. It is meant for
+	// issuer.Options.ValidateSubject.
+	CheckUser struct {
+		options CheckUserOptions
+	}
+)
+
+func (c *CheckUser) Exec(ctx context.Context, userID passport.UserID) error {
+	if err := userID.Validate(); err != nil {
+		return err
+	}
+
+	user, err := c.options.Repository.Find(ctx, userID.Value())
+	if errors.Is(err, sql.ErrNoRows) {
+		return passport.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return user.ValidateUnlocked()
+}
+
+// ValidateSubject is like Exec, except that it takes the subject of the
+// token.
+func (c *CheckUser) ValidateSubject(ctx context.Context, subject string) error {
+	return c.Exec(ctx, passport.NewUserID(subject))
+}
+
+func NewCheckUser(options CheckUserOptions) *CheckUser {
+	return &CheckUser{options}
+}
@@ -0,0 +1,46 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		user   *passport.User
+		err    error
+		want   error
+	}{
+		{"when user id is not provided", "", nil, nil, passport.ErrUserIDRequired},
+		{"when user is not found", "1", nil, sql.ErrNoRows, passport.ErrUserNotFound},
+		{"when user is locked", "1", &passport.User{ID: "1", Lockable: passport.Lockable{LockedAt: fixedNow}}, nil, passport.ErrAccountLocked},
+		{"when user is active", "1", &passport.User{ID: "1"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			svc := usecase.NewCheckUser(usecase.CheckUserOptions{
+				Repository: &mockCheckUserRepository{user: tt.user, err: tt.err},
+			})
+			assert.Equal(tt.want, svc.ValidateSubject(context.TODO(), tt.userID))
+		})
+	}
+}
+
+type mockCheckUserRepository struct {
+	user *passport.User
+	err  error
+}
+
+func (m *mockCheckUserRepository) Find(ctx context.Context, id string) (*passport.User, error) {
+	return m.user, m.err
+}
@@ -12,12 +12,22 @@ import (
 type (
 	confirmRepository interface {
 		WithConfirmationToken(ctx context.Context, token string) (*passport.User, error)
-		UpdateConfirmable(ctx context.Context, email string, confirmable passport.Confirmable) (bool, error)
+		ConsumeConfirmationToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error)
+
+		// Used with signed tokens.
+		Find(ctx context.Context, id string) (*passport.User, error)
+		UpdateConfirmableSigned(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error)
 	}
 
 	ConfirmOptions struct {
 		Repository                confirmRepository
 		ConfirmationTokenValidity time.Duration
+		TxRunner                  txRunner
+		Clock                     passport.Clock
+
+		// TokenSigner is optional. When set, tokens are verified by
+		// their signature instead of being looked up.
+		TokenSigner tokenSigner
 	}
 
 	Confirm struct {
@@ -25,31 +35,87 @@ type (
 	}
 )
 
-func (c *Confirm) Exec(ctx context.Context, token passport.Token) error {
-	if err := token.Validate(); err != nil {
+// Exec confirms the email of the user of the token, and returns the
+// confirmed user.
+func (c *Confirm) Exec(ctx context.Context, token passport.Token) (*passport.User, error) {
+	var user *passport.User
+	err := runInTx(ctx, c.options.TxRunner, func(ctx context.Context) error {
+		var err error
+		user, err = c.exec(ctx, token)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (c *Confirm) exec(ctx context.Context, token passport.Token) (*passport.User, error) {
+	if err := token.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.options.TokenSigner != nil {
+		return c.execSigned(ctx, token)
 	}
 
 	user, err := c.findUser(ctx, token)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := c.checkEmailPresent(user); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := c.checkCanConfirm(user.Confirmable); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := c.checkConfirmationTokenValid(user.Confirmable); err != nil {
-		return err
+		return nil, err
+	}
+
+	// Concurrent requests with the same token may all pass the checks
+	// above, but only one of them can consume the token.
+	return c.consumeToken(ctx, token)
+}
+
+func (c *Confirm) execSigned(ctx context.Context, token passport.Token) (*passport.User, error) {
+	user, err := verifyToken(ctx, c.options.TokenSigner, c.options.Repository, token, passport.TokenPurposeConfirmation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkEmailPresent(user); err != nil {
+		return nil, err
 	}
 
+	if err := c.checkCanConfirm(user.Confirmable); err != nil {
+		return nil, err
+	}
+
+	// Confirming changes the fingerprint, which invalidates the token.
 	var confirmable passport.Confirmable
-	_, err = c.options.Repository.UpdateConfirmable(ctx, user.Email, confirmable)
-	return err
+	if _, err := c.options.Repository.UpdateConfirmableSigned(ctx, user.Email, user.Version, confirmable); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (c *Confirm) consumeToken(ctx context.Context, token passport.Token) (*passport.User, error) {
+	sentAfter := c.options.Clock.Now().Add(-c.options.ConfirmationTokenValidity)
+	user, err := c.options.Repository.ConsumeConfirmationToken(ctx, token.Value(), sentAfter)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 func (c *Confirm) findUser(ctx context.Context, token passport.Token) (*passport.User, error) {
@@ -74,9 +140,12 @@ func (c *Confirm) checkCanConfirm(confirmable passport.Confirmable) error {
 }
 
 func (c *Confirm) checkConfirmationTokenValid(confirmable passport.Confirmable) error {
-	return confirmable.ValidateExpiry(c.options.ConfirmationTokenValidity)
+	return confirmable.ValidateExpiryAt(c.options.Clock.Now(), c.options.ConfirmationTokenValidity)
 }
 
 func NewConfirm(options ConfirmOptions) *Confirm {
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
 	return &Confirm{options}
 }
@@ -38,7 +38,7 @@ func TestConfirmTokenExpired(t *testing.T) {
 		withConfirmationTokenResponse: &passport.User{
 			Email: "john.doe@mail.com",
 			Confirmable: passport.Confirmable{
-				ConfirmationSentAt: time.Now().Add(-25 * time.Hour),
+				ConfirmationSentAt: fixedNow.Add(-25 * time.Hour),
 				ConfirmationToken:  token,
 				UnconfirmedEmail:   "john.doe@mail.com",
 			},
@@ -56,8 +56,8 @@ func TestConfirmTokenEmailVerified(t *testing.T) {
 		withConfirmationTokenResponse: &passport.User{
 			Email: "john.doe@mail.com",
 			Confirmable: passport.Confirmable{
-				ConfirmationSentAt: time.Now().Add(-23 * time.Hour),
-				ConfirmedAt:        time.Now(),
+				ConfirmationSentAt: fixedNow.Add(-23 * time.Hour),
+				ConfirmedAt:        fixedNow,
 				ConfirmationToken:  token,
 				UnconfirmedEmail:   "",
 			},
@@ -75,42 +75,198 @@ func TestConfirmEmailSuccess(t *testing.T) {
 		withConfirmationTokenResponse: &passport.User{
 			Email: "john.doe@mail.com",
 			Confirmable: passport.Confirmable{
-				ConfirmationSentAt: time.Now().Add(-23 * time.Hour),
-				ConfirmedAt:        time.Now(),
+				ConfirmationSentAt: fixedNow.Add(-23 * time.Hour),
+				ConfirmedAt:        fixedNow,
 				ConfirmationToken:  token,
 				UnconfirmedEmail:   "john.doe@mail.com",
 			},
 		},
-		updateConfirmableResponse: true,
+		consumeConfirmationTokenResponse: &passport.User{Email: "john.doe@mail.com"},
 	}, token)
 	assert.Nil(err)
 }
 
+func TestConfirmTokenConsumed(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		token = "xyz"
+	)
+	err := confirm(&mockConfirmRepository{
+		withConfirmationTokenResponse: &passport.User{
+			Email: "john.doe@mail.com",
+			Confirmable: passport.Confirmable{
+				ConfirmationSentAt: fixedNow,
+				ConfirmationToken:  token,
+				UnconfirmedEmail:   "john.doe@mail.com",
+			},
+		},
+		consumeConfirmationTokenError: sql.ErrNoRows,
+	}, token)
+	assert.Equal(passport.ErrTokenInvalid, err)
+}
+
+func TestConfirmReturnsUser(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		token = "xyz"
+	)
+	confirmed := &passport.User{ID: "1", Email: "john.doe@mail.com"}
+	user, err := usecase.NewConfirm(confirmOptions(&mockConfirmRepository{
+		withConfirmationTokenResponse: &passport.User{
+			Email: "john.doe@mail.com",
+			Confirmable: passport.Confirmable{
+				ConfirmationSentAt: fixedNow,
+				ConfirmationToken:  token,
+				UnconfirmedEmail:   "john.doe@mail.com",
+			},
+		},
+		consumeConfirmationTokenResponse: confirmed,
+	}, passport.NewFakeClock(fixedNow))).Exec(context.TODO(), passport.NewToken(token))
+	assert.Nil(err)
+	assert.Equal(confirmed, user)
+}
+
+func TestConfirmTokenExpiryBoundary(t *testing.T) {
+	var (
+		token  = "xyz"
+		sentAt = fixedNow.Add(-passport.ConfirmationTokenValidity)
+	)
+	repo := &mockConfirmRepository{
+		withConfirmationTokenResponse: &passport.User{
+			Email: "john.doe@mail.com",
+			Confirmable: passport.Confirmable{
+				ConfirmationSentAt: sentAt,
+				ConfirmationToken:  token,
+				UnconfirmedEmail:   "john.doe@mail.com",
+			},
+		},
+		consumeConfirmationTokenResponse: &passport.User{Email: "john.doe@mail.com"},
+	}
+
+	t.Run("when token is at the last moment of validity", func(t *testing.T) {
+		assert := assert.New(t)
+		clock := passport.NewFakeClock(fixedNow.Add(-time.Nanosecond))
+		assert.Nil(confirmAt(repo, token, clock))
+		// The repository must still accept the token.
+		assert.True(sentAt.After(repo.sentAfter))
+	})
+
+	t.Run("when token reaches the validity period", func(t *testing.T) {
+		assert := assert.New(t)
+		clock := passport.NewFakeClock(fixedNow)
+		assert.Equal(passport.ErrTokenExpired, confirmAt(repo, token, clock))
+	})
+}
+
+func TestConfirmSignedToken(t *testing.T) {
+	var (
+		clock  = passport.NewFakeClock(fixedNow)
+		signer = passport.NewHMACTokenSigner(passport.NewKeyring(clock, passport.Key{ID: "key_1", Secret: []byte("secret")}))
+		user   = &passport.User{
+			ID:    "1",
+			Email: "john.doe@mail.com",
+			Confirmable: passport.Confirmable{
+				UnconfirmedEmail: "john.doe@mail.com",
+			},
+		}
+	)
+	token, err := signer.Sign(passport.SignedTokenClaims{
+		UserID:      user.ID,
+		Purpose:     passport.TokenPurposeConfirmation,
+		ExpiresAt:   fixedNow.Add(time.Hour).Unix(),
+		Fingerprint: user.Fingerprint(passport.TokenPurposeConfirmation),
+	})
+	assert.Nil(t, err)
+
+	confirmSigned := func(r *mockConfirmRepository) error {
+		opts := confirmOptions(r, clock)
+		opts.TokenSigner = signer
+		_, err := usecase.NewConfirm(opts).Exec(context.TODO(), passport.NewToken(token))
+		return err
+	}
+
+	t.Run("when token is valid", func(t *testing.T) {
+		assert := assert.New(t)
+		err := confirmSigned(&mockConfirmRepository{
+			findResponse:              user,
+			updateConfirmableResponse: true,
+		})
+		assert.Nil(err)
+	})
+
+	t.Run("when token has been used", func(t *testing.T) {
+		assert := assert.New(t)
+		confirmed := *user
+		confirmed.Confirmable = passport.Confirmable{ConfirmedAt: fixedNow}
+		err := confirmSigned(&mockConfirmRepository{
+			findResponse: &confirmed,
+		})
+		assert.Equal(passport.ErrTokenInvalid, err)
+	})
+
+	t.Run("when token is for another purpose", func(t *testing.T) {
+		assert := assert.New(t)
+		opts := confirmOptions(&mockConfirmRepository{findResponse: user}, clock)
+		opts.TokenSigner = signer
+		resetToken, err := signer.Sign(passport.SignedTokenClaims{
+			UserID:    user.ID,
+			Purpose:   passport.TokenPurposeResetPassword,
+			ExpiresAt: fixedNow.Add(time.Hour).Unix(),
+		})
+		assert.Nil(err)
+		_, err = usecase.NewConfirm(opts).Exec(context.TODO(), passport.NewToken(resetToken))
+		assert.Equal(passport.ErrTokenInvalid, err)
+	})
+}
+
 type mockConfirmRepository struct {
-	withConfirmationTokenResponse *passport.User
-	withConfirmationTokenError    error
-	updateConfirmableResponse     bool
-	updateConfirmableError        error
+	withConfirmationTokenResponse    *passport.User
+	withConfirmationTokenError       error
+	consumeConfirmationTokenResponse *passport.User
+	consumeConfirmationTokenError    error
+	sentAfter                        time.Time
+	findResponse                     *passport.User
+	findError                        error
+	updateConfirmableResponse        bool
+	updateConfirmableError           error
+}
+
+func (m *mockConfirmRepository) Find(ctx context.Context, id string) (*passport.User, error) {
+	return m.findResponse, m.findError
+}
+
+func (m *mockConfirmRepository) UpdateConfirmableSigned(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error) {
+	return m.updateConfirmableResponse, m.updateConfirmableError
 }
 
 func (m *mockConfirmRepository) WithConfirmationToken(ctx context.Context, token string) (*passport.User, error) {
 	return m.withConfirmationTokenResponse, m.withConfirmationTokenError
 }
 
-func (m *mockConfirmRepository) UpdateConfirmable(ctx context.Context, email string, confirmable passport.Confirmable) (bool, error) {
-	return m.updateConfirmableResponse, m.updateConfirmableError
+func (m *mockConfirmRepository) ConsumeConfirmationToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error) {
+	m.sentAfter = sentAfter
+	return m.consumeConfirmationTokenResponse, m.consumeConfirmationTokenError
 }
 
-func confirmOptions(r *mockConfirmRepository) usecase.ConfirmOptions {
+// fixedNow is the time returned by the fake clock in the tests.
+var fixedNow = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func confirmOptions(r *mockConfirmRepository, clock passport.Clock) usecase.ConfirmOptions {
 	return usecase.ConfirmOptions{
 		Repository:                r,
 		ConfirmationTokenValidity: passport.ConfirmationTokenValidity,
+		Clock:                     clock,
 	}
 }
 
 func confirm(r *mockConfirmRepository, token string) error {
-	return usecase.NewConfirm(confirmOptions(r)).Exec(
+	return confirmAt(r, token, passport.NewFakeClock(fixedNow))
+}
+
+func confirmAt(r *mockConfirmRepository, token string, clock passport.Clock) error {
+	_, err := usecase.NewConfirm(confirmOptions(r, clock)).Exec(
 		context.TODO(),
 		passport.NewToken(token),
 	)
+	return err
 }
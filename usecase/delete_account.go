@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	deleteAccountRepository interface {
+		Find(ctx context.Context, id string) (*passport.User, error)
+		Delete(ctx context.Context, userID string, version int) (bool, error)
+	}
+
+	DeleteAccountOptions struct {
+		Repository deleteAccountRepository
+		Comparer   passwordComparer
+		TxRunner   txRunner
+	}
+
+	// DeleteAccount soft deletes the account of the current user, after
+	// the user is authenticated again with the password. The personal data
+	// is erased after the grace period by EraseDeletedAccounts.
+	DeleteAccount struct {
+		options DeleteAccountOptions
+	}
+)
+
+func (d *DeleteAccount) Exec(ctx context.Context, currentUserID passport.UserID, password passport.Password) error {
+	return runInTx(ctx, d.options.TxRunner, func(ctx context.Context) error {
+		return d.exec(ctx, currentUserID, password)
+	})
+}
+
+func (d *DeleteAccount) exec(ctx context.Context, currentUserID passport.UserID, password passport.Password) error {
+	if err := d.validate(currentUserID, password); err != nil {
+		return err
+	}
+
+	user, err := d.findUser(ctx, currentUserID)
+	if err != nil {
+		return err
+	}
+
+	if err := d.checkPasswordMatch(user.EncryptedPassword, password); err != nil {
+		return err
+	}
+
+	_, err = d.options.Repository.Delete(ctx, user.ID, user.Version)
+	return err
+}
+
+// validate only requires the password, since it may have been set before
+// the current password policy.
+func (d *DeleteAccount) validate(userID passport.UserID, password passport.Password) error {
+	if err := userID.Validate(); err != nil {
+		return err
+	}
+	if password.Value() == "" {
+		return passport.ErrPasswordRequired
+	}
+
+	return nil
+}
+
+func (d *DeleteAccount) findUser(ctx context.Context, userID passport.UserID) (*passport.User, error) {
+	user, err := d.options.Repository.Find(ctx, userID.Value())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (d *DeleteAccount) checkPasswordMatch(cipherText, plainText passport.Password) error {
+	if err := d.options.Comparer.Compare(
+		cipherText.Byte(),
+		plainText.Byte(),
+	); err != nil {
+		return passport.ErrPasswordInvalid
+	}
+
+	return nil
+}
+
+func NewDeleteAccount(options DeleteAccountOptions) *DeleteAccount {
+	return &DeleteAccount{options}
+}
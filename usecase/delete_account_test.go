@@ -0,0 +1,92 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/usecase"
+	"github.com/alextanhongpin/passwd"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteAccountValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		password string
+		err      error
+	}{
+		{"when user_id is not provided", "", "12345678", passport.ErrUserIDRequired},
+		{"when password is not provided", "123456", "", passport.ErrPasswordRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			err := deleteAccount(&mockDeleteAccountRepository{}, tt.userID, tt.password)
+			assert.Equal(tt.err, err)
+		})
+	}
+}
+
+func TestDeleteAccountNewUser(t *testing.T) {
+	assert := assert.New(t)
+	err := deleteAccount(&mockDeleteAccountRepository{
+		findError: sql.ErrNoRows,
+	}, "123456", "12345678")
+	assert.Equal(passport.ErrUserNotFound, err)
+}
+
+func TestDeleteAccountExistingUser(t *testing.T) {
+	assert := assert.New(t)
+	encrypted, err := passwd.Encrypt([]byte("12345678"))
+	assert.Nil(err)
+
+	repo := &mockDeleteAccountRepository{
+		findResponse: &passport.User{
+			ID:                "123456",
+			EncryptedPassword: passport.NewPassword(encrypted),
+			Version:           3,
+		},
+	}
+
+	t.Run("when password is incorrect", func(t *testing.T) {
+		err := deleteAccount(repo, "123456", "87654321")
+		assert.Equal(passport.ErrPasswordInvalid, err)
+		assert.False(repo.deleted)
+	})
+
+	t.Run("when password is correct", func(t *testing.T) {
+		err := deleteAccount(repo, "123456", "12345678")
+		assert.Nil(err)
+		assert.True(repo.deleted)
+		assert.Equal(3, repo.version)
+	})
+}
+
+type mockDeleteAccountRepository struct {
+	findResponse *passport.User
+	findError    error
+	deleted      bool
+	version      int
+}
+
+func (m *mockDeleteAccountRepository) Find(ctx context.Context, id string) (*passport.User, error) {
+	return m.findResponse, m.findError
+}
+
+func (m *mockDeleteAccountRepository) Delete(ctx context.Context, userID string, version int) (bool, error) {
+	m.deleted = true
+	m.version = version
+	return true, nil
+}
+
+func deleteAccount(r *mockDeleteAccountRepository, userID, password string) error {
+	return usecase.NewDeleteAccount(usecase.DeleteAccountOptions{
+		Repository: r,
+		Comparer:   passport.NewArgon2Password(),
+	}).Exec(context.TODO(), passport.UserID(userID), passport.NewPassword(password))
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	eraseDeletedAccountsRepository interface {
+		EraseDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	}
+
+	EraseDeletedAccountsOptions struct {
+		Repository eraseDeletedAccountsRepository
+		Clock      passport.Clock
+
+		// GracePeriod is the duration the deleted accounts are kept
+		// before they are erased. Defaults to
+		// passport.DeletionGracePeriod.
+		GracePeriod time.Duration
+	}
+
+	// EraseDeletedAccounts erases the personal data of the accounts that
+	// were deleted before the grace period. It is meant to be run
+	// periodically.
+	EraseDeletedAccounts struct {
+		options EraseDeletedAccountsOptions
+	}
+)
+
+// Exec returns the number of accounts erased.
+func (e *EraseDeletedAccounts) Exec(ctx context.Context) (int64, error) {
+	deletedBefore := e.options.Clock.Now().Add(-e.options.GracePeriod)
+	return e.options.Repository.EraseDeleted(ctx, deletedBefore)
+}
+
+func NewEraseDeletedAccounts(options EraseDeletedAccountsOptions) *EraseDeletedAccounts {
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	if options.GracePeriod == 0 {
+		options.GracePeriod = passport.DeletionGracePeriod
+	}
+	return &EraseDeletedAccounts{options}
+}
@@ -0,0 +1,48 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEraseDeletedAccounts(t *testing.T) {
+	tests := []struct {
+		name          string
+		gracePeriod   time.Duration
+		deletedBefore time.Time
+	}{
+		{"when grace period is not set", 0, fixedNow.Add(-passport.DeletionGracePeriod)},
+		{"when grace period is set", time.Hour, fixedNow.Add(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			repo := &mockEraseDeletedAccountsRepository{erased: 2}
+			n, err := usecase.NewEraseDeletedAccounts(usecase.EraseDeletedAccountsOptions{
+				Repository:  repo,
+				Clock:       passport.NewFakeClock(fixedNow),
+				GracePeriod: tt.gracePeriod,
+			}).Exec(context.TODO())
+			assert.Nil(err)
+			assert.Equal(int64(2), n)
+			assert.Equal(tt.deletedBefore, repo.deletedBefore)
+		})
+	}
+}
+
+type mockEraseDeletedAccountsRepository struct {
+	erased        int64
+	deletedBefore time.Time
+}
+
+func (m *mockEraseDeletedAccountsRepository) EraseDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	m.deletedBefore = deletedBefore
+	return m.erased, nil
+}
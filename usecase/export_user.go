@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	exportUserRepository interface {
+		// FindIncludingDeleted also returns the deleted users that are
+		// not erased yet, which can still be exported.
+		FindIncludingDeleted(ctx context.Context, id string) (*passport.User, error)
+	}
+
+	exportUserAuditLog interface {
+		FindByUser(ctx context.Context, userID string, from, to time.Time) ([]passport.AuditEntry, error)
+		FindByIdentifier(ctx context.Context, identifier string, from, to time.Time) ([]passport.AuditEntry, error)
+	}
+
+	ExportUserOptions struct {
+		Repository exportUserRepository
+		Clock      passport.Clock
+
+		// AuditLog is optional. When set, all the entries of the user
+		// are exported, including the failed attempts on the email,
+		// username and phone of the user.
+		AuditLog exportUserAuditLog
+	}
+
+	// ExportUser gathers the personal data stored about the user, for
+	// subject access requests.
+	ExportUser struct {
+		options ExportUserOptions
+	}
+)
+
+func (e *ExportUser) Exec(ctx context.Context, userID passport.UserID) (*passport.UserExport, error) {
+	if err := userID.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := e.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := e.options.Clock.Now()
+	entries, err := e.findAuditEntries(ctx, user, now)
+	if err != nil {
+		return nil, err
+	}
+
+	export := passport.NewUserExport(*user, entries, now)
+	return &export, nil
+}
+
+func (e *ExportUser) findUser(ctx context.Context, userID passport.UserID) (*passport.User, error) {
+	user, err := e.options.Repository.FindIncludingDeleted(ctx, userID.Value())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (e *ExportUser) findAuditEntries(ctx context.Context, user *passport.User, now time.Time) ([]passport.AuditEntry, error) {
+	if e.options.AuditLog == nil {
+		return nil, nil
+	}
+
+	// All the entries of the user up to the export.
+	var from time.Time
+	entries, err := e.options.AuditLog.FindByUser(ctx, user.ID, from, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// The failed attempts are only recorded with the identifier, since
+	// the user is not known. Only the attempts made while the user owned
+	// the identifier are exported, since it may belong to another user
+	// before, or after the user is deleted.
+	to := now
+	if !user.DeletedAt.IsZero() {
+		to = user.DeletedAt
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		seen[entry.ID] = true
+	}
+	for _, identifier := range []struct {
+		value string
+		from  time.Time
+	}{
+		{user.Email, emailOwnedSince(user, entries)},
+		{user.Username, user.CreatedAt},
+		{user.Phone, user.CreatedAt},
+	} {
+		if identifier.value == "" {
+			continue
+		}
+		found, err := e.options.AuditLog.FindByIdentifier(ctx, identifier.value, identifier.from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range found {
+			if !seen[entry.ID] {
+				seen[entry.ID] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// emailOwnedSince returns the time the email of the user was last replaced,
+// by the successful email changes in the entries of the user, oldest first,
+// or the time the user is created when the email was never changed. The
+// username and phone are only set on register.
+func emailOwnedSince(user *passport.User, entries []passport.AuditEntry) time.Time {
+	since := user.CreatedAt
+	for _, entry := range entries {
+		if entry.Outcome != passport.AuditSuccess {
+			continue
+		}
+		switch entry.Action {
+		case passport.AuditChangeEmail,
+			passport.AuditCancelEmailChange,
+			passport.AuditRevertEmailChange:
+			if entry.CreatedAt.After(since) {
+				since = entry.CreatedAt
+			}
+		}
+	}
+	return since
+}
+
+func NewExportUser(options ExportUserOptions) *ExportUser {
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	return &ExportUser{options}
+}
@@ -0,0 +1,145 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportUserValidation(t *testing.T) {
+	assert := assert.New(t)
+	export, err := exportUser(&mockExportUserRepository{}, nil, "")
+	assert.Nil(export)
+	assert.Equal(passport.ErrUserIDRequired, err)
+}
+
+func TestExportUserNewUser(t *testing.T) {
+	assert := assert.New(t)
+	export, err := exportUser(&mockExportUserRepository{
+		findError: sql.ErrNoRows,
+	}, nil, "123456")
+	assert.Nil(export)
+	assert.Equal(passport.ErrUserNotFound, err)
+}
+
+func TestExportUserSuccess(t *testing.T) {
+	var (
+		user = &passport.User{
+			ID:          "123456",
+			Email:       "john.doe@mail.com",
+			Recoverable: passport.NewRecoverableAt("xyz", fixedNow),
+			CreatedAt:   fixedNow.Add(-time.Hour),
+		}
+		entries = []passport.AuditEntry{
+			{ID: "2", Action: passport.AuditLogin, Outcome: passport.AuditSuccess, UserID: "123456", Identifier: "john.doe@mail.com", IP: "127.0.0.1", CreatedAt: fixedNow},
+		}
+		attempts = []passport.AuditEntry{
+			{ID: "1", Action: passport.AuditLogin, Outcome: passport.AuditFailure, Identifier: "john.doe@mail.com", IP: "127.0.0.1", CreatedAt: fixedNow.Add(-time.Minute)},
+			entries[0],
+		}
+	)
+
+	t.Run("without audit log", func(t *testing.T) {
+		assert := assert.New(t)
+		export, err := exportUser(&mockExportUserRepository{findResponse: user}, nil, "123456")
+		assert.Nil(err)
+		assert.Equal(fixedNow, export.ExportedAt)
+		assert.Equal("john.doe@mail.com", export.User.Email)
+		assert.Equal(passport.RedactedToken, export.Tokens[0].Token)
+		assert.Equal([]passport.AuditEntry{}, export.AuditLog)
+	})
+
+	t.Run("with audit log", func(t *testing.T) {
+		assert := assert.New(t)
+		auditLog := &mockExportUserAuditLog{entries: entries, attempts: attempts}
+		export, err := exportUser(&mockExportUserRepository{findResponse: user}, auditLog, "123456")
+		assert.Nil(err)
+		assert.Equal(attempts, export.AuditLog, "the failed attempts on the email are included once, oldest first")
+		assert.Equal("123456", auditLog.userID)
+		assert.Equal([]string{"john.doe@mail.com"}, auditLog.identifiers)
+		assert.Equal(fixedNow, auditLog.to)
+
+		// The attempts on the email are only looked up since the user
+		// is created.
+		assert.Equal([]time.Time{user.CreatedAt}, auditLog.identifierFroms)
+		assert.Equal(fixedNow, auditLog.identifierTo)
+	})
+
+	t.Run("when the email is changed", func(t *testing.T) {
+		assert := assert.New(t)
+		changed := fixedNow.Add(-30 * time.Minute)
+		auditLog := &mockExportUserAuditLog{entries: []passport.AuditEntry{
+			{ID: "3", Action: passport.AuditChangeEmail, Outcome: passport.AuditSuccess, UserID: "123456", CreatedAt: changed},
+			{ID: "4", Action: passport.AuditChangeEmail, Outcome: passport.AuditFailure, UserID: "123456", CreatedAt: fixedNow.Add(-time.Minute)},
+		}}
+		withUsername := *user
+		withUsername.Username = "john.doe"
+		_, err := exportUser(&mockExportUserRepository{findResponse: &withUsername}, auditLog, "123456")
+		assert.Nil(err)
+		assert.Equal([]string{"john.doe@mail.com", "john.doe"}, auditLog.identifiers)
+		assert.Equal([]time.Time{changed, user.CreatedAt}, auditLog.identifierFroms, "the email is owned since the last successful change")
+	})
+
+	t.Run("when the user is deleted", func(t *testing.T) {
+		assert := assert.New(t)
+		deleted := *user
+		deleted.DeletedAt = fixedNow.Add(-time.Minute)
+		auditLog := &mockExportUserAuditLog{}
+		export, err := exportUser(&mockExportUserRepository{findResponse: &deleted}, auditLog, "123456")
+		assert.Nil(err)
+		assert.Equal(&deleted.DeletedAt, export.User.DeletedAt)
+
+		// The email can be registered by another user after the
+		// deletion.
+		assert.Equal(deleted.DeletedAt, auditLog.identifierTo)
+	})
+}
+
+type mockExportUserRepository struct {
+	findResponse *passport.User
+	findError    error
+}
+
+func (m *mockExportUserRepository) FindIncludingDeleted(ctx context.Context, id string) (*passport.User, error) {
+	return m.findResponse, m.findError
+}
+
+type mockExportUserAuditLog struct {
+	entries         []passport.AuditEntry
+	attempts        []passport.AuditEntry
+	userID          string
+	to              time.Time
+	identifiers     []string
+	identifierFroms []time.Time
+	identifierTo    time.Time
+}
+
+func (m *mockExportUserAuditLog) FindByUser(ctx context.Context, userID string, from, to time.Time) ([]passport.AuditEntry, error) {
+	m.userID = userID
+	m.to = to
+	return m.entries, nil
+}
+
+func (m *mockExportUserAuditLog) FindByIdentifier(ctx context.Context, identifier string, from, to time.Time) ([]passport.AuditEntry, error) {
+	m.identifiers = append(m.identifiers, identifier)
+	m.identifierFroms = append(m.identifierFroms, from)
+	m.identifierTo = to
+	return m.attempts, nil
+}
+
+func exportUser(r *mockExportUserRepository, auditLog *mockExportUserAuditLog, userID string) (*passport.UserExport, error) {
+	opts := usecase.ExportUserOptions{
+		Repository: r,
+		Clock:      passport.NewFakeClock(fixedNow),
+	}
+	if auditLog != nil {
+		opts.AuditLog = auditLog
+	}
+	return usecase.NewExportUser(opts).Exec(context.TODO(), passport.UserID(userID))
+}
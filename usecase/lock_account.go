@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	lockAccountRepository interface {
+		Find(ctx context.Context, id string) (*passport.User, error)
+		Lock(ctx context.Context, userID string, version int) (bool, error)
+	}
+
+	LockAccountOptions struct {
+		Repository lockAccountRepository
+		TxRunner   txRunner
+	}
+
+	// LockAccount locks the account on behalf of an admin, e.g. when it is
+	// suspected to be compromised. Login fails until the password is
+	// reset. The tokens that are already issued have to be revoked by the
+	// caller, e.g. with issuer.Issuer.RevokeSubject.
+	LockAccount struct {
+		options LockAccountOptions
+	}
+)
+
+func (l *LockAccount) Exec(ctx context.Context, userID passport.UserID) error {
+	return runInTx(ctx, l.options.TxRunner, func(ctx context.Context) error {
+		return l.exec(ctx, userID)
+	})
+}
+
+func (l *LockAccount) exec(ctx context.Context, userID passport.UserID) error {
+	if err := userID.Validate(); err != nil {
+		return err
+	}
+
+	user, err := l.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Locking is idempotent, so that the tokens can be revoked again.
+	if user.Locked() {
+		return nil
+	}
+
+	_, err = l.options.Repository.Lock(ctx, user.ID, user.Version)
+	return err
+}
+
+func (l *LockAccount) findUser(ctx context.Context, userID passport.UserID) (*passport.User, error) {
+	user, err := l.options.Repository.Find(ctx, userID.Value())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func NewLockAccount(options LockAccountOptions) *LockAccount {
+	return &LockAccount{options}
+}
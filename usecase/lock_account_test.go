@@ -0,0 +1,74 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockAccountValidation(t *testing.T) {
+	assert := assert.New(t)
+	err := lockAccount(&mockLockAccountRepository{}, "")
+	assert.Equal(passport.ErrUserIDRequired, err)
+}
+
+func TestLockAccountNewUser(t *testing.T) {
+	assert := assert.New(t)
+	err := lockAccount(&mockLockAccountRepository{
+		findError: sql.ErrNoRows,
+	}, "123456")
+	assert.Equal(passport.ErrUserNotFound, err)
+}
+
+func TestLockAccountLocked(t *testing.T) {
+	assert := assert.New(t)
+	repo := &mockLockAccountRepository{
+		findResponse: &passport.User{
+			ID:       "123456",
+			Lockable: passport.Lockable{LockedAt: fixedNow},
+		},
+	}
+	err := lockAccount(repo, "123456")
+	assert.Nil(err)
+	assert.False(repo.locked, "already locked")
+}
+
+func TestLockAccountSuccess(t *testing.T) {
+	assert := assert.New(t)
+	repo := &mockLockAccountRepository{
+		findResponse: &passport.User{ID: "123456", Version: 3},
+	}
+	err := lockAccount(repo, "123456")
+	assert.Nil(err)
+	assert.True(repo.locked)
+	assert.Equal(3, repo.version)
+}
+
+type mockLockAccountRepository struct {
+	findResponse *passport.User
+	findError    error
+	lockError    error
+	locked       bool
+	version      int
+}
+
+func (m *mockLockAccountRepository) Find(ctx context.Context, id string) (*passport.User, error) {
+	return m.findResponse, m.findError
+}
+
+func (m *mockLockAccountRepository) Lock(ctx context.Context, userID string, version int) (bool, error) {
+	m.locked = true
+	m.version = version
+	return m.lockError == nil, m.lockError
+}
+
+func lockAccount(r *mockLockAccountRepository, userID string) error {
+	return usecase.NewLockAccount(usecase.LockAccountOptions{
+		Repository: r,
+	}).Exec(context.TODO(), passport.UserID(userID))
+}
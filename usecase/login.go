@@ -10,12 +10,17 @@ import (
 
 type (
 	loginRepository interface {
-		WithEmail(ctx context.Context, email string) (*passport.User, error)
+		WithIdentifier(ctx context.Context, identifier passport.Identifier) (*passport.User, error)
 	}
 
 	LoginOptions struct {
 		Repository loginRepository
 		Comparer   passwordComparer
+
+		// Identifiers are the kinds of identifiers the users can log in
+		// with, e.g. passport.IdentifierUsername. Defaults to the email
+		// only.
+		Identifiers []string
 	}
 
 	// Options are good, since we don't need to care about the sequence,
@@ -34,7 +39,7 @@ func (l *Login) Exec(ctx context.Context, cred passport.Credential) (*passport.U
 		return nil, err
 	}
 
-	user, err := l.findUser(ctx, cred.Email)
+	user, err := l.findUser(ctx, cred.Identifier())
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +51,10 @@ func (l *Login) Exec(ctx context.Context, cred passport.Credential) (*passport.U
 		return nil, err
 	}
 
+	if err := l.checkUserUnlocked(user.Lockable); err != nil {
+		return nil, err
+	}
+
 	if err := l.checkUserConfirmed(user.Confirmable); err != nil {
 		return nil, err
 	}
@@ -54,11 +63,18 @@ func (l *Login) Exec(ctx context.Context, cred passport.Credential) (*passport.U
 }
 
 func (l *Login) validate(cred passport.Credential) error {
-	return cred.Validate()
+	identifier := cred.Identifier()
+	if !identifier.In(l.options.Identifiers...) {
+		return passport.ErrIdentifierUnsupported
+	}
+	if err := identifier.Validate(); err != nil {
+		return err
+	}
+	return cred.Password.Validate()
 }
 
-func (l *Login) findUser(ctx context.Context, email passport.Email) (*passport.User, error) {
-	user, err := l.options.Repository.WithEmail(ctx, email.Value())
+func (l *Login) findUser(ctx context.Context, identifier passport.Identifier) (*passport.User, error) {
+	user, err := l.options.Repository.WithIdentifier(ctx, identifier)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, passport.ErrUserNotFound
 	}
@@ -80,10 +96,17 @@ func (l *Login) checkPasswordMatch(cipherText, plainText passport.Password) erro
 	return nil
 }
 
+func (l *Login) checkUserUnlocked(lockable passport.Lockable) error {
+	return lockable.ValidateUnlocked()
+}
+
 func (l *Login) checkUserConfirmed(confirmable passport.Confirmable) error {
 	return confirmable.ValidateUnconfirmed()
 }
 
 func NewLogin(options LoginOptions) *Login {
+	if len(options.Identifiers) == 0 {
+		options.Identifiers = []string{passport.IdentifierEmail}
+	}
 	return &Login{options}
 }
@@ -83,14 +83,85 @@ func TestLoginExistingUser(t *testing.T) {
 		assert.Nil(res)
 		assert.Equal(passport.ErrEmailOrPasswordInvalid, err)
 	})
+
+	t.Run("when account is locked", func(t *testing.T) {
+		locked := *repo.User
+		locked.LockedAt = time.Now()
+		res, err := login(&mockLoginRepository{User: &locked}, email, password.Value())
+		assert.Nil(res)
+		assert.Equal(passport.ErrAccountLocked, err)
+	})
+}
+
+func TestLoginIdentifiers(t *testing.T) {
+	assert := assert.New(t)
+
+	password := passport.NewPassword("12345678")
+	encrypted, err := passport.NewArgon2Password().Encode(password.Byte())
+	assert.Nil(err)
+
+	repo := &mockLoginRepository{
+		User: &passport.User{
+			Email:             "john.doe@mail.com",
+			Username:          "john",
+			Phone:             "+60123456789",
+			EncryptedPassword: passport.NewPassword(encrypted),
+			Confirmable: passport.Confirmable{
+				ConfirmedAt: time.Now(),
+			},
+		},
+	}
+	opts := loginOptions(repo)
+	opts.Identifiers = []string{passport.IdentifierUsername, passport.IdentifierPhone}
+	svc := usecase.NewLogin(opts)
+
+	t.Run("when logging in with the username", func(t *testing.T) {
+		cred := passport.NewIdentifierCredential(passport.Identifier{Kind: passport.IdentifierUsername, Value: " John "}, password.Value())
+		user, err := svc.Exec(context.TODO(), cred)
+		assert.Nil(err)
+		assert.Equal("john", user.Username)
+		assert.Equal(passport.Identifier{Kind: passport.IdentifierUsername, Value: "john"}, repo.Identifier)
+	})
+
+	t.Run("when logging in with the phone", func(t *testing.T) {
+		cred := passport.NewIdentifierCredential(passport.Identifier{Kind: passport.IdentifierPhone, Value: "+60 12-345 6789"}, password.Value())
+		user, err := svc.Exec(context.TODO(), cred)
+		assert.Nil(err)
+		assert.Equal("+60123456789", user.Phone)
+		assert.Equal(passport.Identifier{Kind: passport.IdentifierPhone, Value: "+60123456789"}, repo.Identifier)
+	})
+
+	t.Run("when the phone is invalid", func(t *testing.T) {
+		cred := passport.NewIdentifierCredential(passport.Identifier{Kind: passport.IdentifierPhone, Value: "0123456789"}, password.Value())
+		user, err := svc.Exec(context.TODO(), cred)
+		assert.Nil(user)
+		assert.Equal(passport.ErrPhoneInvalid, err)
+	})
+
+	t.Run("when the identifier is not configured", func(t *testing.T) {
+		user, err := svc.Exec(context.TODO(), passport.NewCredential("john.doe@mail.com", password.Value()))
+		assert.Nil(user)
+		assert.Equal(passport.ErrIdentifierUnsupported, err)
+
+		user, err = login(repo, "", password.Value())
+		assert.Nil(user)
+		assert.Equal(passport.ErrEmailRequired, err)
+
+		cred := passport.NewIdentifierCredential(passport.Identifier{Kind: passport.IdentifierUsername, Value: "john"}, password.Value())
+		user, err = usecase.NewLogin(loginOptions(repo)).Exec(context.TODO(), cred)
+		assert.Nil(user)
+		assert.Equal(passport.ErrIdentifierUnsupported, err)
+	})
 }
 
 type mockLoginRepository struct {
-	User *passport.User
-	Err  error
+	User       *passport.User
+	Err        error
+	Identifier passport.Identifier
 }
 
-func (m *mockLoginRepository) WithEmail(ctx context.Context, email string) (*passport.User, error) {
+func (m *mockLoginRepository) WithIdentifier(ctx context.Context, identifier passport.Identifier) (*passport.User, error) {
+	m.Identifier = identifier
 	return m.User, m.Err
 }
 
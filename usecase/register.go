@@ -2,18 +2,30 @@ package usecase
 
 import (
 	"context"
+	"errors"
 
 	"github.com/alextanhongpin/passport"
 )
 
 type (
 	registerRepository interface {
-		Create(ctx context.Context, email, password string) (*passport.User, error)
+		Create(ctx context.Context, email, password string, identifiers ...passport.Identifier) (*passport.User, error)
 	}
 
 	RegisterOptions struct {
 		Repository registerRepository
 		Encoder    passwordEncoder
+
+		// ValidateAll returns the errors of all the fields as
+		// passport.ValidationErrors, instead of the first error.
+		ValidateAll bool
+
+		// RequiredIdentifiers are the kinds of alternate identifiers that
+		// must be provided on register, e.g. passport.IdentifierUsername.
+		// The username and phone are optional by default. The email is
+		// always required, since the confirmation and reset password
+		// tokens are sent to it.
+		RequiredIdentifiers []string
 	}
 
 	Register struct {
@@ -31,11 +43,41 @@ func (r *Register) Exec(ctx context.Context, cred passport.Credential) (*passpor
 		return nil, err
 	}
 
-	return r.createAccount(ctx, cred.Email.Value(), cipherText)
+	return r.createAccount(ctx, cred.Email.Value(), cipherText, cred.AlternateIdentifiers())
 }
 
 func (r *Register) validate(cred passport.Credential) error {
-	return cred.Validate()
+	if r.options.ValidateAll {
+		var errs passport.ValidationErrors
+		if err := cred.ValidateAll(); err != nil && !errors.As(err, &errs) {
+			return err
+		}
+		return append(errs, r.missingIdentifiers(cred)...).Err()
+	}
+	if err := cred.Validate(); err != nil {
+		return err
+	}
+	if errs := r.missingIdentifiers(cred); len(errs) > 0 {
+		return errs[0].Err
+	}
+	return nil
+}
+
+// missingIdentifiers returns the errors of the required identifiers that
+// are not provided.
+func (r *Register) missingIdentifiers(cred passport.Credential) passport.ValidationErrors {
+	provided := map[string]bool{passport.IdentifierEmail: cred.Email != ""}
+	for _, identifier := range cred.AlternateIdentifiers() {
+		provided[identifier.Kind] = true
+	}
+
+	var errs passport.ValidationErrors
+	for _, kind := range r.options.RequiredIdentifiers {
+		if !provided[kind] {
+			errs = errs.Add(kind, passport.Identifier{Kind: kind}.Validate(), nil)
+		}
+	}
+	return errs
 }
 
 func (r *Register) encryptPassword(password []byte) (string, error) {
@@ -43,8 +85,8 @@ func (r *Register) encryptPassword(password []byte) (string, error) {
 	return cipherText, err
 }
 
-func (r *Register) createAccount(ctx context.Context, email, password string) (*passport.User, error) {
-	return r.options.Repository.Create(ctx, email, password)
+func (r *Register) createAccount(ctx context.Context, email, password string, identifiers []passport.Identifier) (*passport.User, error) {
+	return r.options.Repository.Create(ctx, email, password, identifiers...)
 }
 
 // NewRegister returns a new Register service.
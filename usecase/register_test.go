@@ -2,6 +2,7 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/alextanhongpin/passport"
@@ -34,6 +35,24 @@ func TestRegisterValidation(t *testing.T) {
 	}
 }
 
+func TestRegisterValidateAll(t *testing.T) {
+	assert := assert.New(t)
+	opts := registerOptions(&mockRegisterRepository{})
+	opts.ValidateAll = true
+	res, err := usecase.NewRegister(opts).Exec(
+		context.TODO(),
+		passport.NewCredential("john.doe", "12345"),
+	)
+	assert.Nil(res)
+	assert.True(errors.Is(err, passport.ErrValidationFailed))
+	assert.True(errors.Is(err, passport.ErrEmailInvalid))
+	assert.True(errors.Is(err, passport.ErrPasswordTooShort))
+	assert.Equal(passport.ValidationErrors{
+		{Field: "email", Err: passport.ErrEmailInvalid},
+		{Field: "password", Err: passport.ErrPasswordTooShort, Params: map[string]interface{}{"min": passport.PasswordMinLen}},
+	}, err)
+}
+
 func TestUserRegisterSuccess(t *testing.T) {
 	assert := assert.New(t)
 	var (
@@ -47,12 +66,84 @@ func TestUserRegisterSuccess(t *testing.T) {
 	assert.NotNil(res)
 }
 
+func TestRegisterIdentifiers(t *testing.T) {
+	assert := assert.New(t)
+
+	cred := passport.NewCredential("john.doe@mail.com", "12345678")
+
+	t.Run("when the required identifier is missing", func(t *testing.T) {
+		opts := registerOptions(&mockRegisterRepository{})
+		opts.RequiredIdentifiers = []string{passport.IdentifierUsername}
+		res, err := usecase.NewRegister(opts).Exec(context.TODO(), cred)
+		assert.Nil(res)
+		assert.Equal(passport.ErrUsernameRequired, err)
+
+		opts.ValidateAll = true
+		opts.RequiredIdentifiers = []string{passport.IdentifierUsername, passport.IdentifierPhone}
+		res, err = usecase.NewRegister(opts).Exec(context.TODO(), passport.NewCredential("john.doe", "12345678"))
+		assert.Nil(res)
+		assert.Equal(passport.ValidationErrors{
+			{Field: "email", Err: passport.ErrEmailInvalid},
+			{Field: "username", Err: passport.ErrUsernameRequired},
+			{Field: "phone", Err: passport.ErrPhoneRequired},
+		}, err)
+	})
+
+	t.Run("when the email is missing", func(t *testing.T) {
+		cred := passport.NewCredential("", "12345678")
+		cred.Username = passport.NewUsername("john")
+		cred.Phone = passport.NewPhone("+60123456789")
+
+		repo := &mockRegisterRepository{user: &passport.User{}}
+		opts := registerOptions(repo)
+		opts.RequiredIdentifiers = []string{passport.IdentifierUsername, passport.IdentifierPhone}
+		res, err := usecase.NewRegister(opts).Exec(context.TODO(), cred)
+		assert.Nil(res)
+		assert.Equal(passport.ErrEmailRequired, err, "the email stays mandatory")
+
+		opts.ValidateAll = true
+		res, err = usecase.NewRegister(opts).Exec(context.TODO(), cred)
+		assert.Nil(res)
+		assert.Equal(passport.ValidationErrors{
+			{Field: "email", Err: passport.ErrEmailRequired},
+		}, err)
+		assert.Nil(repo.identifiers, "the user is not created")
+	})
+
+	t.Run("when the optional identifier is invalid", func(t *testing.T) {
+		cred := cred
+		cred.Phone = passport.NewPhone("0123456789")
+		res, err := usecase.NewRegister(registerOptions(&mockRegisterRepository{})).Exec(context.TODO(), cred)
+		assert.Nil(res)
+		assert.Equal(passport.ErrPhoneInvalid, err)
+	})
+
+	t.Run("when the identifiers are provided", func(t *testing.T) {
+		cred := cred
+		cred.Username = passport.NewUsername("John")
+		cred.Phone = passport.NewPhone("+60 12-345 6789")
+
+		repo := &mockRegisterRepository{user: &passport.User{}}
+		opts := registerOptions(repo)
+		opts.RequiredIdentifiers = []string{passport.IdentifierUsername}
+		res, err := usecase.NewRegister(opts).Exec(context.TODO(), cred)
+		assert.Nil(err)
+		assert.NotNil(res)
+		assert.Equal([]passport.Identifier{
+			{Kind: passport.IdentifierUsername, Value: "john"},
+			{Kind: passport.IdentifierPhone, Value: "+60123456789"},
+		}, repo.identifiers)
+	})
+}
+
 type mockRegisterRepository struct {
-	user *passport.User
-	err  error
+	user        *passport.User
+	err         error
+	identifiers []passport.Identifier
 }
 
-func (m *mockRegisterRepository) Create(ctx context.Context, email, password string) (*passport.User, error) {
+func (m *mockRegisterRepository) Create(ctx context.Context, email, password string, identifiers ...passport.Identifier) (*passport.User, error) {
+	m.identifiers = identifiers
 	return m.user, m.err
 }
 
@@ -4,18 +4,34 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/alextanhongpin/passport"
 )
 
 type (
 	requestResetPasswordRepository interface {
-		UpdateRecoverable(ctx context.Context, email string, recoverable passport.Recoverable) (bool, error)
+		WithEmail(ctx context.Context, email string) (*passport.User, error)
+		UpdateRecoverable(ctx context.Context, email string, version int, recoverable passport.Recoverable) (bool, error)
+
+		// Used with signed tokens.
+		UpdateRecoverableSigned(ctx context.Context, email string, version int, recoverable passport.Recoverable) (bool, error)
 	}
 
 	RequestResetPasswordOptions struct {
 		Repository     requestResetPasswordRepository
 		TokenGenerator tokenGenerator
+		TxRunner       txRunner
+		Clock          passport.Clock
+
+		// TokenSigner is optional. When set, a signed token is returned
+		// instead of a generated token, and no token is stored.
+		TokenSigner              tokenSigner
+		RecoverableTokenValidity time.Duration
+
+		// Throttle limits the tokens sent to the same email. A negative
+		// Cooldown or DailyLimit disables the limit.
+		Throttle passport.Throttle
 	}
 
 	RequestResetPassword struct {
@@ -24,27 +40,89 @@ type (
 )
 
 func (r *RequestResetPassword) Exec(ctx context.Context, email passport.Email) (string, error) {
+	var token string
+	err := runInTx(ctx, r.options.TxRunner, func(ctx context.Context) error {
+		var err error
+		token, err = r.exec(ctx, email)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (r *RequestResetPassword) exec(ctx context.Context, email passport.Email) (string, error) {
 	if err := email.Validate(); err != nil {
 		return "", err
 	}
 
-	token, err := r.options.TokenGenerator.Generate()
+	user, err := r.findUser(ctx, email)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
-	recoverable := passport.NewRecoverable(token)
-	_, err = r.options.Repository.UpdateRecoverable(ctx, email.Value(), recoverable)
-	if errors.Is(err, sql.ErrNoRows) {
-		return "", passport.ErrUserNotFound
+	now := r.options.Clock.Now()
+	count, err := r.options.Throttle.Allow(user.Recoverable.ResetPasswordSentAt, user.Recoverable.ResetPasswordSentCount, now)
+	if err != nil {
+		return "", err
+	}
+
+	var token string
+	if r.options.TokenSigner == nil {
+		token, err = r.options.TokenGenerator.Generate()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	recoverable := passport.NewRecoverableAt(token, now)
+	recoverable.ResetPasswordSentCount = count
+	if r.options.TokenSigner != nil {
+		// Only the time sent is stored.
+		_, err = r.options.Repository.UpdateRecoverableSigned(ctx, email.Value(), user.Version, recoverable)
+	} else {
+		_, err = r.options.Repository.UpdateRecoverable(ctx, email.Value(), user.Version, recoverable)
 	}
 	if err != nil {
 		return "", err
 	}
 
+	if r.options.TokenSigner != nil {
+		// The password is not changed by the update above, hence the
+		// fingerprint of the user read is still valid.
+		expiresAt := now.Add(r.options.RecoverableTokenValidity)
+		return signToken(r.options.TokenSigner, user, passport.TokenPurposeResetPassword, expiresAt)
+	}
+
 	return recoverable.ResetPasswordToken, nil
 }
 
+func (r *RequestResetPassword) findUser(ctx context.Context, email passport.Email) (*passport.User, error) {
+	user, err := r.options.Repository.WithEmail(ctx, email.Value())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func NewRequestResetPassword(opts RequestResetPasswordOptions) *RequestResetPassword {
+	if opts.Clock == nil {
+		opts.Clock = passport.SystemClock{}
+	}
+	if opts.RecoverableTokenValidity == 0 {
+		opts.RecoverableTokenValidity = passport.RecoverableTokenValidity
+	}
+	if opts.Throttle.Cooldown == 0 {
+		opts.Throttle.Cooldown = passport.DefaultCooldown
+	}
+	if opts.Throttle.DailyLimit == 0 {
+		opts.Throttle.DailyLimit = passport.DefaultDailyLimit
+	}
 	return &RequestResetPassword{opts}
 }
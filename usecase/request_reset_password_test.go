@@ -3,7 +3,9 @@ package usecase_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/alextanhongpin/passport"
 	"github.com/alextanhongpin/passport/usecase"
@@ -35,7 +37,7 @@ func TestRequestResetPasswordValidation(t *testing.T) {
 func TestRequestResetPasswordNewEmail(t *testing.T) {
 	assert := assert.New(t)
 	token, err := requestResetPassword(&mockRequestResetPasswordRepository{
-		updateRecoverableError: sql.ErrNoRows,
+		withEmailError: sql.ErrNoRows,
 	}, "john.doe@mail.com")
 	assert.Equal("", token)
 	assert.Equal(passport.ErrUserNotFound, err)
@@ -44,18 +46,109 @@ func TestRequestResetPasswordNewEmail(t *testing.T) {
 func TestRequestResetPasswordSuccess(t *testing.T) {
 	assert := assert.New(t)
 	token, err := requestResetPassword(&mockRequestResetPasswordRepository{
+		withEmailResponse:         &passport.User{Email: "john.doe@mail.com"},
 		updateRecoverableResponse: true,
 	}, "john.doe@mail.com")
 	assert.Nil(err)
 	assert.True(token != "")
 }
 
+func TestRequestResetPasswordSigned(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(fixedNow)
+	repo := &mockRequestResetPasswordRepository{
+		withEmailResponse:         &passport.User{ID: "1", Email: "john.doe@mail.com"},
+		updateRecoverableResponse: true,
+	}
+	opts := requestResetPasswordOptions(repo)
+	opts.Clock = clock
+	opts.TokenSigner = passport.NewHMACTokenSigner(passport.NewKeyring(clock, passport.Key{ID: "key_1", Secret: []byte("secret")}))
+	token, err := usecase.NewRequestResetPassword(opts).Exec(context.TODO(), passport.NewEmail("john.doe@mail.com"))
+	assert.Nil(err)
+	assert.True(token != "")
+
+	// Only the time sent is stored, without the token.
+	assert.True(repo.signed)
+	assert.Equal(fixedNow, repo.recoverable.ResetPasswordSentAt)
+	assert.Equal(1, repo.recoverable.ResetPasswordSentCount)
+	assert.Equal("", repo.recoverable.ResetPasswordToken)
+}
+
+func TestRequestResetPasswordConcurrentModification(t *testing.T) {
+	assert := assert.New(t)
+	token, err := requestResetPassword(&mockRequestResetPasswordRepository{
+		withEmailResponse:      &passport.User{Email: "john.doe@mail.com"},
+		updateRecoverableError: passport.ErrConcurrentModification,
+	}, "john.doe@mail.com")
+	assert.Equal("", token)
+	assert.Equal(passport.ErrConcurrentModification, err)
+}
+
+func TestRequestResetPasswordThrottled(t *testing.T) {
+	now := fixedNow.Add(12 * time.Hour)
+	tests := []struct {
+		name       string
+		sentAt     time.Time
+		count      int
+		retryAfter time.Duration
+		sentCount  int
+	}{
+		{"within cooldown", now.Add(-30 * time.Second), 1, 30 * time.Second, 0},
+		{"daily limit reached", now.Add(-time.Hour), passport.DefaultDailyLimit, 12 * time.Hour, 0},
+		{"daily limit reset", now.Add(-24 * time.Hour), passport.DefaultDailyLimit, 0, 1},
+		{"below daily limit", now.Add(-time.Hour), 2, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			repo := &mockRequestResetPasswordRepository{
+				withEmailResponse: &passport.User{
+					Email: "john.doe@mail.com",
+					Recoverable: passport.Recoverable{
+						ResetPasswordSentAt:    tt.sentAt,
+						ResetPasswordSentCount: tt.count,
+					},
+				},
+				updateRecoverableResponse: true,
+			}
+			options := requestResetPasswordOptions(repo)
+			options.Clock = passport.NewFakeClock(now)
+
+			_, err := usecase.NewRequestResetPassword(options).Exec(context.TODO(), passport.NewEmail("john.doe@mail.com"))
+			retryAfter, ok := passport.RetryAfter(err)
+			assert.Equal(tt.retryAfter, retryAfter)
+			if tt.retryAfter > 0 {
+				assert.True(ok)
+				assert.True(errors.Is(err, passport.ErrTooManyRequests))
+			} else {
+				assert.Nil(err)
+			}
+			assert.Equal(tt.sentCount, repo.recoverable.ResetPasswordSentCount)
+		})
+	}
+}
+
 type mockRequestResetPasswordRepository struct {
+	withEmailResponse         *passport.User
+	withEmailError            error
 	updateRecoverableResponse bool
 	updateRecoverableError    error
+	recoverable               passport.Recoverable
+	signed                    bool
+}
+
+func (m *mockRequestResetPasswordRepository) WithEmail(ctx context.Context, email string) (*passport.User, error) {
+	return m.withEmailResponse, m.withEmailError
+}
+
+func (m *mockRequestResetPasswordRepository) UpdateRecoverable(ctx context.Context, email string, version int, recoverable passport.Recoverable) (bool, error) {
+	m.recoverable = recoverable
+	return m.updateRecoverableResponse, m.updateRecoverableError
 }
 
-func (m *mockRequestResetPasswordRepository) UpdateRecoverable(ctx context.Context, email string, recoverable passport.Recoverable) (bool, error) {
+func (m *mockRequestResetPasswordRepository) UpdateRecoverableSigned(ctx context.Context, email string, version int, recoverable passport.Recoverable) (bool, error) {
+	m.recoverable = recoverable
+	m.signed = true
 	return m.updateRecoverableResponse, m.updateRecoverableError
 }
 
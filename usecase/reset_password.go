@@ -12,14 +12,31 @@ import (
 type (
 	resetPasswordRepository interface {
 		WithResetPasswordToken(ctx context.Context, token string) (*passport.User, error)
-		UpdatePassword(ctx context.Context, userID, encryptedPassword string) (bool, error)
-		UpdateRecoverable(ctx context.Context, email string, recoverable passport.Recoverable) (bool, error)
+		ConsumeResetPasswordToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error)
+		UpdatePassword(ctx context.Context, userID string, version int, encryptedPassword string) (bool, error)
+
+		// Used with signed tokens.
+		Find(ctx context.Context, id string) (*passport.User, error)
+		Unlock(ctx context.Context, userID string, version int) (bool, error)
 	}
 
 	ResetPasswordOptions struct {
 		Repository               resetPasswordRepository
 		EncoderComparer          passwordEncoderComparer
 		RecoverableTokenValidity time.Duration
+		Clock                    passport.Clock
+
+		// TxRunner is required, so that the consumed token is restored
+		// when the password update fails.
+		TxRunner txRunner
+
+		// ValidateAll returns the errors of all the fields as
+		// passport.ValidationErrors, instead of the first error.
+		ValidateAll bool
+
+		// TokenSigner is optional. When set, tokens are verified by
+		// their signature instead of being looked up.
+		TokenSigner tokenSigner
 	}
 
 	ResetPassword struct {
@@ -27,10 +44,30 @@ type (
 	}
 )
 
+// Exec consumes the reset password token and updates the password in the
+// same transaction.
 func (r *ResetPassword) Exec(ctx context.Context, token passport.Token, password, confirmPassword passport.Password) (*passport.User, error) {
+	var user *passport.User
+	err := runInTx(ctx, r.options.TxRunner, func(ctx context.Context) error {
+		var err error
+		user, err = r.exec(ctx, token, password, confirmPassword)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *ResetPassword) exec(ctx context.Context, token passport.Token, password, confirmPassword passport.Password) (*passport.User, error) {
 	if err := r.validate(token, password, confirmPassword); err != nil {
 		return nil, err
 	}
+	if r.options.TokenSigner != nil {
+		return r.execSigned(ctx, token, password)
+	}
+
 	user, err := r.findUser(ctx, token)
 	if err != nil {
 		return nil, err
@@ -51,33 +88,70 @@ func (r *ResetPassword) Exec(ctx context.Context, token passport.Token, password
 		return nil, err
 	}
 
-	var (
-		userID    = user.UserID()
-		userEmail = passport.NewEmail(user.Email)
-	)
+	// Concurrent requests with the same token may all pass the checks
+	// above, but only one of them can consume the token.
+	user, err = r.consumeToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := user.UserID()
 	if err := userID.Validate(); err != nil {
 		return nil, err
 	}
-	if err := userEmail.Validate(); err != nil {
+
+	_, err = r.options.Repository.UpdatePassword(ctx, userID.Value(), user.Version, cipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *ResetPassword) execSigned(ctx context.Context, token passport.Token, password passport.Password) (*passport.User, error) {
+	user, err := verifyToken(ctx, r.options.TokenSigner, r.options.Repository, token, passport.TokenPurposeResetPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkPasswordNotReused(
+		user.EncryptedPassword,
+		password,
+	); err != nil {
 		return nil, err
 	}
 
-	// TODO: Wrap in transactions.
-	_, err = r.options.Repository.UpdatePassword(ctx, userID.Value(), cipherText)
+	cipherText, err := r.options.EncoderComparer.Encode(password.Byte())
 	if err != nil {
 		return nil, err
 	}
 
-	var recoverable passport.Recoverable
-	_, err = r.options.Repository.UpdateRecoverable(ctx, userEmail.Value(), recoverable)
+	// Changing the password changes the fingerprint, which invalidates
+	// the token. The versioned update guards against concurrent use.
+	_, err = r.options.Repository.UpdatePassword(ctx, user.ID, user.Version, cipherText)
 	if err != nil {
 		return nil, err
 	}
 
+	// Consuming a stored token unlocks the account in the same statement.
+	if user.Locked() {
+		if _, err := r.options.Repository.Unlock(ctx, user.ID, user.Version+1); err != nil {
+			return nil, err
+		}
+	}
+
 	return user, nil
 }
 
 func (r *ResetPassword) validate(token passport.Token, password, confirmPassword passport.Password) error {
+	if r.options.ValidateAll {
+		var errs passport.ValidationErrors
+		errs = errs.Add("token", token.Validate(), nil)
+		errs = password.ValidateField("password", errs)
+		errs = errs.Add("confirm_password", password.Equal(confirmPassword), nil)
+		return errs.Err()
+	}
+
 	if err := token.Validate(); err != nil {
 		return err
 	}
@@ -103,8 +177,21 @@ func (r *ResetPassword) findUser(ctx context.Context, token passport.Token) (*pa
 	return user, nil
 }
 
+func (r *ResetPassword) consumeToken(ctx context.Context, token passport.Token) (*passport.User, error) {
+	sentAfter := r.options.Clock.Now().Add(-r.options.RecoverableTokenValidity)
+	user, err := r.options.Repository.ConsumeResetPasswordToken(ctx, token.Value(), sentAfter)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func (r *ResetPassword) checkCanResetPassword(recoverable passport.Recoverable) error {
-	if err := recoverable.ValidateExpiry(r.options.RecoverableTokenValidity); err != nil {
+	if err := recoverable.ValidateExpiryAt(r.options.Clock.Now(), r.options.RecoverableTokenValidity); err != nil {
 		return err
 	}
 	if !recoverable.AllowPasswordChange {
@@ -125,6 +212,14 @@ func (r *ResetPassword) checkPasswordNotReused(cipherText, plainText passport.Pa
 	return nil
 }
 
-func NewResetPassword(options ResetPasswordOptions) *ResetPassword {
-	return &ResetPassword{options}
+// NewResetPassword returns a new ResetPassword, or ErrTxRunnerRequired when
+// the TxRunner is missing.
+func NewResetPassword(options ResetPasswordOptions) (*ResetPassword, error) {
+	if options.TxRunner == nil {
+		return nil, ErrTxRunnerRequired
+	}
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	return &ResetPassword{options}, nil
 }
@@ -60,7 +60,7 @@ func TestResetPasswordTokenExpired(t *testing.T) {
 	res, err := resetPassword(&mockResetPasswordRepository{
 		withResetPasswordTokenResponse: &passport.User{
 			Recoverable: passport.Recoverable{
-				ResetPasswordSentAt: time.Now().Add(-2 * time.Hour),
+				ResetPasswordSentAt: fixedNow.Add(-2 * time.Hour),
 			},
 		},
 	}, token, password, confirmPassword)
@@ -78,7 +78,7 @@ func TestResetPasswordNotAllowed(t *testing.T) {
 	res, err := resetPassword(&mockResetPasswordRepository{
 		withResetPasswordTokenResponse: &passport.User{
 			Recoverable: passport.Recoverable{
-				ResetPasswordSentAt: time.Now(),
+				ResetPasswordSentAt: fixedNow,
 				AllowPasswordChange: false,
 			},
 		},
@@ -101,7 +101,7 @@ func TestResetPasswordSamePassword(t *testing.T) {
 		withResetPasswordTokenResponse: &passport.User{
 			EncryptedPassword: passport.NewPassword(encrypted),
 			Recoverable: passport.Recoverable{
-				ResetPasswordSentAt: time.Now(),
+				ResetPasswordSentAt: fixedNow,
 				AllowPasswordChange: true,
 			},
 		},
@@ -128,36 +128,244 @@ func TestResetPasswordSuccess(t *testing.T) {
 			Email:             "john.doe@mail.com",
 			EncryptedPassword: passport.NewPassword(encrypted),
 			Recoverable: passport.Recoverable{
-				ResetPasswordSentAt: time.Now(),
+				ResetPasswordSentAt: fixedNow,
 				AllowPasswordChange: true,
 			},
 		},
-		updatePasswordResponse:    true,
-		updateRecoverableResponse: true,
+		consumeResetPasswordTokenResponse: &passport.User{ID: "123"},
+		updatePasswordResponse:            true,
 	}, token, password, confirmPassword)
 	assert.Nil(err)
 	assert.NotNil(res)
 }
 
+func TestResetPasswordTokenConsumed(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		token           = "xyz"
+		password        = "12345678"
+		confirmPassword = "12345678"
+		oldPassword     = "87654321"
+	)
+
+	encrypted, err := passwd.Encrypt([]byte(oldPassword))
+	assert.Nil(err)
+
+	res, err := resetPassword(&mockResetPasswordRepository{
+		withResetPasswordTokenResponse: &passport.User{
+			ID:                "123",
+			Email:             "john.doe@mail.com",
+			EncryptedPassword: passport.NewPassword(encrypted),
+			Recoverable: passport.Recoverable{
+				ResetPasswordSentAt: fixedNow,
+				AllowPasswordChange: true,
+			},
+		},
+		consumeResetPasswordTokenError: sql.ErrNoRows,
+	}, token, password, confirmPassword)
+	assert.Nil(res)
+	assert.Equal(passport.ErrTokenInvalid, err)
+}
+
+func TestResetPasswordTransaction(t *testing.T) {
+	var (
+		token    = "xyz"
+		password = passport.NewPassword("12345678")
+	)
+
+	encrypted, err := passwd.Encrypt([]byte("87654321"))
+	assert.Nil(t, err)
+
+	newRepo := func() *mockResetPasswordRepository {
+		return &mockResetPasswordRepository{
+			withResetPasswordTokenResponse: &passport.User{
+				ID:                "123",
+				Email:             "john.doe@mail.com",
+				EncryptedPassword: passport.NewPassword(encrypted),
+				Recoverable: passport.Recoverable{
+					ResetPasswordSentAt: fixedNow,
+					AllowPasswordChange: true,
+				},
+			},
+			consumeResetPasswordTokenResponse: &passport.User{ID: "123"},
+			updatePasswordError:               sql.ErrConnDone,
+		}
+	}
+
+	t.Run("when the password update fails", func(t *testing.T) {
+		assert := assert.New(t)
+		repo := newRepo()
+		runner := &mockTxRunner{}
+		opts := resetPasswordOptions(repo)
+		opts.TxRunner = runner
+
+		uc, err := usecase.NewResetPassword(opts)
+		assert.Nil(err)
+		res, err := uc.Exec(context.TODO(), passport.NewToken(token), password, password)
+		assert.Nil(res)
+		assert.Equal(sql.ErrConnDone, err)
+
+		// The consumed token is restored when the password update fails.
+		assert.Equal(1, runner.calls)
+		assert.True(runner.rolledBack)
+		assert.False(runner.committed)
+		assert.Equal([]string(nil), repo.consumedTokens)
+	})
+
+	t.Run("when there is no TxRunner", func(t *testing.T) {
+		assert := assert.New(t)
+		opts := resetPasswordOptions(newRepo())
+		opts.TxRunner = nil
+
+		uc, err := usecase.NewResetPassword(opts)
+		assert.Nil(uc)
+		assert.Equal(usecase.ErrTxRunnerRequired, err)
+	})
+}
+
+func TestResetPasswordTokenExpiryBoundary(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		token    = "xyz"
+		password = passport.NewPassword("12345678")
+	)
+	encrypted, err := passwd.Encrypt([]byte("87654321"))
+	assert.Nil(err)
+
+	repo := &mockResetPasswordRepository{
+		withResetPasswordTokenResponse: &passport.User{
+			ID:                "123",
+			EncryptedPassword: passport.NewPassword(encrypted),
+			Recoverable: passport.Recoverable{
+				ResetPasswordSentAt: fixedNow,
+				AllowPasswordChange: true,
+			},
+		},
+		consumeResetPasswordTokenResponse: &passport.User{ID: "123"},
+		updatePasswordResponse:            true,
+	}
+	clock := passport.NewFakeClock(fixedNow)
+	opts := resetPasswordOptions(repo)
+	opts.Clock = clock
+	uc, err := usecase.NewResetPassword(opts)
+	assert.Nil(err)
+
+	clock.Advance(passport.RecoverableTokenValidity - time.Nanosecond)
+	res, err := uc.Exec(context.TODO(), passport.NewToken(token), password, password)
+	assert.Nil(err)
+	assert.NotNil(res)
+
+	clock.Advance(time.Nanosecond)
+	res, err = uc.Exec(context.TODO(), passport.NewToken(token), password, password)
+	assert.Nil(res)
+	assert.Equal(passport.ErrTokenExpired, err)
+}
+
+func TestResetPasswordSignedToken(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		clock    = passport.NewFakeClock(fixedNow)
+		signer   = passport.NewHMACTokenSigner(passport.NewKeyring(clock, passport.Key{ID: "key_1", Secret: []byte("secret")}))
+		password = passport.NewPassword("12345678")
+	)
+	encrypted, err := passwd.Encrypt([]byte("87654321"))
+	assert.Nil(err)
+
+	user := &passport.User{
+		ID:                "1",
+		Email:             "john.doe@mail.com",
+		EncryptedPassword: passport.NewPassword(encrypted),
+	}
+	token, err := signer.Sign(passport.SignedTokenClaims{
+		UserID:      user.ID,
+		Purpose:     passport.TokenPurposeResetPassword,
+		ExpiresAt:   fixedNow.Add(time.Hour).Unix(),
+		Fingerprint: user.Fingerprint(passport.TokenPurposeResetPassword),
+	})
+	assert.Nil(err)
+
+	repo := &mockResetPasswordRepository{
+		findResponse:           user,
+		updatePasswordResponse: true,
+	}
+	opts := resetPasswordOptions(repo)
+	opts.TokenSigner = signer
+	uc, err := usecase.NewResetPassword(opts)
+	assert.Nil(err)
+
+	res, err := uc.Exec(context.TODO(), passport.NewToken(token), password, password)
+	assert.Nil(err)
+	assert.Equal(user.ID, res.ID)
+	assert.False(repo.unlocked)
+
+	// The token signed before the lock cannot unlock the account.
+	locked := *user
+	locked.LockedAt = fixedNow
+	repo.findResponse = &locked
+	_, err = uc.Exec(context.TODO(), passport.NewToken(token), password, password)
+	assert.Equal(passport.ErrTokenInvalid, err)
+	assert.False(repo.unlocked)
+
+	// The account is unlocked together with the password change.
+	lockedToken, err := signer.Sign(passport.SignedTokenClaims{
+		UserID:      user.ID,
+		Purpose:     passport.TokenPurposeResetPassword,
+		ExpiresAt:   fixedNow.Add(time.Hour).Unix(),
+		Fingerprint: locked.Fingerprint(passport.TokenPurposeResetPassword),
+	})
+	assert.Nil(err)
+	_, err = uc.Exec(context.TODO(), passport.NewToken(lockedToken), password, password)
+	assert.Nil(err)
+	assert.True(repo.unlocked)
+
+	// Once the password is changed, the token can no longer be used.
+	updated := *user
+	updated.EncryptedPassword = passport.NewPassword("new_hash")
+	repo.findResponse = &updated
+	res, err = uc.Exec(context.TODO(), passport.NewToken(token), password, password)
+	assert.Nil(res)
+	assert.Equal(passport.ErrTokenInvalid, err)
+}
+
 type mockResetPasswordRepository struct {
-	withResetPasswordTokenResponse *passport.User
-	withResetPasswordError         error
-	updatePasswordResponse         bool
-	updatePasswordError            error
-	updateRecoverableResponse      bool
-	updateRecoverableError         error
+	withResetPasswordTokenResponse    *passport.User
+	withResetPasswordError            error
+	consumeResetPasswordTokenResponse *passport.User
+	consumeResetPasswordTokenError    error
+	updatePasswordResponse            bool
+	updatePasswordError               error
+	findResponse                      *passport.User
+	findError                         error
+	unlocked                          bool
+	consumedTokens                    []string
+}
+
+func (m *mockResetPasswordRepository) Find(ctx context.Context, id string) (*passport.User, error) {
+	return m.findResponse, m.findError
 }
 
 func (m *mockResetPasswordRepository) WithResetPasswordToken(ctx context.Context, token string) (*passport.User, error) {
 	return m.withResetPasswordTokenResponse, m.withResetPasswordError
 }
 
-func (m *mockResetPasswordRepository) UpdatePassword(ctx context.Context, userID, encryptedPassword string) (bool, error) {
+func (m *mockResetPasswordRepository) UpdatePassword(ctx context.Context, userID string, version int, encryptedPassword string) (bool, error) {
 	return m.updatePasswordResponse, m.updatePasswordError
 }
 
-func (m *mockResetPasswordRepository) UpdateRecoverable(ctx context.Context, email string, recoverable passport.Recoverable) (bool, error) {
-	return m.updateRecoverableResponse, m.updateRecoverableError
+func (m *mockResetPasswordRepository) Unlock(ctx context.Context, userID string, version int) (bool, error) {
+	m.unlocked = true
+	return true, nil
+}
+
+func (m *mockResetPasswordRepository) ConsumeResetPasswordToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error) {
+	if m.consumeResetPasswordTokenError == nil {
+		consumed := m.consumedTokens
+		m.consumedTokens = append(m.consumedTokens, token)
+		onRollback(ctx, func() {
+			m.consumedTokens = consumed
+		})
+	}
+	return m.consumeResetPasswordTokenResponse, m.consumeResetPasswordTokenError
 }
 
 func resetPasswordOptions(r *mockResetPasswordRepository) usecase.ResetPasswordOptions {
@@ -165,14 +373,58 @@ func resetPasswordOptions(r *mockResetPasswordRepository) usecase.ResetPasswordO
 		Repository:               r,
 		EncoderComparer:          passport.NewArgon2Password(),
 		RecoverableTokenValidity: passport.RecoverableTokenValidity,
+		Clock:                    passport.NewFakeClock(fixedNow),
+		TxRunner:                 &mockTxRunner{},
 	}
 }
 
 func resetPassword(r *mockResetPasswordRepository, token, password, confirmPassword string) (*passport.User, error) {
-	return usecase.NewResetPassword(resetPasswordOptions(r)).Exec(
+	uc, err := usecase.NewResetPassword(resetPasswordOptions(r))
+	if err != nil {
+		return nil, err
+	}
+	return uc.Exec(
 		context.TODO(),
 		passport.NewToken(token),
 		passport.NewPassword(password),
 		passport.NewPassword(confirmPassword),
 	)
 }
+
+// mockTxRunner undoes the writes registered with onRollback when fn fails,
+// like a database transaction.
+type mockTxRunner struct {
+	calls      int
+	err        error
+	committed  bool
+	rolledBack bool
+}
+
+type mockTx struct {
+	undo []func()
+}
+
+type mockTxContext struct{}
+
+func (m *mockTxRunner) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls++
+	tx := &mockTx{}
+	m.err = fn(context.WithValue(ctx, mockTxContext{}, tx))
+	if m.err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		m.rolledBack = true
+		return m.err
+	}
+	m.committed = true
+	return nil
+}
+
+// onRollback registers the undo of a write made in the transaction of the
+// context. Writes made outside of a transaction are never undone.
+func onRollback(ctx context.Context, undo func()) {
+	if tx, ok := ctx.Value(mockTxContext{}).(*mockTx); ok {
+		tx.undo = append(tx.undo, undo)
+	}
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
+type (
+	revertEmailChangeRepository interface {
+		WithRevertEmailToken(ctx context.Context, token string) (*passport.User, error)
+		ConsumeRevertEmailToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error)
+	}
+
+	RevertEmailChangeOptions struct {
+		Repository               revertEmailChangeRepository
+		RevertEmailTokenValidity time.Duration
+		TxRunner                 txRunner
+		Clock                    passport.Clock
+	}
+
+	// RevertEmailChange restores the previous email with the token sent to
+	// it when the email was changed. Since the change was not made by the
+	// owner, the account is locked until the password is reset.
+	RevertEmailChange struct {
+		options RevertEmailChangeOptions
+	}
+)
+
+func (r *RevertEmailChange) Exec(ctx context.Context, token passport.Token) (*passport.User, error) {
+	var user *passport.User
+	err := runInTx(ctx, r.options.TxRunner, func(ctx context.Context) error {
+		var err error
+		user, err = r.exec(ctx, token)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *RevertEmailChange) exec(ctx context.Context, token passport.Token) (*passport.User, error) {
+	if err := token.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := r.findUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkRevertEmailTokenValid(user.Revertable); err != nil {
+		return nil, err
+	}
+
+	// Concurrent requests with the same token may all pass the checks
+	// above, but only one of them can consume the token.
+	return r.consumeToken(ctx, token)
+}
+
+func (r *RevertEmailChange) findUser(ctx context.Context, token passport.Token) (*passport.User, error) {
+	user, err := r.options.Repository.WithRevertEmailToken(ctx, token.Value())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *RevertEmailChange) checkRevertEmailTokenValid(revertable passport.Revertable) error {
+	return revertable.ValidateExpiryAt(r.options.Clock.Now(), r.options.RevertEmailTokenValidity)
+}
+
+func (r *RevertEmailChange) consumeToken(ctx context.Context, token passport.Token) (*passport.User, error) {
+	sentAfter := r.options.Clock.Now().Add(-r.options.RevertEmailTokenValidity)
+	user, err := r.options.Repository.ConsumeRevertEmailToken(ctx, token.Value(), sentAfter)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func NewRevertEmailChange(options RevertEmailChangeOptions) *RevertEmailChange {
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	if options.RevertEmailTokenValidity == 0 {
+		options.RevertEmailTokenValidity = passport.RevertEmailTokenValidity
+	}
+	return &RevertEmailChange{options}
+}
@@ -0,0 +1,98 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/alextanhongpin/passport/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevertEmailChangeValidation(t *testing.T) {
+	assert := assert.New(t)
+	user, err := revertEmailChange(&mockRevertEmailChangeRepository{}, "   ")
+	assert.Nil(user)
+	assert.Equal(passport.ErrTokenRequired, err)
+}
+
+func TestRevertEmailChangeNewToken(t *testing.T) {
+	assert := assert.New(t)
+	user, err := revertEmailChange(&mockRevertEmailChangeRepository{
+		withRevertEmailTokenError: sql.ErrNoRows,
+	}, "xyz")
+	assert.Nil(user)
+	assert.Equal(passport.ErrUserNotFound, err)
+}
+
+func TestRevertEmailChangeTokenExpired(t *testing.T) {
+	assert := assert.New(t)
+	user, err := revertEmailChange(&mockRevertEmailChangeRepository{
+		withRevertEmailTokenResponse: &passport.User{
+			Email:      "jane.doe@mail.com",
+			Revertable: passport.NewRevertableAt("xyz", "john.doe@mail.com", fixedNow.Add(-passport.RevertEmailTokenValidity)),
+		},
+	}, "xyz")
+	assert.Nil(user)
+	assert.Equal(passport.ErrTokenExpired, err)
+}
+
+func TestRevertEmailChangeTokenConsumed(t *testing.T) {
+	assert := assert.New(t)
+	user, err := revertEmailChange(&mockRevertEmailChangeRepository{
+		withRevertEmailTokenResponse: &passport.User{
+			Email:      "jane.doe@mail.com",
+			Revertable: passport.NewRevertableAt("xyz", "john.doe@mail.com", fixedNow),
+		},
+		consumeRevertEmailTokenError: sql.ErrNoRows,
+	}, "xyz")
+	assert.Nil(user)
+	assert.Equal(passport.ErrTokenInvalid, err)
+}
+
+func TestRevertEmailChangeSuccess(t *testing.T) {
+	assert := assert.New(t)
+	sentAt := fixedNow.Add(-24 * time.Hour)
+	repo := &mockRevertEmailChangeRepository{
+		withRevertEmailTokenResponse: &passport.User{
+			Email:      "jane.doe@mail.com",
+			Revertable: passport.NewRevertableAt("xyz", "john.doe@mail.com", sentAt),
+		},
+		consumeRevertEmailTokenResponse: &passport.User{
+			Email:    "john.doe@mail.com",
+			Lockable: passport.Lockable{LockedAt: fixedNow},
+		},
+	}
+	user, err := revertEmailChange(repo, "xyz")
+	assert.Nil(err)
+	assert.Equal("john.doe@mail.com", user.Email)
+	assert.True(user.Locked())
+	assert.True(sentAt.After(repo.sentAfter))
+}
+
+type mockRevertEmailChangeRepository struct {
+	withRevertEmailTokenResponse    *passport.User
+	withRevertEmailTokenError       error
+	consumeRevertEmailTokenResponse *passport.User
+	consumeRevertEmailTokenError    error
+	sentAfter                       time.Time
+}
+
+func (m *mockRevertEmailChangeRepository) WithRevertEmailToken(ctx context.Context, token string) (*passport.User, error) {
+	return m.withRevertEmailTokenResponse, m.withRevertEmailTokenError
+}
+
+func (m *mockRevertEmailChangeRepository) ConsumeRevertEmailToken(ctx context.Context, token string, sentAfter time.Time) (*passport.User, error) {
+	m.sentAfter = sentAfter
+	return m.consumeRevertEmailTokenResponse, m.consumeRevertEmailTokenError
+}
+
+func revertEmailChange(r *mockRevertEmailChangeRepository, token string) (*passport.User, error) {
+	return usecase.NewRevertEmailChange(usecase.RevertEmailChangeOptions{
+		Repository: r,
+		Clock:      passport.NewFakeClock(fixedNow),
+	}).Exec(context.TODO(), passport.NewToken(token))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/alextanhongpin/passport"
 )
@@ -11,12 +12,26 @@ import (
 type (
 	sendConfirmationRepository interface {
 		WithEmail(ctx context.Context, email string) (*passport.User, error)
-		UpdateConfirmable(ctx context.Context, email string, confirmable passport.Confirmable) (bool, error)
+		UpdateConfirmable(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error)
+
+		// Used with signed tokens.
+		UpdateConfirmableSigned(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error)
 	}
 
 	SendConfirmationOptions struct {
 		Repository     sendConfirmationRepository
 		TokenGenerator tokenGenerator
+		TxRunner       txRunner
+		Clock          passport.Clock
+
+		// TokenSigner is optional. When set, a signed token is returned
+		// instead of a generated token, and no token is stored.
+		TokenSigner               tokenSigner
+		ConfirmationTokenValidity time.Duration
+
+		// Throttle limits the tokens sent to the same email. A negative
+		// Cooldown or DailyLimit disables the limit.
+		Throttle passport.Throttle
 	}
 
 	SendConfirmation struct {
@@ -25,6 +40,20 @@ type (
 )
 
 func (s *SendConfirmation) Exec(ctx context.Context, email passport.Email) (string, error) {
+	var token string
+	err := runInTx(ctx, s.options.TxRunner, func(ctx context.Context) error {
+		var err error
+		token, err = s.exec(ctx, email)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *SendConfirmation) exec(ctx context.Context, email passport.Email) (string, error) {
 	if err := email.Validate(); err != nil {
 		return "", err
 	}
@@ -38,12 +67,23 @@ func (s *SendConfirmation) Exec(ctx context.Context, email passport.Email) (stri
 		return "", err
 	}
 
+	now := s.options.Clock.Now()
+	count, err := s.options.Throttle.Allow(user.Confirmable.ConfirmationSentAt, user.Confirmable.ConfirmationSentCount, now)
+	if err != nil {
+		return "", err
+	}
+
+	if s.options.TokenSigner != nil {
+		return s.signToken(ctx, user, email, now, count)
+	}
+
 	token, err := s.options.TokenGenerator.Generate()
 	if err != nil {
 		return "", err
 	}
-	confirmable := passport.NewConfirmable(token, email.Value())
-	_, err = s.options.Repository.UpdateConfirmable(ctx, email.Value(), confirmable)
+	confirmable := passport.NewConfirmableAt(token, email.Value(), now)
+	confirmable.ConfirmationSentCount = count
+	_, err = s.options.Repository.UpdateConfirmable(ctx, email.Value(), user.Version, confirmable)
 	if err != nil {
 		return "", err
 	}
@@ -51,6 +91,25 @@ func (s *SendConfirmation) Exec(ctx context.Context, email passport.Email) (stri
 	return confirmable.ConfirmationToken, nil
 }
 
+func (s *SendConfirmation) signToken(ctx context.Context, user *passport.User, email passport.Email, now time.Time, count int) (string, error) {
+	// Only the time sent is stored, the token is signed against the
+	// updated user.
+	confirmable := passport.NewConfirmableAt("", email.Value(), now)
+	confirmable.ConfirmationSentCount = count
+	_, err := s.options.Repository.UpdateConfirmableSigned(ctx, email.Value(), user.Version, confirmable)
+	if err != nil {
+		return "", err
+	}
+
+	user, err = s.findUser(ctx, email)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := now.Add(s.options.ConfirmationTokenValidity)
+	return signToken(s.options.TokenSigner, user, passport.TokenPurposeConfirmation, expiresAt)
+}
+
 func (s *SendConfirmation) findUser(ctx context.Context, email passport.Email) (*passport.User, error) {
 	user, err := s.options.Repository.WithEmail(ctx, email.Value())
 	if errors.Is(err, sql.ErrNoRows) {
@@ -70,5 +129,17 @@ func (s *SendConfirmation) checkNotYetConfirmed(confirmable passport.Confirmable
 }
 
 func NewSendConfirmation(options SendConfirmationOptions) *SendConfirmation {
+	if options.Clock == nil {
+		options.Clock = passport.SystemClock{}
+	}
+	if options.ConfirmationTokenValidity == 0 {
+		options.ConfirmationTokenValidity = passport.ConfirmationTokenValidity
+	}
+	if options.Throttle.Cooldown == 0 {
+		options.Throttle.Cooldown = passport.DefaultCooldown
+	}
+	if options.Throttle.DailyLimit == 0 {
+		options.Throttle.DailyLimit = passport.DefaultDailyLimit
+	}
 	return &SendConfirmation{options}
 }
@@ -3,6 +3,7 @@ package usecase_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -36,6 +37,27 @@ func TestSendConfirmationEmailNewEmail(t *testing.T) {
 	assert.Equal(passport.ErrUserNotFound, err)
 }
 
+func TestSendConfirmationSigned(t *testing.T) {
+	assert := assert.New(t)
+	clock := passport.NewFakeClock(fixedNow)
+	repo := &mockSendConfirmationRepository{
+		withEmailResponse:         &passport.User{ID: "1", Email: "john.doe@mail.com"},
+		updateConfirmableResponse: true,
+	}
+	opts := sendConfirmationOptions(repo)
+	opts.Clock = clock
+	opts.TokenSigner = passport.NewHMACTokenSigner(passport.NewKeyring(clock, passport.Key{ID: "key_1", Secret: []byte("secret")}))
+	token, err := usecase.NewSendConfirmation(opts).Exec(context.TODO(), passport.NewEmail("john.doe@mail.com"))
+	assert.Nil(err)
+	assert.True(token != "")
+
+	// Only the time sent is stored, without the token.
+	assert.True(repo.signed)
+	assert.Equal(fixedNow, repo.confirmable.ConfirmationSentAt)
+	assert.Equal(1, repo.confirmable.ConfirmationSentCount)
+	assert.Equal("", repo.confirmable.ConfirmationToken)
+}
+
 func TestSendConfirmationEmailAlreadyVerified(t *testing.T) {
 	assert := assert.New(t)
 	token, err := sendConfirmation(&mockSendConfirmationRepository{
@@ -64,18 +86,64 @@ func TestSendConfirmationEmailSuccess(t *testing.T) {
 	assert.True(token != "")
 }
 
+func TestSendConfirmationThrottled(t *testing.T) {
+	assert := assert.New(t)
+	now := fixedNow.Add(time.Hour)
+	clock := passport.NewFakeClock(now)
+	repo := &mockSendConfirmationRepository{
+		withEmailResponse: &passport.User{
+			Confirmable: passport.Confirmable{
+				ConfirmationSentAt:    now.Add(-10 * time.Second),
+				ConfirmationSentCount: 1,
+			},
+		},
+		updateConfirmableResponse: true,
+	}
+	options := sendConfirmationOptions(repo)
+	options.Clock = clock
+	options.Throttle = passport.Throttle{Cooldown: time.Minute, DailyLimit: 2}
+	sendConfirmation := usecase.NewSendConfirmation(options)
+
+	_, err := sendConfirmation.Exec(context.TODO(), passport.NewEmail("john.doe@mail.com"))
+	assert.True(errors.Is(err, passport.ErrTooManyRequests))
+	retryAfter, _ := passport.RetryAfter(err)
+	assert.Equal(50*time.Second, retryAfter)
+
+	clock.Advance(50 * time.Second)
+	token, err := sendConfirmation.Exec(context.TODO(), passport.NewEmail("john.doe@mail.com"))
+	assert.Nil(err)
+	assert.True(token != "")
+	assert.Equal(2, repo.confirmable.ConfirmationSentCount)
+
+	// The daily limit is reached.
+	repo.withEmailResponse.Confirmable = repo.confirmable
+	clock.Advance(time.Hour)
+	_, err = sendConfirmation.Exec(context.TODO(), passport.NewEmail("john.doe@mail.com"))
+	retryAfter, _ = passport.RetryAfter(err)
+	assert.Equal(22*time.Hour-50*time.Second, retryAfter)
+}
+
 type mockSendConfirmationRepository struct {
 	withEmailResponse         *passport.User
 	withEmailError            error
 	updateConfirmableResponse bool
 	updateConfirmableError    error
+	confirmable               passport.Confirmable
+	signed                    bool
 }
 
 func (m *mockSendConfirmationRepository) WithEmail(ctx context.Context, email string) (*passport.User, error) {
 	return m.withEmailResponse, m.withEmailError
 }
 
-func (m *mockSendConfirmationRepository) UpdateConfirmable(ctx context.Context, email string, confirmable passport.Confirmable) (bool, error) {
+func (m *mockSendConfirmationRepository) UpdateConfirmable(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error) {
+	m.confirmable = confirmable
+	return m.updateConfirmableResponse, m.updateConfirmableError
+}
+
+func (m *mockSendConfirmationRepository) UpdateConfirmableSigned(ctx context.Context, email string, version int, confirmable passport.Confirmable) (bool, error) {
+	m.confirmable = confirmable
+	m.signed = true
 	return m.updateConfirmableResponse, m.updateConfirmableError
 }
 
@@ -1,5 +1,14 @@
 package usecase
 
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/alextanhongpin/passport"
+)
+
 type tokenGenerator interface {
 	Generate() (string, error)
 }
@@ -18,3 +27,72 @@ type (
 		passwordComparer
 	}
 )
+
+// ErrTxRunnerRequired indicates that a usecase that writes more than once is
+// created without a TxRunner.
+var ErrTxRunnerRequired = passport.NewError("tx_runner_required", "transaction runner required", passport.CategoryInternal)
+
+// txRunner runs fn atomically. Repositories are expected to pick up the
+// transaction from the context passed to fn, e.g. connector.TxRunner.
+//
+// The usecases that write more than once, ResetPassword and ChangeEmail,
+// require a TxRunner, so that a later write cannot fail with the first one
+// committed, e.g. a consumed reset password token with the password
+// unchanged. It is optional for the other usecases.
+type txRunner interface {
+	RunInTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// runInTx runs fn in the transaction when a runner is provided, and as is
+// otherwise.
+func runInTx(ctx context.Context, runner txRunner, fn func(ctx context.Context) error) error {
+	if runner == nil {
+		return fn(ctx)
+	}
+	return runner.RunInTx(ctx, fn)
+}
+
+// tokenSigner signs stateless tokens, which are verified without looking
+// up the token in the repository, e.g. passport.HMACTokenSigner.
+type tokenSigner interface {
+	Sign(claims passport.SignedTokenClaims) (string, error)
+	Verify(token, purpose string) (*passport.SignedTokenClaims, error)
+}
+
+type userFinder interface {
+	Find(ctx context.Context, id string) (*passport.User, error)
+}
+
+// signToken returns a token that is only valid as long as the user's state
+// for the given purpose does not change.
+func signToken(signer tokenSigner, user *passport.User, purpose string, expiresAt time.Time) (string, error) {
+	return signer.Sign(passport.SignedTokenClaims{
+		UserID:      user.ID,
+		Purpose:     purpose,
+		ExpiresAt:   expiresAt.Unix(),
+		Fingerprint: user.Fingerprint(purpose),
+	})
+}
+
+// verifyToken returns the user the signed token is issued for, provided
+// that the token has not been used.
+func verifyToken(ctx context.Context, signer tokenSigner, finder userFinder, token passport.Token, purpose string) (*passport.User, error) {
+	claims, err := signer.Verify(token.Value(), purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := finder.Find(ctx, claims.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, passport.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Fingerprint(purpose) != claims.Fingerprint {
+		return nil, passport.ErrTokenInvalid
+	}
+
+	return user, nil
+}
@@ -1,11 +1,20 @@
 package passport
 
 import (
-	"errors"
 	"time"
 )
 
-var ErrUserNotFound = errors.New("user not found")
+var (
+	ErrUserNotFound = NewError("user_not_found", "user not found", CategoryNotFound)
+
+	// ErrConcurrentModification indicates that the user has been modified
+	// by another request since it was read.
+	ErrConcurrentModification = NewError("concurrent_modification", "concurrent modification", CategoryAborted)
+)
+
+// DeletionGracePeriod represents the duration the deleted users are kept
+// before their personal data is erased.
+const DeletionGracePeriod = 30 * 24 * time.Hour
 
 // User represents the authenticatable Entity.
 type User struct {
@@ -14,12 +23,31 @@ type User struct {
 	Email             string    `json:"email,omitempty"`
 	EncryptedPassword Password  `json:"encrypted_password,omitempty"`
 
+	// Username and Phone are the alternate identifiers to log in with.
+	// They are unique when set.
+	Username string `json:"username,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+
+	// Version is incremented on every update, and is used to detect
+	// concurrent modifications.
+	Version int `json:"version,omitempty"`
+
+	// DeletedAt is set when the user is soft deleted, until the personal
+	// data is erased after the DeletionGracePeriod.
+	DeletedAt time.Time `json:"deleted_at,omitempty"`
+
 	// Allow account to be recovered by resetting the password.
 	Recoverable
 
 	// Allow emails to be confirmed, especially when changing new email.
 	Confirmable
 
+	// Allow email changes to be reverted from the previous email.
+	Revertable
+
+	// Allow account to be locked until the password is reset.
+	Lockable
+
 	// Allow account information (client ip, user agent, sign in count) to
 	// be tracked.
 	// Trackable
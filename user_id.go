@@ -1,11 +1,10 @@
 package passport
 
 import (
-	"errors"
 	"strings"
 )
 
-var ErrUserIDRequired = errors.New("user_id required")
+var ErrUserIDRequired = NewError("user_id_required", "user_id required", CategoryValidation)
 
 type UserID string
 
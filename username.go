@@ -0,0 +1,48 @@
+package passport
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrUsernameExists   = NewError("username_exists", "username exists", CategoryConflict)
+	ErrUsernameInvalid  = NewError("username_invalid", "username invalid", CategoryValidation)
+	ErrUsernameRequired = NewError("username_required", "username required", CategoryValidation)
+)
+
+// usernameRegex matches 3 to 32 lowercase letters, digits, dots, dashes and
+// underscores, starting and ending with a letter or digit. The usernames
+// never contain @ or start with +, so they cannot be mistaken for emails or
+// phone numbers.
+var usernameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,30}[a-z0-9]$`)
+
+// Username is the case-insensitive handle the user may log in with.
+type Username string
+
+func (u Username) Valid() bool {
+	return usernameRegex.MatchString(u.Value())
+}
+
+func (u Username) Validate() error {
+	if u.Value() == "" {
+		return ErrUsernameRequired
+	}
+	if !u.Valid() {
+		return ErrUsernameInvalid
+	}
+	return nil
+}
+
+func (u Username) String() string {
+	return string(u)
+}
+
+func (u Username) Value() string {
+	return string(u)
+}
+
+// NewUsername returns the username trimmed and in lowercase.
+func NewUsername(username string) Username {
+	return Username(strings.ToLower(strings.TrimSpace(username)))
+}
@@ -0,0 +1,88 @@
+package passport
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrValidationFailed is the Error of ValidationErrors.
+var ErrValidationFailed = NewError("validation_failed", "validation failed", CategoryValidation)
+
+// FieldError is the validation error of a single field of the request.
+type FieldError struct {
+	Field string
+	Err   *Error
+
+	// Params are the parameters of the rule that failed, e.g. the min
+	// length of the password.
+	Params map[string]interface{}
+}
+
+func (f FieldError) Error() string {
+	return f.Field + ": " + f.Err.Message
+}
+
+func (f FieldError) Unwrap() error {
+	return f.Err
+}
+
+// ValidationErrors collects the errors of all the fields, instead of
+// stopping at the first error.
+type ValidationErrors []FieldError
+
+// Add appends the error of the field. Nil errors are skipped, so the result
+// of Validate can be passed directly. The params of a ParamsError are used
+// when no params are given.
+func (v ValidationErrors) Add(field string, err error, params map[string]interface{}) ValidationErrors {
+	if err == nil {
+		return v
+	}
+	var p ParamsError
+	if params == nil && errors.As(err, &p) {
+		params = p.Params
+	}
+	return append(v, FieldError{
+		Field:  field,
+		Err:    AsError(err),
+		Params: params,
+	})
+}
+
+// Err returns nil when there are no errors.
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, f := range v {
+		msgs[i] = f.Error()
+	}
+	return ErrValidationFailed.Message + ": " + strings.Join(msgs, ", ")
+}
+
+// Is matches ErrValidationFailed and the errors of the fields.
+func (v ValidationErrors) Is(target error) bool {
+	if target == ErrValidationFailed {
+		return true
+	}
+	for _, f := range v {
+		if errors.Is(f.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As sets the target *Error to ErrValidationFailed, so that the
+// ValidationErrors are not treated as internal errors.
+func (v ValidationErrors) As(target interface{}) bool {
+	if e, ok := target.(**Error); ok {
+		*e = ErrValidationFailed
+		return true
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package passport_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alextanhongpin/passport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialValidateAll(t *testing.T) {
+	t.Run("when all fields are valid", func(t *testing.T) {
+		assert.Nil(t, passport.NewCredential("john.doe@mail.com", "12345678").ValidateAll())
+	})
+
+	t.Run("when all fields are invalid", func(t *testing.T) {
+		assert := assert.New(t)
+		err := passport.NewCredential("", "").ValidateAll()
+
+		var errs passport.ValidationErrors
+		assert.True(errors.As(err, &errs))
+		assert.Equal(2, len(errs))
+		assert.Equal("email", errs[0].Field)
+		assert.Equal(passport.ErrEmailRequired, errs[0].Err)
+		assert.Equal("password", errs[1].Field)
+		assert.Equal(passport.ErrPasswordRequired, errs[1].Err)
+
+		assert.Equal(passport.ErrValidationFailed, passport.AsError(err))
+		assert.Equal("validation failed: email: email required, password: password required", err.Error())
+	})
+
+	t.Run("when the optional identifiers are invalid", func(t *testing.T) {
+		assert := assert.New(t)
+		cred := passport.NewCredential("john.doe@mail.com", "12345678")
+		cred.Username = passport.NewUsername("j")
+		cred.Phone = passport.NewPhone("123")
+
+		var errs passport.ValidationErrors
+		assert.True(errors.As(cred.ValidateAll(), &errs))
+		assert.Equal(2, len(errs))
+		assert.Equal("username", errs[0].Field)
+		assert.Equal(passport.ErrUsernameInvalid, errs[0].Err)
+		assert.Equal("phone", errs[1].Field)
+		assert.Equal(passport.ErrPhoneInvalid, errs[1].Err)
+	})
+}